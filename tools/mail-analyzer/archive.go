@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// buildMailArchive zips the given files, stored under their path relative
+// to baseDir
+func buildMailArchive(w io.Writer, baseDir string, files []string) error {
+	archive := zip.NewWriter(w)
+	for _, path := range files {
+		if err := addArchiveFile(archive, baseDir, path); err != nil {
+			archive.Close()
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// addArchiveFile adds a single file to a zip archive
+func addArchiveFile(archive *zip.Writer, baseDir, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	name, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	header.Method = zip.Deflate
+
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", path, err)
+	}
+	if _, err := io.Copy(entry, file); err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", path, err)
+	}
+	return nil
+}
+
+// writeMailArchive writes the zip archive of files to archivePath. The
+// archive is complete and synced to disk once this returns without error,
+// so the source files may be deleted afterwards.
+func writeMailArchive(archivePath, baseDir string, files []string) error {
+	var buf bytes.Buffer
+	if err := buildMailArchive(&buf, baseDir, files); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(archivePath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// isMailArchive reports whether path is an archive file of mail files that
+// --input accepts: .zip, .tar, .tar.gz or .tgz
+func isMailArchive(path string) bool {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return false
+	}
+
+	lower := strings.ToLower(path)
+	for _, suffix := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkMailArchive calls fn with the slash-separated name, content and
+// modification time of each .mail entry of a zip or tar archive, in archive
+// order. Other entries are skipped. Only the first maxSize+1 bytes of an
+// entry are read, enough for fn to tell that it is too large. It stops at
+// the first error fn returns.
+func walkMailArchive(path string, maxSize int64, fn func(name string, data []byte, modTime time.Time) error) error {
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		return walkZipArchive(path, maxSize, fn)
+	}
+	return walkTarArchive(path, maxSize, fn)
+}
+
+// walkZipArchive implements walkMailArchive for zip archives
+func walkZipArchive(path string, maxSize int64, fn func(name string, data []byte, modTime time.Time) error) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	defer archive.Close()
+
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !strings.HasSuffix(entry.Name, ".mail") {
+			continue
+		}
+
+		reader, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s in archive %s: %w", entry.Name, path, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s in archive %s: %w", entry.Name, path, err)
+		}
+
+		if err := fn(entry.Name, data, entry.Modified); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTarArchive implements walkMailArchive for tar archives, optionally
+// gzip-compressed
+func walkTarArchive(path string, maxSize int64, fn func(name string, data []byte, modTime time.Time) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to decompress archive %s: %w", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", path, err)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, ".mail") {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(archive, maxSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to read %s in archive %s: %w", header.Name, path, err)
+		}
+
+		if err := fn(header.Name, data, header.ModTime); err != nil {
+			return err
+		}
+	}
+}
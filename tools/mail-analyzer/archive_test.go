@@ -0,0 +1,327 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMailArchive(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "flat directory",
+			files: []string{"1.mail", "2.mail", "3.mail", "4.mail", "5.mail"},
+			want:  []string{"1.mail", "2.mail", "3.mail", "4.mail", "5.mail"},
+		},
+		{
+			name:  "character directories",
+			files: []string{"han/1.mail", "han/2.mail", "leia/3.mail", "leia/4.mail", "5.mail"},
+			want:  []string{"5.mail", "han/1.mail", "han/2.mail", "leia/3.mail", "leia/4.mail"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, name := range tt.files {
+				writeTestMail(t, dir, filepath.FromSlash(name), fmt.Sprint(i+1), "Player", "Hello", 1700000000+int64(i), "Body")
+			}
+			// Files that fail to parse are not archived
+			if err := os.WriteFile(filepath.Join(dir, "broken.mail"), []byte("not a mail"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			archivePath := filepath.Join(t.TempDir(), "mails.zip")
+			if err := writeMailArchive(archivePath, dir, result.ParsedFiles); err != nil {
+				t.Fatal(err)
+			}
+
+			archive, err := zip.OpenReader(archivePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer archive.Close()
+
+			var names []string
+			for _, entry := range archive.File {
+				names = append(names, entry.Name)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("archive contains %v, want %v", names, tt.want)
+			}
+
+			// The entries parse back to the archived mails
+			var ids []string
+			err = walkMailArchive(archivePath, defaultMaxFileSize, func(name string, data []byte, modTime time.Time) error {
+				mail, err := parseMailData(name, data, modTime, ParseOptions{})
+				if err != nil {
+					return err
+				}
+				ids = append(ids, mail.MailID)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != len(tt.want) {
+				t.Errorf("archive holds %d mails, want %d", len(ids), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMailFilesToArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeTestMail(t, dir, "1.mail", "1", "Player", "Hello", 1700000000, "First")
+	writeTestMail(t, dir, "2.mail", "2", "Player", "Hello", 1700000001, "Second")
+	writeTestMail(t, dir, "3.mail", "3", "Player", "Hello", 1700000002, "Third")
+	// A copy under another mail ID and another mail with an ID seen before
+	writeTestMail(t, dir, "copy.mail", "4", "Player", "Hello", 1700000000, "First")
+	writeTestMail(t, dir, "reused.mail", "1", "Player", "Hello", 1700000003, "Fourth")
+	if err := os.WriteFile(filepath.Join(dir, "broken.mail"), []byte("not a mail"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1.mail", "2.mail", "3.mail", "copy.mail", "reused.mail"}
+
+	// A checkpoint of an interrupted parse with the default content dedup
+	checkpointFile := filepath.Join(t.TempDir(), "mail_data_checkpoint.ndjson")
+	checkpoint, err := newParseCheckpoint(checkpointFile, []string{dir}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.file.Close()
+	if result.DuplicateMails != 2 {
+		t.Fatalf("fixture has %d duplicate mails, want 2", result.DuplicateMails)
+	}
+
+	tests := []struct {
+		name           string
+		checkpointFile string
+		wantErr        bool
+	}{
+		{"parse", "", false},
+		{"checkpoint", checkpointFile, false},
+		{"missing checkpoint", filepath.Join(dir, "missing.ndjson"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := mailFilesToArchive(context.Background(), dir, tt.checkpointFile, "unix")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mailFilesToArchive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var names []string
+			for _, file := range files {
+				names = append(names, filepath.Base(file))
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, want) {
+				t.Errorf("files to archive = %v, want %v", names, want)
+			}
+		})
+	}
+}
+
+// testArchiveEntry is an entry of a test archive
+type testArchiveEntry struct {
+	name    string
+	content string
+}
+
+// writeTestZip writes a zip archive of entries to dir/name and returns its
+// path
+func writeTestZip(t *testing.T, dir, name string, entries []testArchiveEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := archive.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, entry.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTestTar writes a tar archive of entries to dir/name, gzip-compressed
+// if name ends in .gz or .tgz, and returns its path
+func writeTestTar(t *testing.T, dir, name string, entries []testArchiveEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	archive := tar.NewWriter(w)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), ModTime: time.Unix(1700000000, 0)}
+		if strings.HasSuffix(entry.name, "/") {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0755
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(archive, entry.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testMailContent returns the content of a text mail file
+func testMailContent(id, sender, subject string, timestamp int64, body string) string {
+	return fmt.Sprintf("%s\n%s\n%s\nTIMESTAMP: %d\n%s\n", id, sender, subject, timestamp, body)
+}
+
+func TestIsMailArchive(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"mails.zip", "mails.tar", "mails.tar.gz", "mails.TGZ", "mails.gz", "1.mail"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "folder.zip"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"mails.zip", true},
+		{"mails.tar", true},
+		{"mails.tar.gz", true},
+		{"mails.TGZ", true},
+		{"mails.gz", false},
+		{"1.mail", false},
+		{"folder.zip", false},
+		{"missing.zip", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isMailArchive(filepath.Join(dir, tt.name)); got != tt.want {
+				t.Errorf("isMailArchive(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMailArchives(t *testing.T) {
+	entries := func(ids ...string) []testArchiveEntry {
+		list := []testArchiveEntry{
+			{"han/", ""},
+			{"han/notes.txt", "not a mail"},
+		}
+		for _, id := range ids {
+			n, _ := strconv.Atoi(id)
+			list = append(list, testArchiveEntry{"han/" + id + ".mail", testMailContent(id, "SWG.Restoration.auctioner", "Vendor Sale Complete",
+				1705312800+int64(n)*3600, "Vendor: Crafter has sold Rifle to Han for "+id+"00 credits.")})
+		}
+		return list
+	}
+
+	archives := t.TempDir()
+	zipPath := writeTestZip(t, archives, "2023.zip", entries("1", "2"))
+	tarPath := writeTestTar(t, archives, "2024.tar", entries("3"))
+	tgzPath := writeTestTar(t, archives, "2025.tar.gz", entries("4", "5"))
+	current := t.TempDir()
+	writeTestMail(t, current, "6.mail", "6", "Leia Organa", "Hello", 1705312800+6*3600, "Hello")
+
+	for _, path := range []string{zipPath, tarPath, tgzPath} {
+		var names []string
+		err := walkMailArchive(path, defaultMaxFileSize, func(name string, data []byte, modTime time.Time) error {
+			names = append(names, name)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if !strings.HasPrefix(name, "han/") || !strings.HasSuffix(name, ".mail") {
+				t.Errorf("walkMailArchive(%s) passed %q, want only .mail entries", filepath.Base(path), name)
+			}
+		}
+	}
+
+	result, err := parseMailFromDirectories(context.Background(), []string{zipPath, tarPath, tgzPath, current}, ParseOptions{InferCharacterFromDir: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mailIDs(result.Mails), []string{"1", "2", "3", "4", "5", "6"}; !slices.Equal(got, want) {
+		t.Fatalf("parsed mails = %v, want %v", got, want)
+	}
+	for _, mail := range result.Mails {
+		if mail.MailID == "6" {
+			continue
+		}
+		if mail.Character != "han" {
+			t.Errorf("mail %s character = %q, want han from its archive directory", mail.MailID, mail.Character)
+		}
+		if !strings.HasPrefix(mail.Source, archives) {
+			t.Errorf("mail %s source = %q, want the archive it was read from", mail.MailID, mail.Source)
+		}
+	}
+
+	// A corrupt archive fails the parse instead of being skipped silently
+	broken := filepath.Join(archives, "broken.tar.gz")
+	if err := os.WriteFile(broken, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseMailFromDirectories(context.Background(), []string{broken}, ParseOptions{}); err == nil {
+		t.Error("parsing a corrupt archive succeeded, want an error")
+	}
+}
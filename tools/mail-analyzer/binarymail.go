@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// binaryMailFields is the number of fields in a binary mail file
+const binaryMailFields = 5
+
+// decodeBinaryMail decodes the binary variant of mail files written by some
+// clients and returns the lines of the equivalent text mail file. A binary
+// mail file is a sequence of fields, each a little-endian uint32 byte
+// length followed by the field content:
+//
+//	mail ID, sender, subject, timestamp (decimal Unix seconds), body
+//
+// Each field is transcoded with toUTF8. It reports false for data that is
+// not exactly such a sequence of fields, e.g. text mail files, whose first
+// bytes read as an implausibly large length.
+func decodeBinaryMail(data []byte) ([]string, bool) {
+	fields := make([]string, 0, binaryMailFields)
+	rest := data
+	for range binaryMailFields {
+		if len(rest) < 4 {
+			return nil, false
+		}
+		length := binary.LittleEndian.Uint32(rest)
+		rest = rest[4:]
+		if uint64(length) > uint64(len(rest)) {
+			return nil, false
+		}
+		fields = append(fields, string(toUTF8(rest[:length])))
+		rest = rest[length:]
+	}
+	if len(rest) != 0 {
+		return nil, false
+	}
+
+	lines := []string{
+		fields[0],
+		fields[1],
+		fields[2],
+		"TIMESTAMP: " + fields[3],
+	}
+	body := strings.ReplaceAll(fields[4], "\r\n", "\n")
+	if body != "" {
+		lines = append(lines, strings.Split(body, "\n")...)
+	}
+	return lines, true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// encodeBinaryMail returns the fields as a binary mail file, see
+// decodeBinaryMail
+func encodeBinaryMail(fields ...string) []byte {
+	var data []byte
+	for _, field := range fields {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+	return data
+}
+
+func TestDecodeBinaryMail(t *testing.T) {
+	mail := encodeBinaryMail("1", "SWG.Restoration.auctioner", "Vendor Sale Complete", "1705312800", "Line one\r\nLine two")
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   []string
+		wantOK bool
+	}{
+		{"binary mail", mail, []string{"1", "SWG.Restoration.auctioner", "Vendor Sale Complete", "TIMESTAMP: 1705312800", "Line one", "Line two"}, true},
+		{"empty body", encodeBinaryMail("2", "Han Solo", "Hi", "1705312800", ""), []string{"2", "Han Solo", "Hi", "TIMESTAMP: 1705312800"}, true},
+		{"Latin-1 field", encodeBinaryMail("3", "Han Solo", "S\xe9ance", "1705312800", "Robe"), []string{"3", "Han Solo", "Séance", "TIMESTAMP: 1705312800", "Robe"}, true},
+		{"text mail", []byte("1\nSWG.Restoration.auctioner\nVendor Sale Complete\nTIMESTAMP: 1705312800\nBody\n"), nil, false},
+		{"truncated", mail[:len(mail)-1], nil, false},
+		{"trailing bytes", append(slices.Clone(mail), 0), nil, false},
+		{"missing fields", encodeBinaryMail("1", "Han Solo"), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := decodeBinaryMail(tt.data)
+			if ok != tt.wantOK || !slices.Equal(got, tt.want) {
+				t.Errorf("decodeBinaryMail() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseBinaryMailFile(t *testing.T) {
+	dir := t.TempDir()
+	data := encodeBinaryMail("1", "SWG.Restoration.auctioner", "Vendor Sale Complete", "1705312800",
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	if err := os.WriteFile(filepath.Join(dir, "1.mail"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestMail(t, dir, "2.mail", "2", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705399200,
+		"Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+
+	result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mailIDs(result.Mails), []string{"1", "2"}; !slices.Equal(got, want) {
+		t.Fatalf("parsed mails = %v, want %v", got, want)
+	}
+	mail := result.Mails[0]
+	if mail.Timestamp.Unix() != 1705312800 || mail.Sale == nil || mail.Sale.ItemName != "Rifle" || mail.Sale.Price != 1000 {
+		t.Errorf("binary mail parsed at %v with sale %+v, want Rifle for 1000 credits at 1705312800", mail.Timestamp, mail.Sale)
+	}
+}
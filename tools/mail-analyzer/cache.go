@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v3"
+)
+
+// parserVersion is part of the parse cache key. Increment it with every
+// change that makes the parser produce different mails from the same file,
+// so that results cached by earlier versions are not used.
+const parserVersion = 1
+
+// parseCacheKeyFlags are the parse flags the parse result of a mail file
+// depends on; cache entries written with other values are not used
+var parseCacheKeyFlags = []string{"timestamp-format", "normalize-ids", "galaxy", "recover-headers"}
+
+// parseCacheKeyFiles are the parse flags naming files the parse result of a
+// mail file depends on; cache entries written with other file contents are
+// not used
+var parseCacheKeyFiles = []string{"system-senders-file", "item-db", "item-key-rules", "patterns-file", "item-category-rules"}
+
+// parseCache stores the parsed mails of mail files on disk by the SHA-256
+// hash of the file content, so that repeated parses of unchanged files are
+// served from the cache, see --cache-dir
+type parseCache struct {
+	dir  string
+	hits atomic.Int64
+}
+
+// openParseCache returns the cache for the parse settings of cmd below dir.
+// The settings, parserVersion and the batch schema version make up the
+// cache key, so results are never shared between different settings or
+// versions of the parser.
+func openParseCache(dir string, cmd *cli.Command) (*parseCache, error) {
+	key, err := parseCacheKey(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &parseCache{dir: filepath.Join(dir, key)}
+	if err := os.MkdirAll(cache.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return cache, nil
+}
+
+// parseCacheKey hashes the parse settings of cmd and the parser and schema
+// versions into the name of a cache subdirectory
+func parseCacheKey(cmd *cli.Command) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "parser=%d\x00schema=%d\x00", parserVersion, CurrentSchemaVersion)
+	for _, name := range parseCacheKeyFlags {
+		fmt.Fprintf(h, "%s=%v\x00", name, cmd.Value(name))
+	}
+	for _, name := range parseCacheKeyFiles {
+		path := cmd.String(name)
+		fmt.Fprintf(h, "%s=", name)
+		if path != "" {
+			if err := hashFile(h, path); err != nil {
+				return "", err
+			}
+		}
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// hashFile writes the content of the file at path to h
+func hashFile(h io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(h, file)
+	return err
+}
+
+// entryPath returns the path of the cache entry for a mail file content
+// hash, in a subdirectory by the first two hex digits to keep directories
+// small
+func (c *parseCache) entryPath(hash string) string {
+	return filepath.Join(c.dir, hash[:2], hash+".json")
+}
+
+// get returns the cached mail of a mail file content. Unreadable entries
+// count as missing.
+func (c *parseCache) get(hash string) (*MailData, bool) {
+	data, err := os.ReadFile(c.entryPath(hash))
+	if err != nil {
+		return nil, false
+	}
+
+	var mail MailData
+	if err := json.Unmarshal(data, &mail); err != nil {
+		return nil, false
+	}
+	c.hits.Add(1)
+	return &mail, true
+}
+
+// put stores the mail parsed from a mail file content. Failures are
+// ignored, as they only cost the time to parse the file again next run.
+func (c *parseCache) put(hash string, mail *MailData) {
+	path := c.entryPath(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	data, err := json.Marshal(mail)
+	if err != nil {
+		return
+	}
+	writeFileAtomic(path, data, 0644)
+}
+
+// fileContentHash returns the SHA-256 hash of a mail file content, the key
+// of its parse cache entry
+func fileContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// mustMarshal returns v as JSON
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseCache(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestMail(t, dir, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	writeTestMail(t, dir, "2.mail", "2", "Han Solo", "Re: Rifle", 1705399200, "Thanks for the rifle!")
+	if err := os.WriteFile(filepath.Join(dir, "broken.mail"), []byte("not a mail"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := &parseCache{dir: t.TempDir()}
+
+	parse := func() []MailData {
+		t.Helper()
+		result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{Cache: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Mails
+	}
+
+	uncached := parse()
+	if hits := cache.hits.Load(); hits != 0 {
+		t.Errorf("first run served %d mail files from the cache, want 0", hits)
+	}
+	data, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := cache.entryPath(fileContentHash(data))
+	if _, err := os.Stat(entry); err != nil {
+		t.Fatalf("no cache entry for the content of 1.mail: %v", err)
+	}
+
+	// Unchanged contents are served from the cache, files that failed to
+	// parse are parsed again
+	cached := parse()
+	if hits := cache.hits.Load(); hits != 2 {
+		t.Errorf("second run served %d mail files from the cache, want 2", hits)
+	}
+	if got, want := mustMarshal(t, cached), mustMarshal(t, uncached); got != want {
+		t.Errorf("cached mails = %s, want %s", got, want)
+	}
+
+	// Entries are looked up by content, not by path: a renamed file is a
+	// hit, an edited one a miss
+	var edited MailData
+	if err := json.Unmarshal([]byte(mustMarshal(t, uncached[0])), &edited); err != nil {
+		t.Fatal(err)
+	}
+	edited.Sale.ItemName = "Cached Rifle"
+	if err := os.WriteFile(entry, []byte(mustMarshal(t, edited)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(first, filepath.Join(dir, "renamed.mail")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestMail(t, dir, "2.mail", "2", "Han Solo", "Re: Rifle", 1705399200, "Thanks again for the rifle!")
+
+	mails := parse()
+	if hits := cache.hits.Load(); hits != 3 {
+		t.Errorf("third run served %d mail files from the cache in total, want 3", hits)
+	}
+	if got, want := mailIDs(mails), []string{"1", "2"}; !slices.Equal(got, want) {
+		t.Fatalf("third run parsed %v, want %v", got, want)
+	}
+	if mails[0].Sale == nil || mails[0].Sale.ItemName != "Cached Rifle" {
+		t.Errorf("renamed file parsed to %+v, want the cached sale", mails[0].Sale)
+	}
+	if mails[1].Body != "Thanks again for the rifle!" {
+		t.Errorf("edited file parsed to body %q, want the new content", mails[1].Body)
+	}
+
+	// Corrupt entries count as missing
+	if err := os.WriteFile(entry, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if mail, ok := cache.get(fileContentHash(data)); ok {
+		t.Errorf("get() of a corrupt entry = %+v, want a miss", mail)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"strings"
+)
+
+// Mail categories assigned by categorize
+const (
+	CategorySale     = "sale"
+	CategoryPurchase = "purchase"
+	CategoryAuction  = "auction"
+	CategoryExpired  = "expired"
+	CategoryFactory  = "factory"
+	CategorySurvey   = "survey"
+	CategoryPlayer   = "player"
+	CategoryUnknown  = "unknown"
+)
+
+// Sale types assigned by saleType
+const (
+	SaleTypeVendor  = "vendor"
+	SaleTypeBazaar  = "bazaar"
+	SaleTypeUnknown = "unknown"
+)
+
+// Expected formats:
+// "Vendor: VendorName has sold [SEA] ItemName to BuyerName for 30000 credits."
+// "Your auction of [SEA] ItemName has been sold to BuyerName for 30000 credits"
+var (
+	vendorSalePattern = regexp.MustCompile(`Vendor: (.*?) has sold `)
+	bazaarSalePattern = regexp.MustCompile(`Your auction of .*? has been sold `)
+)
+
+// Expected format: "A resource survey of ResourceName (Type) was performed at Location."
+var surveyPattern = regexp.MustCompile(`A resource survey of .+? \(.+?\) was performed at .+?\.`)
+
+// categorize assigns a mail category based on its sender, subject and body
+func categorize(sender, subject, body string) string {
+	switch {
+	case strings.Contains(subject, "Auction Item Purchased") || purchasePattern.MatchString(body):
+		return CategoryPurchase
+	case auctionWonPattern.MatchString(body) || auctionOutbidPattern.MatchString(body):
+		return CategoryAuction
+	case expiredPattern.MatchString(body):
+		return CategoryExpired
+	case isFactoryRun(body):
+		return CategoryFactory
+	case strings.Contains(subject, "Sale Complete") || pricePattern.MatchString(body):
+		return CategorySale
+	case surveyPattern.MatchString(body) || surveyReportPattern.MatchString(subject+"\n"+body):
+		return CategorySurvey
+	case sender != "" && !strings.Contains(sender, "."):
+		// System senders use a dot-separated namespace, players never do
+		return CategoryPlayer
+	default:
+		return CategoryUnknown
+	}
+}
+
+// auctioneerSender is the sender of bazaar and vendor sale notifications
+const auctioneerSender = "SWG.Restoration.auctioner"
+
+// SystemSenders maps known system senders to human-readable labels. It can
+// be replaced with --system-senders-file.
+var SystemSenders = map[string]string{
+	"SWG.Restoration.auctioner":     "Bazaar",
+	"SWG.Restoration.vendormanager": "Vendor Manager",
+	"SWG.Restoration.system":        "System Announcements",
+	"SWG.Restoration.survey":        "Resource Survey",
+	"SWG.Restoration.guild":         "Guild",
+	"SWG.Restoration.city":          "City Management",
+}
+
+// defaultAnnouncementSender is the sender prefix of server announcements
+const defaultAnnouncementSender = "SWG.Restoration.system"
+
+// isAnnouncement reports whether a mail is a server announcement, i.e. its
+// sender starts with senderPrefix
+func isAnnouncement(mail MailData, senderPrefix string) bool {
+	return senderPrefix != "" && strings.HasPrefix(mail.Sender, senderPrefix)
+}
+
+// collapseAnnouncements keeps only the first of several announcements with
+// the same body. Announcements are forwarded to every player under a new mail
+// ID and timestamp, so only the body identifies them.
+func collapseAnnouncements(mails []MailData, senderPrefix string) ([]MailData, int) {
+	seen := make(map[[sha256.Size]byte]bool)
+	kept := make([]MailData, 0, len(mails))
+	for _, mail := range mails {
+		if isAnnouncement(mail, senderPrefix) {
+			hash := sha256.Sum256([]byte(mail.Body))
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+		}
+		kept = append(kept, mail)
+	}
+	return kept, len(mails) - len(kept)
+}
+
+// fillGalaxies assigns mails without a detected galaxy, such as player
+// mails, the most common galaxy of the other mails from the same input
+// directory. Ties go to the alphabetically first galaxy.
+func fillGalaxies(mails []MailData) {
+	counts := make(map[string]map[string]int)
+	for _, mail := range mails {
+		if mail.Galaxy == "" {
+			continue
+		}
+		if counts[mail.Source] == nil {
+			counts[mail.Source] = make(map[string]int)
+		}
+		counts[mail.Source][mail.Galaxy]++
+	}
+
+	dominant := make(map[string]string, len(counts))
+	for source, galaxies := range counts {
+		best := ""
+		for galaxy, count := range galaxies {
+			if best == "" || count > galaxies[best] || (count == galaxies[best] && galaxy < best) {
+				best = galaxy
+			}
+		}
+		dominant[source] = best
+	}
+
+	for i := range mails {
+		if mails[i].Galaxy == "" {
+			mails[i].Galaxy = dominant[mails[i].Source]
+		}
+	}
+}
+
+// isSaleNotification reports whether a mail is an auctioneer "Sale
+// Complete" notification
+func isSaleNotification(mail *MailData) bool {
+	return mail.Sender == auctioneerSender && strings.Contains(mail.Subject, "Sale Complete")
+}
+
+// saleData returns the structured sale details of a sale notification. It
+// relies on the sale fields and SaleType already being extracted from the
+// body.
+func saleData(mail *MailData) *SaleData {
+	return &SaleData{
+		ItemName:     mail.ItemName,
+		Buyer:        mail.Buyer,
+		Price:        mail.Price,
+		SaleChannel:  mail.SaleType,
+		VendorName:   mail.VendorName,
+		Category:     mail.ItemCategory,
+		SerialNumber: mail.SerialNumber,
+		UnitCount:    mail.UnitCount,
+		PricePerUnit: mail.PricePerUnit,
+	}
+}
+
+// parseVendorName extracts the vendor name from a vendor sale body
+func parseVendorName(body string) string {
+	matches := vendorSalePattern.FindStringSubmatch(body)
+	if len(matches) == 2 {
+		return strings.TrimSpace(matches[1])
+	}
+	return ""
+}
+
+// saleType tells vendor sales from bazaar sales. Mails that are not sales
+// have no sale type.
+func saleType(category, body string) string {
+	switch {
+	case category != CategorySale:
+		return ""
+	case vendorSalePattern.MatchString(body):
+		return SaleTypeVendor
+	case bazaarSalePattern.MatchString(body):
+		return SaleTypeBazaar
+	default:
+		return SaleTypeUnknown
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIsAnnouncement(t *testing.T) {
+	tests := []struct {
+		sender string
+		prefix string
+		want   bool
+	}{
+		{"SWG.Restoration.system", defaultAnnouncementSender, true},
+		{"SWG.Restoration.system.maintenance", defaultAnnouncementSender, true},
+		{"SWG.Restoration.auctioner", defaultAnnouncementSender, false},
+		{"Han Solo", defaultAnnouncementSender, false},
+		{"SWG.Restoration.system", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sender+" "+tt.prefix, func(t *testing.T) {
+			if got := isAnnouncement(MailData{Sender: tt.sender}, tt.prefix); got != tt.want {
+				t.Errorf("isAnnouncement(%q, %q) = %v, want %v", tt.sender, tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+// forwardedMails returns count mails from sender with the same body, each
+// under its own mail ID as forwarded to every player
+func forwardedMails(firstID, count int, sender, body string) []MailData {
+	mails := make([]MailData, count)
+	for i := range mails {
+		mails[i] = MailData{MailID: strconv.Itoa(firstID + i), Sender: sender, Body: body, Timestamp: date(2024, 1, 1+i%28)}
+	}
+	return mails
+}
+
+func TestCollapseAnnouncements(t *testing.T) {
+	const maintenance = "The server will restart for maintenance in 15 minutes."
+
+	tests := []struct {
+		name          string
+		mails         []MailData
+		prefix        string
+		wantIDs       []string
+		wantCollapsed int
+	}{
+		{
+			name:          "same announcement to 50 players",
+			mails:         forwardedMails(1, 50, defaultAnnouncementSender, maintenance),
+			prefix:        defaultAnnouncementSender,
+			wantIDs:       []string{"1"},
+			wantCollapsed: 49,
+		},
+		{
+			name: "different announcements",
+			mails: slices.Concat(
+				forwardedMails(1, 3, defaultAnnouncementSender, maintenance),
+				forwardedMails(4, 3, defaultAnnouncementSender, "Double XP weekend starts now!"),
+			),
+			prefix:        defaultAnnouncementSender,
+			wantIDs:       []string{"1", "4"},
+			wantCollapsed: 4,
+		},
+		{
+			name: "player mails with the same body are kept",
+			mails: slices.Concat(
+				forwardedMails(1, 2, "Han Solo", "Thanks!"),
+				forwardedMails(3, 2, defaultAnnouncementSender, maintenance),
+			),
+			prefix:        defaultAnnouncementSender,
+			wantIDs:       []string{"1", "2", "3"},
+			wantCollapsed: 1,
+		},
+		{
+			name:          "custom sender prefix",
+			mails:         forwardedMails(1, 5, "EMU.Basilisk.system", maintenance),
+			prefix:        "EMU.Basilisk.system",
+			wantIDs:       []string{"1"},
+			wantCollapsed: 4,
+		},
+		{
+			name:          "other prefix keeps all",
+			mails:         forwardedMails(1, 5, "EMU.Basilisk.system", maintenance),
+			prefix:        defaultAnnouncementSender,
+			wantIDs:       []string{"1", "2", "3", "4", "5"},
+			wantCollapsed: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, collapsed := collapseAnnouncements(tt.mails, tt.prefix)
+			if got := mailIDs(kept); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("collapseAnnouncements() kept %v, want %v", got, tt.wantIDs)
+			}
+			if collapsed != tt.wantCollapsed {
+				t.Errorf("collapseAnnouncements() collapsed %d, want %d", collapsed, tt.wantCollapsed)
+			}
+		})
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		sender  string
+		subject string
+		body    string
+		want    string
+	}{
+		{
+			name:    "sale",
+			sender:  auctioneerSender,
+			subject: "Vendor Sale Complete",
+			body:    "Vendor: Crafter has sold Rifle to Han for 1000 credits.",
+			want:    CategorySale,
+		},
+		{
+			name:   "sale without subject",
+			sender: auctioneerSender,
+			body:   "Your Rifle has been sold to Han for 1000 credits.",
+			want:   CategorySale,
+		},
+		{
+			name:    "purchase",
+			sender:  auctioneerSender,
+			subject: "Auction Item Purchased",
+			body:    "You have purchased Rifle from Crafter for 1000 credits.",
+			want:    CategoryPurchase,
+		},
+		{
+			name:    "auction won",
+			sender:  auctioneerSender,
+			subject: "Auction Won",
+			body:    "You won the auction of Rifle with a bid of 1000 credits.",
+			want:    CategoryAuction,
+		},
+		{
+			name:    "auction outbid",
+			sender:  auctioneerSender,
+			subject: "Outbid",
+			body:    "You have been outbid on Rifle. The new high bid is 1200 credits.",
+			want:    CategoryAuction,
+		},
+		{
+			name:    "expired",
+			sender:  auctioneerSender,
+			subject: "Auction Expired",
+			body:    "Your auction of Rifle has expired.",
+			want:    CategoryExpired,
+		},
+		{
+			name:    "factory",
+			sender:  "SWG.Restoration.factory",
+			subject: "Manufacturing Run Complete",
+			body:    "Your factory Weapons Line has completed its manufacturing run of 500 units of Rifle.",
+			want:    CategoryFactory,
+		},
+		{
+			name:    "survey",
+			sender:  "SWG.Restoration.survey",
+			subject: "Survey Results",
+			body:    "A resource survey of Polysteel Copper (Copper) was performed at Tatooine.",
+			want:    CategorySurvey,
+		},
+		{
+			name:    "player",
+			sender:  "Han Solo",
+			subject: "Thanks",
+			body:    "Thanks for the rifle!",
+			want:    CategoryPlayer,
+		},
+		{
+			name:    "unknown system mail",
+			sender:  "SWG.Restoration.guild",
+			subject: "Guild News",
+			body:    "The guild hall has been upgraded.",
+			want:    CategoryUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categorize(tt.sender, tt.subject, tt.body); got != tt.want {
+				t.Errorf("categorize() = %q, want %q", got, tt.want)
+			}
+
+			lines := []string{"1", tt.sender, tt.subject, "TIMESTAMP: 1700000000", tt.body}
+			mail, err := parseMailLines("1.mail", lines, time.Time{}, ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mail.MailCategory != tt.want {
+				t.Errorf("MailCategory = %q, want %q", mail.MailCategory, tt.want)
+			}
+		})
+	}
+}
+
+func TestSenderLabel(t *testing.T) {
+	senders := []string{
+		"SWG.Restoration.auctioner",
+		"SWG.Restoration.auctioner",
+		"SWG.Restoration.survey",
+		"SWG.Restoration.unknown",
+		"Han Solo",
+	}
+
+	tests := []struct {
+		name          string
+		systemSenders map[string]string
+		wantLabels    []string
+		known         int
+		unknown       int
+	}{
+		{
+			name:       "default system senders",
+			wantLabels: []string{"Bazaar", "Bazaar", "Resource Survey", "", ""},
+			known:      3,
+			unknown:    2,
+		},
+		{
+			name:          "system senders file",
+			systemSenders: map[string]string{"SWG.Restoration.unknown": "Mystery", "Han Solo": "Smuggler"},
+			wantLabels:    []string{"", "", "", "Mystery", "Smuggler"},
+			known:         2,
+			unknown:       3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mails []MailData
+			for i, sender := range senders {
+				lines := []string{strconv.Itoa(i + 1), sender, "Hello", "TIMESTAMP: 1700000000", "Body"}
+				mail, err := parseMailLines("mail", lines, time.Time{}, ParseOptions{SystemSenders: tt.systemSenders})
+				if err != nil {
+					t.Fatal(err)
+				}
+				mails = append(mails, *mail)
+			}
+
+			var labels []string
+			for _, mail := range mails {
+				labels = append(labels, mail.SenderLabel)
+			}
+			if !slices.Equal(labels, tt.wantLabels) {
+				t.Errorf("SenderLabel = %q, want %q", labels, tt.wantLabels)
+			}
+
+			stats := generateMailStats(mails)
+			if stats.KnownSystemMails != tt.known || stats.UnknownSenderMails != tt.unknown {
+				t.Errorf("KnownSystemMails, UnknownSenderMails = %d, %d, want %d, %d",
+					stats.KnownSystemMails, stats.UnknownSenderMails, tt.known, tt.unknown)
+			}
+		})
+	}
+}
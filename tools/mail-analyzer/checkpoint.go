@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// parseCheckpoint is the progress of a parse that is saved periodically, so
+// that an interrupted parse can continue where it stopped with --resume.
+//
+// The checkpoint file is NDJSON that only grows while parsing: the inputs
+// on the first line, then for every save the mails and skipped files added
+// since the previous save, each on a line of its own, and a line with the
+// paths processed since then that commits them. Lines after the last
+// commit, e.g. of a save that was interrupted, are dropped on resume.
+type parseCheckpoint struct {
+	path      string
+	every     int
+	pending   int
+	processed map[string]bool
+
+	// file is the checkpoint file while it is written, size the length of
+	// its committed lines
+	file *os.File
+	size int64
+
+	// The number of processed paths, mails and skipped files already saved
+	savedProcessed int
+	savedMails     int
+	savedSkipped   int
+
+	// The inputs the checkpoint belongs to, the paths processed, and the
+	// mails, skipped files and duplicates so far
+	Inputs            []string
+	Processed         []string
+	Mails             []MailData
+	Skipped           []SkippedFile
+	DuplicateMails    int
+	ContentDuplicates int
+}
+
+// checkpointLine is a line of a checkpoint file
+type checkpointLine struct {
+	Inputs  []string     `json:"inputs,omitempty"`
+	Mail    *MailData    `json:"mail,omitempty"`
+	Skipped *SkippedFile `json:"skipped,omitempty"`
+
+	// Processed commits the lines before it
+	Processed         []string `json:"processed,omitempty"`
+	DuplicateMails    int      `json:"duplicate_mails,omitempty"`
+	ContentDuplicates int      `json:"content_duplicates,omitempty"`
+}
+
+// checkpointPath returns the path of the checkpoint written next to
+// outputFile, e.g. "sales_checkpoint.ndjson" for "sales.json"
+func checkpointPath(outputFile string) string {
+	return outputFileBase(outputFile) + "_checkpoint.ndjson"
+}
+
+// newParseCheckpoint returns an empty checkpoint for inputs, saved to path
+// each time another every mail files are processed
+func newParseCheckpoint(path string, inputs []string, every int) (*parseCheckpoint, error) {
+	absInputs := make([]string, len(inputs))
+	for i, input := range inputs {
+		abs, err := filepath.Abs(input)
+		if err != nil {
+			return nil, err
+		}
+		absInputs[i] = abs
+	}
+
+	return &parseCheckpoint{
+		path:      path,
+		every:     every,
+		processed: make(map[string]bool),
+		Inputs:    absInputs,
+	}, nil
+}
+
+// loadParseCheckpoint reads the checkpoint at path to resume a parse of
+// inputs. It returns nil if there is no checkpoint, and an error if the
+// checkpoint belongs to other inputs.
+func loadParseCheckpoint(path string, inputs []string, every int) (*parseCheckpoint, error) {
+	checkpoint, err := newParseCheckpoint(path, inputs, every)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	defer file.Close()
+
+	// Mails and skipped files are only taken over once committed
+	var mails []MailData
+	var skipped []SkippedFile
+	var offset int64
+	var inputsThen []string
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// An incomplete last line is a save that was interrupted
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+		offset += int64(len(data))
+
+		var line checkpointLine
+		if err := json.Unmarshal(data, &line); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint %s line %d: %w", path, lineNumber, err)
+		}
+
+		switch {
+		case lineNumber == 1:
+			inputsThen = line.Inputs
+		case line.Mail != nil:
+			mails = append(mails, *line.Mail)
+		case line.Skipped != nil:
+			skipped = append(skipped, *line.Skipped)
+		case len(line.Processed) > 0:
+			checkpoint.Processed = append(checkpoint.Processed, line.Processed...)
+			checkpoint.Mails = append(checkpoint.Mails, mails...)
+			checkpoint.Skipped = append(checkpoint.Skipped, skipped...)
+			checkpoint.DuplicateMails = line.DuplicateMails
+			checkpoint.ContentDuplicates = line.ContentDuplicates
+			checkpoint.size = offset
+			mails, skipped = nil, nil
+		}
+	}
+
+	if offset == 0 {
+		// Interrupted before anything was saved
+		return nil, nil
+	}
+	if !slices.Equal(inputsThen, checkpoint.Inputs) {
+		return nil, fmt.Errorf("checkpoint %s was written for the inputs %s, run without --resume to start over",
+			path, strings.Join(inputsThen, ", "))
+	}
+
+	for _, processed := range checkpoint.Processed {
+		checkpoint.processed[processed] = true
+	}
+	checkpoint.savedProcessed = len(checkpoint.Processed)
+	checkpoint.savedMails = len(checkpoint.Mails)
+	checkpoint.savedSkipped = len(checkpoint.Skipped)
+	return checkpoint, nil
+}
+
+// done reports whether the mail file at path was processed before the
+// checkpoint was saved
+func (c *parseCheckpoint) done(path string) bool {
+	return c.processed[path]
+}
+
+// parsedFiles returns the processed paths that parsed successfully,
+// including those dropped as duplicates
+func (c *parseCheckpoint) parsedFiles() []string {
+	failed := make(map[string]bool)
+	for _, skipped := range c.Skipped {
+		if !skipped.Duplicate {
+			failed[skipped.File] = true
+		}
+	}
+
+	var parsed []string
+	for _, path := range c.Processed {
+		if !failed[path] {
+			parsed = append(parsed, path)
+		}
+	}
+	return parsed
+}
+
+// record marks the mail file at path as processed and saves the checkpoint
+// every c.every files. mails and skipped are the mails and skipped files so
+// far, which only grow between calls; only those added since the previous
+// save are written. duplicateMails and contentDuplicates are the counts of
+// ParseResult so far.
+func (c *parseCheckpoint) record(path string, mails []MailData, skipped []SkippedFile, duplicateMails, contentDuplicates int) error {
+	c.processed[path] = true
+	c.Processed = append(c.Processed, path)
+
+	c.pending++
+	if c.pending < c.every {
+		return nil
+	}
+	c.pending = 0
+
+	if err := c.save(mails, skipped, duplicateMails, contentDuplicates); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// save appends the mails, skipped files and processed paths added since the
+// previous save to the checkpoint file
+func (c *parseCheckpoint) save(mails []MailData, skipped []SkippedFile, duplicateMails, contentDuplicates int) error {
+	if c.file == nil {
+		if err := c.open(); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(c.file)
+	encoder := json.NewEncoder(w)
+	for i := c.savedMails; i < len(mails); i++ {
+		if err := encoder.Encode(checkpointLine{Mail: &mails[i]}); err != nil {
+			return err
+		}
+	}
+	for i := c.savedSkipped; i < len(skipped); i++ {
+		if err := encoder.Encode(checkpointLine{Skipped: &skipped[i]}); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Encode(checkpointLine{
+		Processed:         c.Processed[c.savedProcessed:],
+		DuplicateMails:    duplicateMails,
+		ContentDuplicates: contentDuplicates,
+	}); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := c.file.Sync(); err != nil {
+		return err
+	}
+
+	c.savedProcessed = len(c.Processed)
+	c.savedMails = len(mails)
+	c.savedSkipped = len(skipped)
+	return nil
+}
+
+// open opens the checkpoint file for appending. A new checkpoint starts
+// with its inputs; a loaded one drops the lines after its last commit.
+func (c *parseCheckpoint) open() error {
+	if c.size == 0 {
+		file, err := os.OpenFile(c.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(file).Encode(checkpointLine{Inputs: c.Inputs}); err != nil {
+			file.Close()
+			return err
+		}
+		c.file = file
+		return nil
+	}
+
+	file, err := os.OpenFile(c.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(c.size); err != nil {
+		file.Close()
+		return err
+	}
+	c.file = file
+	return nil
+}
+
+// remove deletes the saved checkpoint once the parse is complete
+func (c *parseCheckpoint) remove() error {
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
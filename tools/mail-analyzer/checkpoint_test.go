@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseResumeFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	for i, id := range []string{"1", "2", "3"} {
+		writeTestMail(t, dir, id+".mail", id, "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800+int64(i)*3600,
+			"Vendor: Crafter has sold Rifle "+id+" to Han for 100 credits.")
+	}
+	checkpointFile := filepath.Join(t.TempDir(), "mail_data_checkpoint.ndjson")
+
+	// The parse saves after two files; the third is not saved yet when it
+	// is interrupted, as is the save cut off in the middle of a line
+	checkpoint, err := newParseCheckpoint(checkpointFile, []string{dir}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{Checkpoint: checkpoint}); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.file.Close()
+	file, err := os.OpenFile(checkpointFile, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"mail":{"mail_id":"3","sen`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	// Mail files processed before the checkpoint are not read again
+	checkpoint, err = loadParseCheckpoint(checkpointFile, []string{dir}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint == nil || len(checkpoint.Processed) != 2 || len(checkpoint.Mails) != 2 {
+		t.Fatalf("loadParseCheckpoint() = %+v, want 2 processed files and mails", checkpoint)
+	}
+	for _, path := range checkpoint.Processed {
+		if err := os.WriteFile(path, []byte("changed since the checkpoint"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestMail(t, dir, "4.mail", "4", "Leia Organa", "Guild meeting", 1706004000, "See you at the hall.")
+
+	result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := mailIDs(result.Mails)
+	slices.Sort(ids)
+	if want := []string{"1", "2", "3", "4"}; !slices.Equal(ids, want) || len(result.SkippedFiles) != 0 {
+		t.Errorf("resumed parse = %v with %d skipped files, want %v and none", ids, len(result.SkippedFiles), want)
+	}
+	checkpoint.file.Close()
+
+	// The resumed parse appended to the committed lines only
+	checkpoint, err = loadParseCheckpoint(checkpointFile, []string{dir}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint == nil || len(checkpoint.Processed) != 4 || len(checkpoint.Mails) != 4 {
+		t.Errorf("checkpoint after resuming = %+v, want 4 processed files and mails", checkpoint)
+	}
+
+	// A checkpoint is only resumed for the inputs it was written for
+	if _, err := loadParseCheckpoint(checkpointFile, []string{t.TempDir()}, 2); err == nil {
+		t.Error("loadParseCheckpoint() for other inputs succeeded, want an error")
+	}
+	if checkpoint, err := loadParseCheckpoint(filepath.Join(dir, "missing.ndjson"), []string{dir}, 2); checkpoint != nil || err != nil {
+		t.Errorf("loadParseCheckpoint() of a missing file = %v, %v, want nil, nil", checkpoint, err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// ItemCategoryOther is assigned to items no rule matches
+const ItemCategoryOther = "Other"
+
+// itemCategoryRule assigns Category to items whose name matches Pattern
+type itemCategoryRule struct {
+	Category string
+	Pattern  *regexp.Regexp
+}
+
+// defaultItemCategoryRules classify common SWG items by their name. The
+// first matching rule wins, so ship components come before the generic
+// armor and weapon rules.
+var defaultItemCategoryRules = []itemCategoryRule{
+	{"Engine", regexp.MustCompile(`(?i)\bengine\b`)},
+	{"Reactor", regexp.MustCompile(`(?i)\breactor\b`)},
+	{"Shield", regexp.MustCompile(`(?i)\bshield\b`)},
+	{"Capacitor", regexp.MustCompile(`(?i)\bcapacitor\b`)},
+	{"Booster", regexp.MustCompile(`(?i)\bbooster\b`)},
+	{"Droid Interface", regexp.MustCompile(`(?i)\bdroid interface\b`)},
+	{"Armor", regexp.MustCompile(`(?i)\b(?:armou?r|plating|helmet|chest ?plate|bracer|bicep|leggings|boots|gloves|belt)\b`)},
+	{"Weapon", regexp.MustCompile(`(?i)\b(?:blaster|rifle|carbine|pistol|cannon|launcher|sword|knife|axe|lance|polearm|baton|weapon)\b`)},
+	{"Food", regexp.MustCompile(`(?i)\b(?:soup|stew|bread|cake|pie|pastry|salad|ale|wine|juice|brandy|spice|meal|food|drink)\b`)},
+	{"Resource", regexp.MustCompile(`(?i)\b(?:ore|metal|steel|copper|iron|aluminum|gemstone|crystal|gas|chemical|water|fiber|hide|meat|bone|milk|egg|seeds?|flora|wood|radioactive|petrochem|polymer)\b`)},
+}
+
+// classifyItem returns the category of the first rule matching the item
+// name, ItemCategoryOther if none does, or "" for mails without an item
+func classifyItem(name string, rules []itemCategoryRule) string {
+	if name == "" {
+		return ""
+	}
+	for _, rule := range rules {
+		if rule.Pattern.MatchString(name) {
+			return rule.Category
+		}
+	}
+	return ItemCategoryOther
+}
+
+// loadItemCategoryRules reads a JSON array of {"category": ..., "pattern": ...}
+// objects that replace defaultItemCategoryRules
+func loadItemCategoryRules(path string) ([]itemCategoryRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item category rules: %w", err)
+	}
+
+	var raw []struct {
+		Category string `json:"category"`
+		Pattern  string `json:"pattern"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse item category rules: %w", err)
+	}
+
+	rules := make([]itemCategoryRule, 0, len(raw))
+	for i, r := range raw {
+		if r.Category == "" {
+			return nil, fmt.Errorf("item category rule %d has no category", i+1)
+		}
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in item category rule %d: %w", i+1, err)
+		}
+		rules = append(rules, itemCategoryRule{Category: r.Category, Pattern: pattern})
+	}
+	return rules, nil
+}
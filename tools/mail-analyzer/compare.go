@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+)
+
+// buildComparisonReport compares the mails received in two date ranges.
+// Both end dates are inclusive.
+func buildComparisonReport(mails []MailData, aStart, aEnd, bStart, bEnd time.Time) ComparisonReport {
+	a := ComparisonPeriod{Start: aStart, End: aEnd, Stats: generateMailStats(SplitByDateRange(mails, aStart, aEnd))}
+	b := ComparisonPeriod{Start: bStart, End: bEnd, Stats: generateMailStats(SplitByDateRange(mails, bStart, bEnd))}
+
+	delta := ComparisonDelta{
+		TotalMails:        b.Stats.TotalMails - a.Stats.TotalMails,
+		SaleNotifications: b.Stats.SaleNotifications - a.Stats.SaleNotifications,
+		TotalRevenue:      b.Stats.TotalRevenue - a.Stats.TotalRevenue,
+	}
+	if a.Stats.TotalRevenue != 0 {
+		delta.RevenueChangePct = sanitizeFloat(float64(delta.TotalRevenue) / float64(a.Stats.TotalRevenue) * 100)
+	}
+
+	report := ComparisonReport{PeriodA: a, PeriodB: b, Delta: delta}
+	if start, end, ok := periodOverlap(aStart, aEnd, bStart, bEnd); ok {
+		report.OverlapWarning = true
+		report.OverlapMailCount = len(SplitByDateRange(mails, start, end))
+	}
+
+	return report
+}
+
+// periodOverlap returns the days two periods with inclusive end dates have in
+// common, if any
+func periodOverlap(aStart, aEnd, bStart, bEnd time.Time) (start, end time.Time, ok bool) {
+	start = aStart
+	if bStart.After(start) {
+		start = bStart
+	}
+	end = aEnd
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	return start, end, !start.After(end)
+}
+
+// warnPeriodOverlap logs which mails are counted in both compared periods
+func warnPeriodOverlap(mails []MailData, aStart, aEnd, bStart, bEnd time.Time) {
+	start, end, ok := periodOverlap(aStart, aEnd, bStart, bEnd)
+	if !ok {
+		return
+	}
+
+	overlap := SplitByDateRange(mails, start, end)
+	ids := make([]string, len(overlap))
+	for i, mail := range overlap {
+		ids[i] = mail.MailID
+	}
+
+	slog.Warn("compared periods overlap, mails in the overlap are counted in both",
+		"overlap_start", start.Format("2006-01-02"),
+		"overlap_end", end.Format("2006-01-02"),
+		"overlap_duration", end.AddDate(0, 0, 1).Sub(start),
+		"mail_ids", ids)
+}
+
+// renderComparisonReport writes a comparison report as a text table
+func renderComparisonReport(w io.Writer, report ComparisonReport) error {
+	a, b, delta := report.PeriodA, report.PeriodB, report.Delta
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "METRIC\tPERIOD A\tPERIOD B\tDELTA\n")
+	fmt.Fprintf(tw, "Period\t%s - %s\t%s - %s\t\n",
+		a.Start.Format("2006-01-02"), a.End.Format("2006-01-02"),
+		b.Start.Format("2006-01-02"), b.End.Format("2006-01-02"))
+	fmt.Fprintf(tw, "Mails\t%d\t%d\t%+d\n", a.Stats.TotalMails, b.Stats.TotalMails, delta.TotalMails)
+	fmt.Fprintf(tw, "Sale notifications\t%d\t%d\t%+d\n", a.Stats.SaleNotifications, b.Stats.SaleNotifications, delta.SaleNotifications)
+	fmt.Fprintf(tw, "Revenue\t%s\t%s\t%s (%+.1f%%)\n", FormatCredits(a.Stats.TotalRevenue), FormatCredits(b.Stats.TotalRevenue),
+		signedCredits(delta.TotalRevenue), delta.RevenueChangePct)
+	return tw.Flush()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// comparisonMails returns a mail per day of January 2024 from day 1 to 20.
+// The mails of days 1 to 4 and 11 to 16 are sales of 100 credits.
+func comparisonMails() []MailData {
+	var mails []MailData
+	for day := 1; day <= 20; day++ {
+		id := strconv.Itoa(day)
+		if day <= 4 || (day >= 11 && day <= 16) {
+			mail := testSale(id, date(2024, time.January, day), "Rifle", "Han", 100)
+			mail.Sale = &SaleData{ItemName: "Rifle", Buyer: "Han", Price: 100}
+			mails = append(mails, mail)
+			continue
+		}
+		mails = append(mails, MailData{MailID: id, Sender: "Han Solo", Timestamp: date(2024, time.January, day)})
+	}
+	return mails
+}
+
+func TestBuildComparisonReport(t *testing.T) {
+	mails := comparisonMails()
+	day := func(d int) time.Time { return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name                 string
+		aStart, aEnd         time.Time
+		bStart, bEnd         time.Time
+		mailsA, mailsB       int
+		revenueA, revenueB   int64
+		wantDelta            ComparisonDelta
+		wantOverlap          bool
+		wantOverlapMailCount int
+	}{
+		{
+			name:   "halves",
+			aStart: day(1), aEnd: day(10), bStart: day(11), bEnd: day(20),
+			mailsA: 10, mailsB: 10, revenueA: 400, revenueB: 600,
+			wantDelta: ComparisonDelta{TotalMails: 0, SaleNotifications: 2, TotalRevenue: 200, RevenueChangePct: 50},
+		},
+		{
+			name:   "uneven split",
+			aStart: day(1), aEnd: day(5), bStart: day(6), bEnd: day(20),
+			mailsA: 5, mailsB: 15, revenueA: 400, revenueB: 600,
+			wantDelta: ComparisonDelta{TotalMails: 10, SaleNotifications: 2, TotalRevenue: 200, RevenueChangePct: 50},
+		},
+		{
+			name:   "falling revenue",
+			aStart: day(11), aEnd: day(20), bStart: day(1), bEnd: day(10),
+			mailsA: 10, mailsB: 10, revenueA: 600, revenueB: 400,
+			wantDelta: ComparisonDelta{TotalMails: 0, SaleNotifications: -2, TotalRevenue: -200, RevenueChangePct: -100.0 / 3},
+		},
+		{
+			// No change in percent without revenue in period A
+			name:   "no revenue in period A",
+			aStart: day(5), aEnd: day(10), bStart: day(11), bEnd: day(20),
+			mailsA: 6, mailsB: 10, revenueA: 0, revenueB: 600,
+			wantDelta: ComparisonDelta{TotalMails: 4, SaleNotifications: 6, TotalRevenue: 600},
+		},
+		{
+			name:   "overlapping periods",
+			aStart: day(1), aEnd: day(12), bStart: day(10), bEnd: day(20),
+			mailsA: 12, mailsB: 11, revenueA: 600, revenueB: 600,
+			wantDelta:            ComparisonDelta{TotalMails: -1, SaleNotifications: 0, TotalRevenue: 0},
+			wantOverlap:          true,
+			wantOverlapMailCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := buildComparisonReport(mails, tt.aStart, tt.aEnd, tt.bStart, tt.bEnd)
+
+			a, b := report.PeriodA.Stats, report.PeriodB.Stats
+			if a.TotalMails != tt.mailsA || b.TotalMails != tt.mailsB || a.TotalRevenue != tt.revenueA || b.TotalRevenue != tt.revenueB {
+				t.Errorf("periods have %d and %d mails with revenue %d and %d, want %d and %d with %d and %d",
+					a.TotalMails, b.TotalMails, a.TotalRevenue, b.TotalRevenue, tt.mailsA, tt.mailsB, tt.revenueA, tt.revenueB)
+			}
+			delta := report.Delta
+			if math.Abs(delta.RevenueChangePct-tt.wantDelta.RevenueChangePct) < 1e-9 {
+				delta.RevenueChangePct = tt.wantDelta.RevenueChangePct
+			}
+			if delta != tt.wantDelta {
+				t.Errorf("Delta = %+v, want %+v", report.Delta, tt.wantDelta)
+			}
+			if report.OverlapWarning != tt.wantOverlap || report.OverlapMailCount != tt.wantOverlapMailCount {
+				t.Errorf("OverlapWarning, OverlapMailCount = %v, %d, want %v, %d",
+					report.OverlapWarning, report.OverlapMailCount, tt.wantOverlap, tt.wantOverlapMailCount)
+			}
+		})
+	}
+}
+
+func TestWarnPeriodOverlap(t *testing.T) {
+	mails := comparisonMails()
+	day := func(d int) time.Time { return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name         string
+		aStart, aEnd time.Time
+		bStart, bEnd time.Time
+		wantStart    string
+		wantEnd      string
+		wantDuration time.Duration
+		wantIDs      []string
+	}{
+		{"overlapping periods", day(1), day(12), day(10), day(20), "2024-01-10", "2024-01-12", 72 * time.Hour, []string{"10", "11", "12"}},
+		{"period within the other", day(1), day(20), day(5), day(5), "2024-01-05", "2024-01-05", 24 * time.Hour, []string{"5"}},
+		{"separate periods", day(1), day(10), day(11), day(20), "", "", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			defaultLogger := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+			defer slog.SetDefault(defaultLogger)
+
+			warnPeriodOverlap(mails, tt.aStart, tt.aEnd, tt.bStart, tt.bEnd)
+
+			if tt.wantIDs == nil {
+				if buf.Len() > 0 {
+					t.Errorf("logged %s, want no warning", buf.String())
+				}
+				return
+			}
+			var warning struct {
+				Level    string
+				Start    string        `json:"overlap_start"`
+				End      string        `json:"overlap_end"`
+				Duration time.Duration `json:"overlap_duration"`
+				MailIDs  []string      `json:"mail_ids"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &warning); err != nil {
+				t.Fatalf("failed to decode warning %q: %v", buf.String(), err)
+			}
+			if warning.Level != "WARN" || warning.Start != tt.wantStart || warning.End != tt.wantEnd || warning.Duration != tt.wantDuration {
+				t.Errorf("warning = %s %s to %s (%v), want WARN %s to %s (%v)",
+					warning.Level, warning.Start, warning.End, warning.Duration, tt.wantStart, tt.wantEnd, tt.wantDuration)
+			}
+			if !slices.Equal(warning.MailIDs, tt.wantIDs) {
+				t.Errorf("mail_ids = %v, want %v", warning.MailIDs, tt.wantIDs)
+			}
+		})
+	}
+}
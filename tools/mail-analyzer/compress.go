@@ -0,0 +1,82 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/urfave/cli/v3"
+)
+
+// Supported --compress values; CompressionNone writes uncompressed output
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// compressionExts maps the file extensions of compressed output to their
+// compression
+var compressionExts = map[string]string{
+	".gz":  CompressionGzip,
+	".zst": CompressionZstd,
+}
+
+// compressionFromCommand returns the --compress value of cmd
+func compressionFromCommand(cmd *cli.Command) (string, error) {
+	compression := cmd.String("compress")
+	switch compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return compression, nil
+	}
+	return "", fmt.Errorf("unsupported --compress %q, expected gzip or zstd", compression)
+}
+
+// compressionExt returns the file extension of output compressed with
+// compression, e.g. ".gz" for gzip
+func compressionExt(compression string) string {
+	for ext, c := range compressionExts {
+		if c == compression {
+			return ext
+		}
+	}
+	return ""
+}
+
+// trimCompressionExt strips the extension of a compressed output file from
+// path, e.g. "mail_data.json" for "mail_data.json.gz"
+func trimCompressionExt(path string) string {
+	ext := filepath.Ext(path)
+	if _, ok := compressionExts[ext]; ok {
+		return strings.TrimSuffix(path, ext)
+	}
+	return path
+}
+
+// compressed returns write with its output compressed with compression.
+// The compressed stream is only complete once the returned function has
+// returned without error.
+func compressed(compression string, write func(w io.Writer) error) func(w io.Writer) error {
+	if compression == CompressionNone {
+		return write
+	}
+	return func(w io.Writer) error {
+		var cw io.WriteCloser
+		if compression == CompressionGzip {
+			cw = gzip.NewWriter(w)
+		} else {
+			encoder, err := zstd.NewWriter(w)
+			if err != nil {
+				return fmt.Errorf("failed to create zstd encoder: %w", err)
+			}
+			cw = encoder
+		}
+		if err := write(cw); err != nil {
+			return err
+		}
+		return cw.Close()
+	}
+}
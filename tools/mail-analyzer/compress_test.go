@@ -0,0 +1,110 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// decompressedReader returns a reader of the content of r decompressed
+// with compression
+func decompressedReader(t *testing.T, r io.Reader, compression string) io.Reader {
+	t.Helper()
+	switch compression {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gr
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(zr.Close)
+		return zr
+	}
+	return r
+}
+
+func TestWriteOutputFileCompressed(t *testing.T) {
+	batch := parseTestBatch(t)
+
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run("compression "+compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mail_data.json"+compressionExt(compression))
+			err := writeOutputFile(path, compression, func(w io.Writer) error {
+				return writeBatchToWriter(w, &batch, jsonOptions{KeyCase: KeyCaseSnake})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			got, err := readBatchFromReader(decompressedReader(t, file, compression))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Mails) != len(batch.Mails) || got.Stats.TotalRevenue != batch.Stats.TotalRevenue {
+				t.Errorf("read %d mails with revenue %d, want %d with %d",
+					len(got.Mails), got.Stats.TotalRevenue, len(batch.Mails), batch.Stats.TotalRevenue)
+			}
+		})
+	}
+}
+
+func TestCompressionExt(t *testing.T) {
+	tests := []struct {
+		compression string
+		path        string
+		wantExt     string
+		wantTrimmed string
+	}{
+		{CompressionNone, "mail_data.json", "", "mail_data.json"},
+		{CompressionGzip, "mail_data.json.gz", ".gz", "mail_data.json"},
+		{CompressionZstd, "out/mail_data.csv.zst", ".zst", "out/mail_data.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := compressionExt(tt.compression); got != tt.wantExt {
+				t.Errorf("compressionExt(%q) = %q, want %q", tt.compression, got, tt.wantExt)
+			}
+			if got := trimCompressionExt(tt.path); got != tt.wantTrimmed {
+				t.Errorf("trimCompressionExt(%q) = %q, want %q", tt.path, got, tt.wantTrimmed)
+			}
+		})
+	}
+}
+
+func TestWriteParsedCSVCompressed(t *testing.T) {
+	batch := parseTestBatch(t)
+	dir := t.TempDir()
+	if err := writeParsedCSV(io.Discard, filepath.Join(dir, "mail_data.csv.gz"), batch, CompressionGzip); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sales are compressed like the mails, the statistics are not
+	for _, name := range []string{"mail_data.csv.gz", "mail_data_sales.csv.gz"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := io.ReadAll(decompressedReader(t, file, CompressionGzip)); err != nil {
+			t.Errorf("%s is not gzip compressed: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mail_data_stats.json")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Byte order marks written by some client installs
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// toUTF8 detects the encoding of a mail file and returns its content as
+// UTF-8. Older client installs write mail files in UTF-16 (with or without
+// a byte order mark) or Latin-1 instead of UTF-8.
+func toUTF8(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return data[len(utf8BOM):]
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return decodeUTF16(data[len(utf16LEBOM):], binary.LittleEndian)
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return decodeUTF16(data[len(utf16BEBOM):], binary.BigEndian)
+	}
+
+	// Mail files are mostly ASCII, so UTF-16 without a byte order mark
+	// shows up as every other byte being zero
+	if order, ok := guessUTF16(data); ok {
+		return decodeUTF16(data, order)
+	}
+	if utf8.Valid(data) {
+		return data
+	}
+	return decodeLatin1(data)
+}
+
+// guessUTF16 reports whether data looks like UTF-16 without a byte order
+// mark, and in which byte order
+func guessUTF16(data []byte) (binary.ByteOrder, bool) {
+	if len(data) < 4 || len(data)%2 != 0 {
+		return nil, false
+	}
+
+	var evenZeros, oddZeros int
+	for i := 0; i < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+
+	// Require most code units to be ASCII-range to avoid mistaking binary
+	// junk for text
+	half := len(data) / 2
+	switch {
+	case oddZeros*2 > half && evenZeros == 0:
+		return binary.LittleEndian, true
+	case evenZeros*2 > half && oddZeros == 0:
+		return binary.BigEndian, true
+	}
+	return nil, false
+}
+
+// decodeUTF16 converts UTF-16 in the given byte order to UTF-8. A trailing
+// odd byte is dropped.
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// decodeLatin1 converts ISO 8859-1 to UTF-8. Every byte maps to the code
+// point of the same value.
+func decodeLatin1(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/4)
+	for _, b := range data {
+		out = utf8.AppendRune(out, rune(b))
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 returns s as UTF-16 in the given byte order
+func encodeUTF16(s string, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 2*len(units))
+	for i, unit := range units {
+		order.PutUint16(out[2*i:], unit)
+	}
+	return out
+}
+
+func TestToUTF8(t *testing.T) {
+	const text = "1\nSWG.Restoration.auctioner\nVendor Sale Complete\nTIMESTAMP: 1705312800\nCrafter has sold Bothan Séance Robe to Han"
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"UTF-8", []byte(text)},
+		{"UTF-8 with BOM", slices.Concat(utf8BOM, []byte(text))},
+		{"UTF-16LE with BOM", slices.Concat(utf16LEBOM, encodeUTF16(text, binary.LittleEndian))},
+		{"UTF-16BE with BOM", slices.Concat(utf16BEBOM, encodeUTF16(text, binary.BigEndian))},
+		{"UTF-16LE without BOM", encodeUTF16(text, binary.LittleEndian)},
+		{"UTF-16BE without BOM", encodeUTF16(text, binary.BigEndian)},
+		{"Latin-1", []byte("1\nSWG.Restoration.auctioner\nVendor Sale Complete\nTIMESTAMP: 1705312800\nCrafter has sold Bothan S\xe9ance Robe to Han")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(toUTF8(tt.data)); got != text {
+				t.Errorf("toUTF8() = %q, want %q", got, text)
+			}
+		})
+	}
+}
+
+func TestParseMailFileEncodings(t *testing.T) {
+	const body = "Vendor: Crafter has sold Séance Robe to Han for 1000 credits."
+	text := "1\r\nSWG.Restoration.auctioner\r\nVendor Sale Complete\r\nTIMESTAMP: 1705312800\r\n" + body + "\r\n"
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"UTF-16LE with BOM", slices.Concat(utf16LEBOM, encodeUTF16(text, binary.LittleEndian))},
+		{"Latin-1", []byte("1\nSWG.Restoration.auctioner\nVendor Sale Complete\nTIMESTAMP: 1705312800\nVendor: Crafter has sold S\xe9ance Robe to Han for 1000 credits.\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "1.mail")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			mail, err := parseMailFile(path, ParseOptions{})
+			if err != nil {
+				t.Fatalf("parseMailFile() error = %v", err)
+			}
+			if mail.MailID != "1" || mail.Body != body {
+				t.Errorf("parsed mail %q with body %q, want %q with body %q", mail.MailID, mail.Body, "1", body)
+			}
+			if mail.Sale == nil || mail.Sale.ItemName != "Séance Robe" || mail.Sale.Price != 1000 {
+				t.Errorf("Sale = %+v, want Séance Robe for 1000 credits", mail.Sale)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ApplyFilters returns the mails matching all filter options, preserving their order
+func ApplyFilters(mails []MailData, opts FilterOpts) []MailData {
+	filtered := make([]MailData, 0, len(mails))
+	for _, mail := range mails {
+		if matchesFilters(mail, opts) {
+			filtered = append(filtered, mail)
+		}
+	}
+	return filtered
+}
+
+// SplitByDateRange returns the mails received between start and end, both
+// inclusive; end covers the whole day
+func SplitByDateRange(mails []MailData, start, end time.Time) []MailData {
+	return ApplyFilters(mails, FilterOpts{StartDate: start, EndDate: end})
+}
+
+// matchesFilters reports whether a mail passes all filter options
+func matchesFilters(mail MailData, opts FilterOpts) bool {
+	if opts.SenderFilter != "" && !strings.Contains(mail.Sender, opts.SenderFilter) {
+		return false
+	}
+
+	if opts.SenderDomain != "" && !strings.EqualFold(mail.SenderDomain, opts.SenderDomain) {
+		return false
+	}
+
+	if opts.SubjectFilter != "" {
+		subject := mail.Subject
+		if opts.UseNormalizedSubject {
+			subject = mail.NormalizedSubject
+		}
+		if !strings.Contains(subject, opts.SubjectFilter) {
+			return false
+		}
+	}
+
+	if opts.Category != "" && mail.MailCategory != opts.Category {
+		return false
+	}
+
+	if opts.MailType != "" && mail.MailType != opts.MailType {
+		return false
+	}
+
+	if opts.SaleType != "" && mail.SaleType != opts.SaleType {
+		return false
+	}
+
+	if !opts.StartDate.IsZero() && mail.Timestamp.Before(opts.StartDate) {
+		return false
+	}
+
+	// The end date is inclusive, so anything before the following day passes
+	if !opts.EndDate.IsZero() && !mail.Timestamp.Before(opts.EndDate.AddDate(0, 0, 1)) {
+		return false
+	}
+
+	if len(opts.TagFilter) > 0 && !matchesTags(mail, opts.TagFilter, opts.TagAny) {
+		return false
+	}
+
+	if len(opts.NotTags) > 0 && matchesTags(mail, opts.NotTags, true) {
+		return false
+	}
+
+	return true
+}
+
+// sortMails sorts mails in place by the given field: timestamp, id, sender,
+// subject or price. Ties keep their previous order.
+func sortMails(mails []MailData, by string, desc bool) error {
+	var less func(a, b MailData) bool
+	switch by {
+	case "timestamp":
+		less = func(a, b MailData) bool { return a.Timestamp.Before(b.Timestamp) }
+	case "id":
+		less = func(a, b MailData) bool { return a.MailID < b.MailID }
+	case "sender":
+		less = func(a, b MailData) bool { return a.Sender < b.Sender }
+	case "subject":
+		less = func(a, b MailData) bool { return a.Subject < b.Subject }
+	case "price":
+		less = func(a, b MailData) bool { return a.Price < b.Price }
+	default:
+		return fmt.Errorf("unsupported sort field %q, expected timestamp, id, sender, subject or price", by)
+	}
+
+	sort.SliceStable(mails, func(i, j int) bool {
+		if desc {
+			return less(mails[j], mails[i])
+		}
+		return less(mails[i], mails[j])
+	})
+
+	return nil
+}
+
+// parseFilterDate parses a YYYY-MM-DD date in local time, returning the zero time for an empty value
+func parseFilterDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// matchesTags reports whether a mail carries the required tags. With any set,
+// a single matching tag is enough; otherwise all tags must be present.
+// Tags are compared case-insensitively.
+func matchesTags(mail MailData, required []string, any bool) bool {
+	if len(required) == 0 {
+		return true
+	}
+
+	for _, tag := range required {
+		found := hasTag(mail, tag)
+		if any && found {
+			return true
+		}
+		if !any && !found {
+			return false
+		}
+	}
+
+	return !any
+}
+
+// hasTag reports whether a mail carries the given tag
+func hasTag(mail MailData, tag string) bool {
+	for _, t := range mail.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMatchesTags(t *testing.T) {
+	mail := MailData{MailID: "1", Tags: []string{"vendor", "Weapon", "high-value"}}
+
+	tests := []struct {
+		name     string
+		required []string
+		any      bool
+		want     bool
+	}{
+		{"no tags required", nil, false, true},
+		{"all present", []string{"vendor", "weapon"}, false, true},
+		{"one missing", []string{"vendor", "armor"}, false, false},
+		{"case-insensitive", []string{"HIGH-VALUE"}, false, true},
+		{"any with one present", []string{"armor", "weapon"}, true, true},
+		{"any with none present", []string{"armor", "food"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesTags(mail, tt.required, tt.any); got != tt.want {
+				t.Errorf("matchesTags(%v, %v) = %v, want %v", tt.required, tt.any, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFiltersTags(t *testing.T) {
+	mails := []MailData{
+		{MailID: "1", Tags: []string{"vendor", "weapon"}},
+		{MailID: "2", Tags: []string{"vendor", "armor"}},
+		{MailID: "3", Tags: []string{"bazaar", "weapon"}},
+		{MailID: "4"},
+	}
+
+	tests := []struct {
+		name string
+		opts FilterOpts
+		want []string
+	}{
+		{"no filter", FilterOpts{}, []string{"1", "2", "3", "4"}},
+		{"all tags", FilterOpts{TagFilter: []string{"vendor", "weapon"}}, []string{"1"}},
+		{"any tag", FilterOpts{TagFilter: []string{"armor", "bazaar"}, TagAny: true}, []string{"2", "3"}},
+		{"excluded tag", FilterOpts{NotTags: []string{"weapon"}}, []string{"2", "4"}},
+		{"required and excluded", FilterOpts{TagFilter: []string{"vendor"}, NotTags: []string{"armor"}}, []string{"1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, mail := range ApplyFilters(mails, tt.opts) {
+				ids = append(ids, mail.MailID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("ApplyFilters() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	mainDir, altDir := t.TempDir(), t.TempDir()
+	writeTestMail(t, mainDir, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	writeTestMail(t, altDir, "2.mail", "2", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705399200,
+		"Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+
+	var mails []MailData
+	for _, input := range []struct {
+		dir  string
+		tags []string
+	}{{mainDir, []string{"main", "vendor"}}, {altDir, []string{"alt", "vendor"}}} {
+		result, err := parseMailFromDirectory(context.Background(), input.dir, ParseOptions{Tags: input.tags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mail := range result.Mails {
+			if !slices.Equal(mail.Tags, input.tags) {
+				t.Errorf("mail %s tags = %v, want %v", mail.MailID, mail.Tags, input.tags)
+			}
+		}
+		mails = append(mails, result.Mails...)
+	}
+	// Every mail gets its own copy of the tags
+	mails[0].Tags[0] = "edited"
+	if mails[1].Tags[0] != "alt" {
+		t.Errorf("editing the tags of mail 1 changed mail 2 to %v", mails[1].Tags)
+	}
+	mails[0].Tags[0] = "main"
+
+	tests := []struct {
+		name string
+		opts FilterOpts
+		want []string
+	}{
+		{"shared tag", FilterOpts{TagFilter: []string{"vendor"}}, []string{"1", "2"}},
+		{"one account", FilterOpts{TagFilter: []string{"alt"}}, []string{"2"}},
+		{"either account", FilterOpts{TagFilter: []string{"main", "alt"}, TagAny: true}, []string{"1", "2"}},
+		{"excluded account", FilterOpts{NotTags: []string{"main"}}, []string{"2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mailIDs(ApplyFilters(mails, tt.opts)); !slices.Equal(got, tt.want) {
+				t.Errorf("ApplyFilters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFiltersMatchesParse(t *testing.T) {
+	dir := t.TempDir()
+	writeTestMail(t, dir, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	writeTestMail(t, dir, "2.mail", "2", "Han Solo", "Re: Rifle", 1705399200, "Thanks for the rifle!")
+	writeTestMail(t, dir, "3.mail", "3", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705917600,
+		"Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+	writeTestMail(t, dir, "4.mail", "4", "Leia Organa", "Guild meeting", 1706004000, "See you at the hall.")
+
+	all, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startDate, _ := parseFilterDate("2024-01-16")
+	endDate, _ := parseFilterDate("2024-01-22")
+	tests := []struct {
+		name string
+		opts FilterOpts
+		want int
+	}{
+		{"sender", FilterOpts{SenderFilter: "auctioner"}, 2},
+		{"subject", FilterOpts{SubjectFilter: "Rifle"}, 1},
+		{"date range", FilterOpts{StartDate: startDate, EndDate: endDate}, 2},
+		{"category", FilterOpts{Category: all.Mails[0].MailCategory}, 2},
+		{"sender and date", FilterOpts{SenderFilter: "auctioner", StartDate: startDate}, 1},
+		{"nothing matches", FilterOpts{SenderFilter: "Jabba"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{FilterOpts: tt.opts})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed.Mails) != tt.want {
+				t.Errorf("parse kept %d mails, want %d", len(parsed.Mails), tt.want)
+			}
+			filtered := ApplyFilters(all.Mails, tt.opts)
+			if !slices.EqualFunc(parsed.Mails, filtered, func(a, b MailData) bool { return a.MailID == b.MailID }) {
+				t.Errorf("parse kept %v, filter kept %v", mailIDs(parsed.Mails), mailIDs(filtered))
+			}
+			if parsedStats, filteredStats := generateMailStats(parsed.Mails), generateMailStats(filtered); parsedStats.TotalRevenue != filteredStats.TotalRevenue ||
+				parsedStats.TotalMails != filteredStats.TotalMails {
+				t.Errorf("parse stats %d mails, %d cr, filter stats %d mails, %d cr",
+					parsedStats.TotalMails, parsedStats.TotalRevenue, filteredStats.TotalMails, filteredStats.TotalRevenue)
+			}
+		})
+	}
+}
+
+func TestSortMails(t *testing.T) {
+	mails := []MailData{
+		{MailID: "2", Sender: "b", Price: 300, Timestamp: date(2024, time.January, 2)},
+		{MailID: "1", Sender: "c", Price: 100, Timestamp: date(2024, time.January, 3)},
+		{MailID: "3", Sender: "a", Price: 100, Timestamp: date(2024, time.January, 1)},
+	}
+
+	tests := []struct {
+		by      string
+		desc    bool
+		want    []string
+		wantErr bool
+	}{
+		{by: "timestamp", want: []string{"3", "2", "1"}},
+		{by: "timestamp", desc: true, want: []string{"1", "2", "3"}},
+		{by: "id", want: []string{"1", "2", "3"}},
+		{by: "sender", want: []string{"3", "2", "1"}},
+		// Ties keep their previous order
+		{by: "price", want: []string{"1", "3", "2"}},
+		{by: "price", desc: true, want: []string{"2", "1", "3"}},
+		{by: "buyer", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s desc=%v", tt.by, tt.desc), func(t *testing.T) {
+			sorted := slices.Clone(mails)
+			err := sortMails(sorted, tt.by, tt.desc)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("sortMails() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mailIDs(sorted); !slices.Equal(got, tt.want) {
+				t.Errorf("sortMails(%q, %v) = %v, want %v", tt.by, tt.desc, got, tt.want)
+			}
+		})
+	}
+}
+
+// mailIDs returns the IDs of mails in order
+func mailIDs(mails []MailData) []string {
+	ids := make([]string, len(mails))
+	for i, mail := range mails {
+		ids[i] = mail.MailID
+	}
+	return ids
+}
+
+func TestApplyFiltersSenderDomain(t *testing.T) {
+	mails := []MailData{
+		{MailID: "1", SenderDomain: "SWG"},
+		{MailID: "2", SenderDomain: "EMU"},
+		{MailID: "3", SenderDomain: "swg"},
+	}
+
+	tests := []struct {
+		domain string
+		want   []string
+	}{
+		{"", []string{"1", "2", "3"}},
+		{"SWG", []string{"1", "3"}},
+		{"emu", []string{"2"}},
+		{"Other", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			got := mailIDs(ApplyFilters(mails, FilterOpts{SenderDomain: tt.domain}))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ApplyFilters(SenderDomain: %q) = %v, want %v", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Supported --credits-format values
+const (
+	CreditsFormatPlain  = "plain"
+	CreditsFormatComma  = "comma"
+	CreditsFormatAbbrev = "abbrev"
+)
+
+// creditsFormat is the format used by FormatCredits, set from --credits-format
+var creditsFormat = CreditsFormatComma
+
+// setCreditsFormat validates and selects the format used by FormatCredits
+func setCreditsFormat(format string) error {
+	switch format {
+	case CreditsFormatPlain, CreditsFormatComma, CreditsFormatAbbrev:
+		creditsFormat = format
+		return nil
+	default:
+		return fmt.Errorf("unsupported credits format %q, expected plain, comma or abbrev", format)
+	}
+}
+
+// FormatCredits formats a credit amount for human-readable output,
+// e.g. "1,234,567,890 cr" with the default comma format
+func FormatCredits(amount int64) string {
+	return formatCreditsAs(amount, creditsFormat)
+}
+
+// formatCreditsAs formats a credit amount in the given format
+func formatCreditsAs(amount int64, format string) string {
+	switch format {
+	case CreditsFormatPlain:
+		return strconv.FormatInt(amount, 10) + " cr"
+	case CreditsFormatAbbrev:
+		return abbreviate(amount) + " cr"
+	default:
+		return formatThousands(amount) + " cr"
+	}
+}
+
+// creditUnits are the suffixes used by the abbrev format, smallest first
+var creditUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"K", 1e3},
+	{"M", 1e6},
+	{"B", 1e9},
+	{"T", 1e12},
+}
+
+// abbreviate shortens an integer with a K, M, B or T suffix and one
+// decimal, e.g. 1234567890 becomes "1.2B". Values below 1000 are unchanged.
+func abbreviate(n int64) string {
+	sign := ""
+	value := float64(n)
+	if n < 0 {
+		sign, value = "-", -value
+	}
+
+	if value < 1e3 {
+		return strconv.FormatInt(n, 10)
+	}
+
+	// Pick the largest unit, moving up when rounding would show 1000.0
+	unit := creditUnits[0]
+	for _, next := range creditUnits[1:] {
+		if value/unit.size < 999.95 {
+			break
+		}
+		unit = next
+	}
+
+	text := strconv.FormatFloat(value/unit.size, 'f', 1, 64)
+	return sign + strings.TrimSuffix(text, ".0") + unit.suffix
+}
+
+// formatThousands formats an integer with comma thousands separators
+func formatThousands(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+
+	return sign + sb.String()
+}
+
+// signedCredits formats a credit difference with an explicit sign,
+// e.g. "+1,000 cr" or "-250 cr"
+func signedCredits(delta int64) string {
+	if delta >= 0 {
+		return "+" + FormatCredits(delta)
+	}
+	return FormatCredits(delta)
+}
+
+// snakeToCamel converts a snake_case key to camelCase, e.g. "mail_id"
+// becomes "mailId"
+func snakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	var sb strings.Builder
+	sb.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+// influxTagEscaper escapes the characters InfluxDB line protocol does not
+// allow unescaped in tag keys and values
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// ToInfluxLine formats a mail as an InfluxDB line protocol line. Sales are
+// written to the swg_sale measurement with planet, item and character tags,
+// all other mails to swg_mail with a mail count of one. Empty tags are left
+// out, as line protocol does not allow empty tag values.
+func ToInfluxLine(mail MailData) string {
+	timestamp := strconv.FormatInt(mail.Timestamp.UnixNano(), 10)
+	if mail.MailCategory != CategorySale {
+		return "swg_mail mail_count=1i " + timestamp
+	}
+
+	var sb strings.Builder
+	sb.WriteString("swg_sale")
+	for _, tag := range []struct{ key, value string }{
+		{"planet", mail.Planet},
+		{"item", mail.ItemName},
+		{"character", mail.Character},
+	} {
+		if tag.value != "" {
+			sb.WriteString("," + tag.key + "=" + influxTagEscaper.Replace(tag.value))
+		}
+	}
+	price := strconv.FormatInt(mail.Price, 10)
+	sb.WriteString(" price=" + price + "i,revenue=" + price + "i " + timestamp)
+	return sb.String()
+}
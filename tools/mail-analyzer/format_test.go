@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFormatCreditsAs(t *testing.T) {
+	tests := []struct {
+		amount int64
+		plain  string
+		comma  string
+		abbrev string
+	}{
+		{0, "0 cr", "0 cr", "0 cr"},
+		{999, "999 cr", "999 cr", "999 cr"},
+		{1000, "1000 cr", "1,000 cr", "1K cr"},
+		{1500, "1500 cr", "1,500 cr", "1.5K cr"},
+		{1000000, "1000000 cr", "1,000,000 cr", "1M cr"},
+		{1234567890, "1234567890 cr", "1,234,567,890 cr", "1.2B cr"},
+		{-999, "-999 cr", "-999 cr", "-999 cr"},
+		{-1000, "-1000 cr", "-1,000 cr", "-1K cr"},
+		{-1234567, "-1234567 cr", "-1,234,567 cr", "-1.2M cr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.FormatInt(tt.amount, 10), func(t *testing.T) {
+			for format, want := range map[string]string{
+				CreditsFormatPlain:  tt.plain,
+				CreditsFormatComma:  tt.comma,
+				CreditsFormatAbbrev: tt.abbrev,
+			} {
+				if got := formatCreditsAs(tt.amount, format); got != want {
+					t.Errorf("formatCreditsAs(%d, %q) = %q, want %q", tt.amount, format, got, want)
+				}
+			}
+			// The comma format is the default of FormatCredits
+			if got := FormatCredits(tt.amount); got != tt.comma {
+				t.Errorf("FormatCredits(%d) = %q, want %q", tt.amount, got, tt.comma)
+			}
+		})
+	}
+}
+
+func TestAbbreviate(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{999, "999"},
+		{1049, "1K"},
+		{1050, "1.1K"},
+		// Values that round to 1000.0 of a unit move up to the next one
+		{999949, "999.9K"},
+		{999950, "1M"},
+		{-999950, "-1M"},
+		{999949999, "999.9M"},
+		{999950000, "1B"},
+		{999950000000, "1T"},
+		// There is no unit above T
+		{5000000000000000, "5000T"},
+	}
+
+	for _, tt := range tests {
+		if got := abbreviate(tt.n); got != tt.want {
+			t.Errorf("abbreviate(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestToInfluxLine(t *testing.T) {
+	at := time.Unix(1705312800, 0)
+	timestamp := strconv.FormatInt(at.UnixNano(), 10)
+
+	tests := []struct {
+		name string
+		mail MailData
+		want string
+	}{
+		{
+			name: "sale",
+			mail: MailData{Timestamp: at, MailCategory: CategorySale, Planet: "Tatooine", ItemName: "Rifle", Character: "Crafter", Price: 1000},
+			want: "swg_sale,planet=Tatooine,item=Rifle,character=Crafter price=1000i,revenue=1000i " + timestamp,
+		},
+		{
+			name: "sale without planet and character",
+			mail: MailData{Timestamp: at, MailCategory: CategorySale, ItemName: "Rifle", Price: 1000},
+			want: "swg_sale,item=Rifle price=1000i,revenue=1000i " + timestamp,
+		},
+		{
+			name: "escaped tag values",
+			mail: MailData{Timestamp: at, MailCategory: CategorySale, Planet: "Yavin 4", ItemName: "Rifle, Mk=2", Price: 5},
+			want: `swg_sale,planet=Yavin\ 4,item=Rifle\,\ Mk\=2 price=5i,revenue=5i ` + timestamp,
+		},
+		{
+			name: "other mail",
+			mail: MailData{Timestamp: at, MailCategory: CategoryPlayer, Planet: "Tatooine", ItemName: "Rifle"},
+			want: "swg_mail mail_count=1i " + timestamp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToInfluxLine(tt.mail); got != tt.want {
+				t.Errorf("ToInfluxLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnakeToCamel(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"mail_id", "mailId"},
+		{"sender", "sender"},
+		{"vendor_to_bazaar_ratio", "vendorToBazaarRatio"},
+		{"trailing_", "trailing"},
+	}
+
+	for _, tt := range tests {
+		if got := snakeToCamel(tt.key); got != tt.want {
+			t.Errorf("snakeToCamel(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
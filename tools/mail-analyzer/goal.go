@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// goalWindow is the trailing period used to estimate daily revenue
+const goalWindow = 30 * 24 * time.Hour
+
+// goalProgressPercent returns how much of the goal has been earned, in percent
+func goalProgressPercent(earned, goal int64) float64 {
+	if goal <= 0 {
+		return 0
+	}
+	return float64(earned) / float64(goal) * 100
+}
+
+// averageDailyRevenue returns the average revenue per day over the last 30
+// days of data, or over the whole date range if it is shorter
+func averageDailyRevenue(mails []MailData, dateRange DateRange) float64 {
+	window := min(goalWindow, dateRange.EndDate.Sub(dateRange.StartDate))
+	window = max(window, 24*time.Hour)
+	windowStart := dateRange.EndDate.Add(-window)
+
+	var revenue int64
+	for _, mail := range mails {
+		if mail.Timestamp.After(windowStart) {
+			revenue += mail.Price
+		}
+	}
+
+	return float64(revenue) / (window.Hours() / 24)
+}
+
+// estimateGoalETA estimates when the goal is reached at the recent daily
+// revenue rate, starting from the last mail. It reports false if the goal
+// is already reached or there is no recent revenue.
+func estimateGoalETA(mails []MailData, stats MailStats, goal int64) (time.Time, bool) {
+	remaining := goal - stats.TotalRevenue
+	if remaining <= 0 {
+		return time.Time{}, false
+	}
+
+	daily := averageDailyRevenue(mails, stats.DateRange)
+	if daily <= 0 {
+		return time.Time{}, false
+	}
+
+	days := float64(remaining) / daily
+	return stats.DateRange.EndDate.Add(time.Duration(days * float64(24*time.Hour))), true
+}
+
+// formatGoalProgress renders a progress line such as
+// "Goal: 1,000,000 cr | Earned: 750,000 cr | 75.0% [########..] ETA: 2024-03-15"
+func formatGoalProgress(goal, earned int64, eta time.Time, hasETA bool) string {
+	percent := goalProgressPercent(earned, goal)
+
+	filled := min(int(percent/10), 10)
+	bar := strings.Repeat("#", filled) + strings.Repeat(".", 10-filled)
+
+	etaText := "n/a"
+	if earned >= goal {
+		etaText = "reached"
+	} else if hasETA {
+		etaText = eta.Format("2006-01-02")
+	}
+
+	return fmt.Sprintf("Goal: %s | Earned: %s | %.1f%% [%s] ETA: %s",
+		FormatCredits(goal), FormatCredits(earned), percent, bar, etaText)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGoalProgressPercent(t *testing.T) {
+	tests := []struct {
+		earned, goal int64
+		want         float64
+	}{
+		{750, 1000, 75},
+		{0, 1000, 0},
+		{1500, 1000, 150},
+		// No goal set
+		{750, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := goalProgressPercent(tt.earned, tt.goal); got != tt.want {
+			t.Errorf("goalProgressPercent(%d, %d) = %v, want %v", tt.earned, tt.goal, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateGoalETA(t *testing.T) {
+	// A sale of 100 credits per day from January 1 to 11
+	var sales []MailData
+	for day := 1; day <= 11; day++ {
+		sales = append(sales, testSale("sale", date(2024, time.January, day), "Rifle", "Han", 100))
+	}
+	// The same days without revenue
+	var letters []MailData
+	for day := 1; day <= 11; day++ {
+		letters = append(letters, MailData{MailID: "letter", Timestamp: date(2024, time.January, day)})
+	}
+
+	tests := []struct {
+		name    string
+		mails   []MailData
+		goal    int64
+		want    time.Time
+		wantETA bool
+	}{
+		// 1,000 credits to go at 100 credits a day
+		{"goal ahead", sales, 2100, date(2024, time.January, 21), true},
+		{"goal reached", sales, 1000, time.Time{}, false},
+		{"no revenue", letters, 1000, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := generateMailStats(tt.mails)
+			got, ok := estimateGoalETA(tt.mails, stats, tt.goal)
+			if ok != tt.wantETA || !got.Equal(tt.want) {
+				t.Errorf("estimateGoalETA() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantETA)
+			}
+		})
+	}
+}
+
+func TestFormatGoalProgress(t *testing.T) {
+	eta := date(2024, time.January, 21)
+
+	tests := []struct {
+		name   string
+		goal   int64
+		earned int64
+		hasETA bool
+		want   string
+	}{
+		{"with ETA", 1000, 750, true, "Goal: 1,000 cr | Earned: 750 cr | 75.0% [#######...] ETA: 2024-01-21"},
+		{"without revenue", 1000, 0, false, "Goal: 1,000 cr | Earned: 0 cr | 0.0% [..........] ETA: n/a"},
+		{"reached", 1000, 1500, false, "Goal: 1,000 cr | Earned: 1,500 cr | 150.0% [##########] ETA: reached"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatGoalProgress(tt.goal, tt.earned, eta, tt.hasETA); got != tt.want {
+				t.Errorf("formatGoalProgress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// itemKeyRule rewrites every match of Pattern in an item name with Replace,
+// which may refer to capture groups as in regexp.ReplaceAllString
+type itemKeyRule struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// defaultItemKeyRules strip the parts of item names that differ between
+// otherwise identical items. They are applied in order.
+var defaultItemKeyRules = []itemKeyRule{
+	// Stack counts, e.g. "(100000) Polysteel Copper", "Copper Ore (x20)",
+	// "Copper Ore x20" or "20x Copper Ore"
+	{regexp.MustCompile(`^\(\d+\)\s+`), ""},
+	{regexp.MustCompile(`(?i)\s*\(x?\d+\)\s*$`), ""},
+	{regexp.MustCompile(`(?i)\s+x\d+\s*$`), ""},
+	{regexp.MustCompile(`(?i)^\d+\s*x\s+`), ""},
+	// Serial numbers, e.g. "Heavy Blaster #a1b2c3", "Heavy Blaster (SN: 1234)"
+	// or "Heavy Blaster (serial: xyz123)"
+	{regexp.MustCompile(`\s*#\w+\s*$`), ""},
+	{regexp.MustCompile(`(?i)\s*\(S/?N:?\s*\w+\)\s*$`), ""},
+	{regexp.MustCompile(`(?i)\s*\(serial:[^)]*\)\s*$`), ""},
+	// Resources crafted into the item, e.g. "Durasteel Plating (Resource: Thoranium Steel)"
+	{regexp.MustCompile(`(?i)\s*\(resource:[^)]*\)\s*$`), ""},
+	// Experimented values, e.g. "Mark III Durasteel Plating (966.4)"
+	{regexp.MustCompile(`\s*\(\d+(?:\.\d+)?\)\s*$`), ""},
+}
+
+// normalizeItemKey returns the key an item name is grouped under. Rules are
+// applied in order, then whitespace is collapsed. Names that a rule would
+// reduce to nothing are kept as they are.
+func normalizeItemKey(name string, rules []itemKeyRule) string {
+	key := name
+	for _, rule := range rules {
+		key = rule.Pattern.ReplaceAllString(key, rule.Replace)
+	}
+
+	key = strings.Join(strings.Fields(key), " ")
+	if key == "" {
+		return strings.TrimSpace(name)
+	}
+	return key
+}
+
+// loadItemKeyRules reads a JSON array of {"pattern": ..., "replace": ...}
+// objects that replace defaultItemKeyRules
+func loadItemKeyRules(path string) ([]itemKeyRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item key rules: %w", err)
+	}
+
+	var raw []struct {
+		Pattern string `json:"pattern"`
+		Replace string `json:"replace"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse item key rules: %w", err)
+	}
+
+	rules := make([]itemKeyRule, 0, len(raw))
+	for i, r := range raw {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in item key rule %d: %w", i+1, err)
+		}
+		rules = append(rules, itemKeyRule{Pattern: pattern, Replace: r.Replace})
+	}
+	return rules, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import "regexp"
+
+// localizedPatterns recognize the sale notifications written by the German
+// and French clients. They are applied to every mail after the built-in
+// English patterns; each only matches mails in its own language.
+//
+// Expected German formats:
+// "Verkauf abgeschlossen"
+// "Ihre Auktion von [SEA] ItemName wurde an BuyerName für 30.000 Credits verkauft."
+// "Verkäufer: VendorName hat [SEA] ItemName an BuyerName für 30.000 Credits verkauft."
+// "Der Verkauf fand in LocationName auf PlanetName statt."
+//
+// Expected French formats:
+// "Vente terminée"
+// "Votre enchère de [SEA] ItemName a été vendue à BuyerName pour 30 000 crédits."
+// "Vendeur : VendorName a vendu [SEA] ItemName à BuyerName pour 30 000 crédits."
+// "La vente a eu lieu à LocationName, sur PlanetName."
+var localizedPatterns = []*serverPatterns{
+	{
+		AuctioneerSenders: []string{auctioneerSender},
+		SaleSubject:       regexp.MustCompile(`Verkauf abgeschlossen`),
+		SaleBodies: []*regexp.Regexp{
+			regexp.MustCompile(`Ihre Auktion von (?:\[.*?\] )?(?P<item>.*?) wurde an (?P<buyer>.*?) für (?P<price>[\d., \x{00a0}\x{202f}]+?) Credits verkauft`),
+			regexp.MustCompile(`Verkäufer: (?P<vendor>.*?) hat (?:\[.*?\] )?(?P<item>.*?) an (?P<buyer>.*?) für (?P<price>[\d., \x{00a0}\x{202f}]+?) Credits verkauft`),
+		},
+		Location: regexp.MustCompile(`Der Verkauf fand in (?P<city>.*?) auf (?P<planet>.*?) statt\.`),
+	},
+	{
+		AuctioneerSenders: []string{auctioneerSender},
+		SaleSubject:       regexp.MustCompile(`Vente terminée`),
+		SaleBodies: []*regexp.Regexp{
+			regexp.MustCompile(`Votre enchère de (?:\[.*?\] )?(?P<item>.*?) a été vendue? à (?P<buyer>.*?) pour (?P<price>[\d., \x{00a0}\x{202f}]+?) crédits`),
+			regexp.MustCompile(`Vendeur ?: (?P<vendor>.*?) a vendu (?:\[.*?\] )?(?P<item>.*?) à (?P<buyer>.*?) pour (?P<price>[\d., \x{00a0}\x{202f}]+?) crédits`),
+		},
+		Location: regexp.MustCompile(`La vente a eu lieu à (?P<city>.*?), sur (?P<planet>.*?)\.`),
+	},
+}
@@ -0,0 +1,105 @@
+package main
+
+import "strings"
+
+// planetNames matches the ground planet names in mail text, for use in
+// case-insensitive patterns
+const planetNames = `tatooine|naboo|corellia|talus|rori|dantooine|lok|yavin ?(?:4|iv)|endor|dathomir|kashyyyk|mustafar`
+
+// canonicalPlanets maps lowercase planet names and common abbreviations to
+// the planet names used in reports
+var canonicalPlanets = map[string]string{
+	"tatooine":  "Tatooine",
+	"tat":       "Tatooine",
+	"tatt":      "Tatooine",
+	"naboo":     "Naboo",
+	"nab":       "Naboo",
+	"corellia":  "Corellia",
+	"cor":       "Corellia",
+	"corl":      "Corellia",
+	"talus":     "Talus",
+	"rori":      "Rori",
+	"dantooine": "Dantooine",
+	"dant":      "Dantooine",
+	"lok":       "Lok",
+	"yavin iv":  "Yavin IV",
+	"yavin 4":   "Yavin IV",
+	"yavin4":    "Yavin IV",
+	"yavin":     "Yavin IV",
+	"yav":       "Yavin IV",
+	"endor":     "Endor",
+	"dathomir":  "Dathomir",
+	"dath":      "Dathomir",
+	"kashyyyk":  "Kashyyyk",
+	"kash":      "Kashyyyk",
+	"mustafar":  "Mustafar",
+	"must":      "Mustafar",
+}
+
+// npcCity is the canonical spelling of an NPC city and the planet it is on
+type npcCity struct {
+	Name   string
+	Planet string
+}
+
+// npcCities maps the lowercase names of well-known NPC cities, so that
+// their spelling is consistent and the planet is known even when a mail
+// only names the city
+var npcCities = map[string]npcCity{
+	"mos eisley":             {"Mos Eisley", "Tatooine"},
+	"mos espa":               {"Mos Espa", "Tatooine"},
+	"mos entha":              {"Mos Entha", "Tatooine"},
+	"mos taike":              {"Mos Taike", "Tatooine"},
+	"anchorhead":             {"Anchorhead", "Tatooine"},
+	"bestine":                {"Bestine", "Tatooine"},
+	"wayfar":                 {"Wayfar", "Tatooine"},
+	"theed":                  {"Theed", "Naboo"},
+	"keren":                  {"Keren", "Naboo"},
+	"moenia":                 {"Moenia", "Naboo"},
+	"kaadara":                {"Kaadara", "Naboo"},
+	"deeja peak":             {"Deeja Peak", "Naboo"},
+	"lake retreat":           {"Lake Retreat", "Naboo"},
+	"coronet":                {"Coronet", "Corellia"},
+	"tyrena":                 {"Tyrena", "Corellia"},
+	"kor vella":              {"Kor Vella", "Corellia"},
+	"doaba guerfel":          {"Doaba Guerfel", "Corellia"},
+	"bela vistal":            {"Bela Vistal", "Corellia"},
+	"vreni island":           {"Vreni Island", "Corellia"},
+	"dearic":                 {"Dearic", "Talus"},
+	"nashal":                 {"Nashal", "Talus"},
+	"narmle":                 {"Narmle", "Rori"},
+	"restuss":                {"Restuss", "Rori"},
+	"nym's stronghold":       {"Nym's Stronghold", "Lok"},
+	"kachirho":               {"Kachirho", "Kashyyyk"},
+	"mensix mining facility": {"Mensix Mining Facility", "Mustafar"},
+}
+
+// normalizeLocation canonicalizes the planet and city of a location: planet
+// names are capitalized consistently and abbreviations expanded, NPC city
+// names are spelled consistently, and the planet of a known NPC city is
+// filled in if missing. Unknown names are only trimmed.
+func normalizeLocation(city, planet string) (string, string) {
+	city = strings.TrimSpace(city)
+	planet = strings.TrimSpace(planet)
+
+	if canonical, ok := canonicalPlanets[strings.ToLower(strings.Join(strings.Fields(planet), " "))]; ok {
+		planet = canonical
+	}
+	if known, ok := npcCities[strings.ToLower(strings.Join(strings.Fields(city), " "))]; ok {
+		city = known.Name
+		if planet == "" {
+			planet = known.Planet
+		}
+	}
+	return city, planet
+}
+
+// planetScene returns the lowercase scene name of a planet as used in
+// waypoints, e.g. "yavin4" for "Yavin IV" or "yavin iv"
+func planetScene(planet string) string {
+	_, planet = normalizeLocation("", planet)
+	if planet == "Yavin IV" {
+		return "yavin4"
+	}
+	return strings.ToLower(strings.Join(strings.Fields(planet), ""))
+}
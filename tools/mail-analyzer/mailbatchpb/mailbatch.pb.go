@@ -0,0 +1,2020 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: mailbatch.proto
+
+package mailbatchpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MailBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SchemaVersion int32                  `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	Mails         []*MailData            `protobuf:"bytes,2,rep,name=mails,proto3" json:"mails,omitempty"`
+	Stats         *MailStats             `protobuf:"bytes,3,opt,name=stats,proto3" json:"stats,omitempty"`
+	Merge         *MergeInfo             `protobuf:"bytes,4,opt,name=merge,proto3" json:"merge,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MailBatch) Reset() {
+	*x = MailBatch{}
+	mi := &file_mailbatch_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MailBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MailBatch) ProtoMessage() {}
+
+func (x *MailBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MailBatch.ProtoReflect.Descriptor instead.
+func (*MailBatch) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MailBatch) GetSchemaVersion() int32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *MailBatch) GetMails() []*MailData {
+	if x != nil {
+		return x.Mails
+	}
+	return nil
+}
+
+func (x *MailBatch) GetStats() *MailStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *MailBatch) GetMerge() *MergeInfo {
+	if x != nil {
+		return x.Merge
+	}
+	return nil
+}
+
+type MailData struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	MailId            string                 `protobuf:"bytes,1,opt,name=mail_id,json=mailId,proto3" json:"mail_id,omitempty"`
+	Sender            string                 `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
+	Subject           string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	Timestamp         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Body              string                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	Location          string                 `protobuf:"bytes,6,opt,name=location,proto3" json:"location,omitempty"`
+	City              string                 `protobuf:"bytes,7,opt,name=city,proto3" json:"city,omitempty"`
+	Planet            string                 `protobuf:"bytes,8,opt,name=planet,proto3" json:"planet,omitempty"`
+	Tags              []string               `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	MailIdNormalized  string                 `protobuf:"bytes,10,opt,name=mail_id_normalized,json=mailIdNormalized,proto3" json:"mail_id_normalized,omitempty"`
+	ContentHash       string                 `protobuf:"bytes,11,opt,name=content_hash,json=contentHash,proto3" json:"content_hash,omitempty"`
+	Galaxy            string                 `protobuf:"bytes,12,opt,name=galaxy,proto3" json:"galaxy,omitempty"`
+	Source            string                 `protobuf:"bytes,13,opt,name=source,proto3" json:"source,omitempty"`
+	Recovered         bool                   `protobuf:"varint,14,opt,name=recovered,proto3" json:"recovered,omitempty"`
+	Character         string                 `protobuf:"bytes,15,opt,name=character,proto3" json:"character,omitempty"`
+	MailCategory      string                 `protobuf:"bytes,16,opt,name=mail_category,json=mailCategory,proto3" json:"mail_category,omitempty"`
+	MailType          string                 `protobuf:"bytes,17,opt,name=mail_type,json=mailType,proto3" json:"mail_type,omitempty"`
+	BroadcastName     string                 `protobuf:"bytes,18,opt,name=broadcast_name,json=broadcastName,proto3" json:"broadcast_name,omitempty"`
+	SaleType          string                 `protobuf:"bytes,19,opt,name=sale_type,json=saleType,proto3" json:"sale_type,omitempty"`
+	NormalizedSubject string                 `protobuf:"bytes,20,opt,name=normalized_subject,json=normalizedSubject,proto3" json:"normalized_subject,omitempty"`
+	SenderDomain      string                 `protobuf:"bytes,21,opt,name=sender_domain,json=senderDomain,proto3" json:"sender_domain,omitempty"`
+	SenderSubsystem   string                 `protobuf:"bytes,22,opt,name=sender_subsystem,json=senderSubsystem,proto3" json:"sender_subsystem,omitempty"`
+	SenderLabel       string                 `protobuf:"bytes,23,opt,name=sender_label,json=senderLabel,proto3" json:"sender_label,omitempty"`
+	ItemName          string                 `protobuf:"bytes,24,opt,name=item_name,json=itemName,proto3" json:"item_name,omitempty"`
+	CanonicalItemName string                 `protobuf:"bytes,25,opt,name=canonical_item_name,json=canonicalItemName,proto3" json:"canonical_item_name,omitempty"`
+	Buyer             string                 `protobuf:"bytes,26,opt,name=buyer,proto3" json:"buyer,omitempty"`
+	Price             int64                  `protobuf:"varint,27,opt,name=price,proto3" json:"price,omitempty"`
+	ItemKey           string                 `protobuf:"bytes,28,opt,name=item_key,json=itemKey,proto3" json:"item_key,omitempty"`
+	ItemCategory      string                 `protobuf:"bytes,29,opt,name=item_category,json=itemCategory,proto3" json:"item_category,omitempty"`
+	SerialNumber      string                 `protobuf:"bytes,30,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	UnitCount         int64                  `protobuf:"varint,31,opt,name=unit_count,json=unitCount,proto3" json:"unit_count,omitempty"`
+	PricePerUnit      float64                `protobuf:"fixed64,32,opt,name=price_per_unit,json=pricePerUnit,proto3" json:"price_per_unit,omitempty"`
+	VendorName        string                 `protobuf:"bytes,33,opt,name=vendor_name,json=vendorName,proto3" json:"vendor_name,omitempty"`
+	PriceType         string                 `protobuf:"bytes,34,opt,name=price_type,json=priceType,proto3" json:"price_type,omitempty"`
+	Sale              *Sale                  `protobuf:"bytes,35,opt,name=sale,proto3" json:"sale,omitempty"`
+	Purchase          *Purchase              `protobuf:"bytes,36,opt,name=purchase,proto3" json:"purchase,omitempty"`
+	Auction           *Auction               `protobuf:"bytes,37,opt,name=auction,proto3" json:"auction,omitempty"`
+	Expired           *ExpiredItem           `protobuf:"bytes,38,opt,name=expired,proto3" json:"expired,omitempty"`
+	FactoryRun        *FactoryRun            `protobuf:"bytes,39,opt,name=factory_run,json=factoryRun,proto3" json:"factory_run,omitempty"`
+	Income            *Income                `protobuf:"bytes,40,opt,name=income,proto3" json:"income,omitempty"`
+	SurveyResults     []*SurveyResult        `protobuf:"bytes,41,rep,name=survey_results,json=surveyResults,proto3" json:"survey_results,omitempty"`
+	HasCoordinates    bool                   `protobuf:"varint,42,opt,name=has_coordinates,json=hasCoordinates,proto3" json:"has_coordinates,omitempty"`
+	LocationX         float64                `protobuf:"fixed64,43,opt,name=location_x,json=locationX,proto3" json:"location_x,omitempty"`
+	LocationY         float64                `protobuf:"fixed64,44,opt,name=location_y,json=locationY,proto3" json:"location_y,omitempty"`
+	LocationZ         float64                `protobuf:"fixed64,45,opt,name=location_z,json=locationZ,proto3" json:"location_z,omitempty"`
+	Waypoints         []*Waypoint            `protobuf:"bytes,46,rep,name=waypoints,proto3" json:"waypoints,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *MailData) Reset() {
+	*x = MailData{}
+	mi := &file_mailbatch_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MailData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MailData) ProtoMessage() {}
+
+func (x *MailData) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MailData.ProtoReflect.Descriptor instead.
+func (*MailData) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MailData) GetMailId() string {
+	if x != nil {
+		return x.MailId
+	}
+	return ""
+}
+
+func (x *MailData) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *MailData) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *MailData) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *MailData) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *MailData) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *MailData) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *MailData) GetPlanet() string {
+	if x != nil {
+		return x.Planet
+	}
+	return ""
+}
+
+func (x *MailData) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *MailData) GetMailIdNormalized() string {
+	if x != nil {
+		return x.MailIdNormalized
+	}
+	return ""
+}
+
+func (x *MailData) GetContentHash() string {
+	if x != nil {
+		return x.ContentHash
+	}
+	return ""
+}
+
+func (x *MailData) GetGalaxy() string {
+	if x != nil {
+		return x.Galaxy
+	}
+	return ""
+}
+
+func (x *MailData) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *MailData) GetRecovered() bool {
+	if x != nil {
+		return x.Recovered
+	}
+	return false
+}
+
+func (x *MailData) GetCharacter() string {
+	if x != nil {
+		return x.Character
+	}
+	return ""
+}
+
+func (x *MailData) GetMailCategory() string {
+	if x != nil {
+		return x.MailCategory
+	}
+	return ""
+}
+
+func (x *MailData) GetMailType() string {
+	if x != nil {
+		return x.MailType
+	}
+	return ""
+}
+
+func (x *MailData) GetBroadcastName() string {
+	if x != nil {
+		return x.BroadcastName
+	}
+	return ""
+}
+
+func (x *MailData) GetSaleType() string {
+	if x != nil {
+		return x.SaleType
+	}
+	return ""
+}
+
+func (x *MailData) GetNormalizedSubject() string {
+	if x != nil {
+		return x.NormalizedSubject
+	}
+	return ""
+}
+
+func (x *MailData) GetSenderDomain() string {
+	if x != nil {
+		return x.SenderDomain
+	}
+	return ""
+}
+
+func (x *MailData) GetSenderSubsystem() string {
+	if x != nil {
+		return x.SenderSubsystem
+	}
+	return ""
+}
+
+func (x *MailData) GetSenderLabel() string {
+	if x != nil {
+		return x.SenderLabel
+	}
+	return ""
+}
+
+func (x *MailData) GetItemName() string {
+	if x != nil {
+		return x.ItemName
+	}
+	return ""
+}
+
+func (x *MailData) GetCanonicalItemName() string {
+	if x != nil {
+		return x.CanonicalItemName
+	}
+	return ""
+}
+
+func (x *MailData) GetBuyer() string {
+	if x != nil {
+		return x.Buyer
+	}
+	return ""
+}
+
+func (x *MailData) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *MailData) GetItemKey() string {
+	if x != nil {
+		return x.ItemKey
+	}
+	return ""
+}
+
+func (x *MailData) GetItemCategory() string {
+	if x != nil {
+		return x.ItemCategory
+	}
+	return ""
+}
+
+func (x *MailData) GetSerialNumber() string {
+	if x != nil {
+		return x.SerialNumber
+	}
+	return ""
+}
+
+func (x *MailData) GetUnitCount() int64 {
+	if x != nil {
+		return x.UnitCount
+	}
+	return 0
+}
+
+func (x *MailData) GetPricePerUnit() float64 {
+	if x != nil {
+		return x.PricePerUnit
+	}
+	return 0
+}
+
+func (x *MailData) GetVendorName() string {
+	if x != nil {
+		return x.VendorName
+	}
+	return ""
+}
+
+func (x *MailData) GetPriceType() string {
+	if x != nil {
+		return x.PriceType
+	}
+	return ""
+}
+
+func (x *MailData) GetSale() *Sale {
+	if x != nil {
+		return x.Sale
+	}
+	return nil
+}
+
+func (x *MailData) GetPurchase() *Purchase {
+	if x != nil {
+		return x.Purchase
+	}
+	return nil
+}
+
+func (x *MailData) GetAuction() *Auction {
+	if x != nil {
+		return x.Auction
+	}
+	return nil
+}
+
+func (x *MailData) GetExpired() *ExpiredItem {
+	if x != nil {
+		return x.Expired
+	}
+	return nil
+}
+
+func (x *MailData) GetFactoryRun() *FactoryRun {
+	if x != nil {
+		return x.FactoryRun
+	}
+	return nil
+}
+
+func (x *MailData) GetIncome() *Income {
+	if x != nil {
+		return x.Income
+	}
+	return nil
+}
+
+func (x *MailData) GetSurveyResults() []*SurveyResult {
+	if x != nil {
+		return x.SurveyResults
+	}
+	return nil
+}
+
+func (x *MailData) GetHasCoordinates() bool {
+	if x != nil {
+		return x.HasCoordinates
+	}
+	return false
+}
+
+func (x *MailData) GetLocationX() float64 {
+	if x != nil {
+		return x.LocationX
+	}
+	return 0
+}
+
+func (x *MailData) GetLocationY() float64 {
+	if x != nil {
+		return x.LocationY
+	}
+	return 0
+}
+
+func (x *MailData) GetLocationZ() float64 {
+	if x != nil {
+		return x.LocationZ
+	}
+	return 0
+}
+
+func (x *MailData) GetWaypoints() []*Waypoint {
+	if x != nil {
+		return x.Waypoints
+	}
+	return nil
+}
+
+type Sale struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ItemName      string                 `protobuf:"bytes,1,opt,name=item_name,json=itemName,proto3" json:"item_name,omitempty"`
+	Buyer         string                 `protobuf:"bytes,2,opt,name=buyer,proto3" json:"buyer,omitempty"`
+	Price         int64                  `protobuf:"varint,3,opt,name=price,proto3" json:"price,omitempty"`
+	SaleChannel   string                 `protobuf:"bytes,4,opt,name=sale_channel,json=saleChannel,proto3" json:"sale_channel,omitempty"`
+	VendorName    string                 `protobuf:"bytes,6,opt,name=vendor_name,json=vendorName,proto3" json:"vendor_name,omitempty"`
+	Category      string                 `protobuf:"bytes,7,opt,name=category,proto3" json:"category,omitempty"`
+	SerialNumber  string                 `protobuf:"bytes,8,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	UnitCount     int64                  `protobuf:"varint,9,opt,name=unit_count,json=unitCount,proto3" json:"unit_count,omitempty"`
+	PricePerUnit  float64                `protobuf:"fixed64,10,opt,name=price_per_unit,json=pricePerUnit,proto3" json:"price_per_unit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sale) Reset() {
+	*x = Sale{}
+	mi := &file_mailbatch_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sale) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sale) ProtoMessage() {}
+
+func (x *Sale) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sale.ProtoReflect.Descriptor instead.
+func (*Sale) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Sale) GetItemName() string {
+	if x != nil {
+		return x.ItemName
+	}
+	return ""
+}
+
+func (x *Sale) GetBuyer() string {
+	if x != nil {
+		return x.Buyer
+	}
+	return ""
+}
+
+func (x *Sale) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Sale) GetSaleChannel() string {
+	if x != nil {
+		return x.SaleChannel
+	}
+	return ""
+}
+
+func (x *Sale) GetVendorName() string {
+	if x != nil {
+		return x.VendorName
+	}
+	return ""
+}
+
+func (x *Sale) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Sale) GetSerialNumber() string {
+	if x != nil {
+		return x.SerialNumber
+	}
+	return ""
+}
+
+func (x *Sale) GetUnitCount() int64 {
+	if x != nil {
+		return x.UnitCount
+	}
+	return 0
+}
+
+func (x *Sale) GetPricePerUnit() float64 {
+	if x != nil {
+		return x.PricePerUnit
+	}
+	return 0
+}
+
+type Purchase struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ItemName      string                 `protobuf:"bytes,1,opt,name=item_name,json=itemName,proto3" json:"item_name,omitempty"`
+	Seller        string                 `protobuf:"bytes,2,opt,name=seller,proto3" json:"seller,omitempty"`
+	Price         int64                  `protobuf:"varint,3,opt,name=price,proto3" json:"price,omitempty"`
+	Location      string                 `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Purchase) Reset() {
+	*x = Purchase{}
+	mi := &file_mailbatch_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Purchase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Purchase) ProtoMessage() {}
+
+func (x *Purchase) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Purchase.ProtoReflect.Descriptor instead.
+func (*Purchase) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Purchase) GetItemName() string {
+	if x != nil {
+		return x.ItemName
+	}
+	return ""
+}
+
+func (x *Purchase) GetSeller() string {
+	if x != nil {
+		return x.Seller
+	}
+	return ""
+}
+
+func (x *Purchase) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Purchase) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+type Auction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         string                 `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	ItemName      string                 `protobuf:"bytes,2,opt,name=item_name,json=itemName,proto3" json:"item_name,omitempty"`
+	Bid           int64                  `protobuf:"varint,3,opt,name=bid,proto3" json:"bid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Auction) Reset() {
+	*x = Auction{}
+	mi := &file_mailbatch_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auction) ProtoMessage() {}
+
+func (x *Auction) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auction.ProtoReflect.Descriptor instead.
+func (*Auction) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Auction) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *Auction) GetItemName() string {
+	if x != nil {
+		return x.ItemName
+	}
+	return ""
+}
+
+func (x *Auction) GetBid() int64 {
+	if x != nil {
+		return x.Bid
+	}
+	return 0
+}
+
+type ExpiredItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ItemName      string                 `protobuf:"bytes,1,opt,name=item_name,json=itemName,proto3" json:"item_name,omitempty"`
+	Location      string                 `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpiredItem) Reset() {
+	*x = ExpiredItem{}
+	mi := &file_mailbatch_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpiredItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpiredItem) ProtoMessage() {}
+
+func (x *ExpiredItem) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpiredItem.ProtoReflect.Descriptor instead.
+func (*ExpiredItem) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExpiredItem) GetItemName() string {
+	if x != nil {
+		return x.ItemName
+	}
+	return ""
+}
+
+func (x *ExpiredItem) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+type FactoryRun struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Factory       string                 `protobuf:"bytes,1,opt,name=factory,proto3" json:"factory,omitempty"`
+	ItemName      string                 `protobuf:"bytes,2,opt,name=item_name,json=itemName,proto3" json:"item_name,omitempty"`
+	ItemKey       string                 `protobuf:"bytes,3,opt,name=item_key,json=itemKey,proto3" json:"item_key,omitempty"`
+	Quantity      int64                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FactoryRun) Reset() {
+	*x = FactoryRun{}
+	mi := &file_mailbatch_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FactoryRun) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FactoryRun) ProtoMessage() {}
+
+func (x *FactoryRun) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FactoryRun.ProtoReflect.Descriptor instead.
+func (*FactoryRun) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FactoryRun) GetFactory() string {
+	if x != nil {
+		return x.Factory
+	}
+	return ""
+}
+
+func (x *FactoryRun) GetItemName() string {
+	if x != nil {
+		return x.ItemName
+	}
+	return ""
+}
+
+func (x *FactoryRun) GetItemKey() string {
+	if x != nil {
+		return x.ItemKey
+	}
+	return ""
+}
+
+func (x *FactoryRun) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type Income struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Credits       int64                  `protobuf:"varint,2,opt,name=credits,proto3" json:"credits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Income) Reset() {
+	*x = Income{}
+	mi := &file_mailbatch_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Income) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Income) ProtoMessage() {}
+
+func (x *Income) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Income.ProtoReflect.Descriptor instead.
+func (*Income) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Income) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Income) GetCredits() int64 {
+	if x != nil {
+		return x.Credits
+	}
+	return 0
+}
+
+type SurveyResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Resource      string                 `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	Planet        string                 `protobuf:"bytes,2,opt,name=planet,proto3" json:"planet,omitempty"`
+	Concentration float64                `protobuf:"fixed64,3,opt,name=concentration,proto3" json:"concentration,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SurveyResult) Reset() {
+	*x = SurveyResult{}
+	mi := &file_mailbatch_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SurveyResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SurveyResult) ProtoMessage() {}
+
+func (x *SurveyResult) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SurveyResult.ProtoReflect.Descriptor instead.
+func (*SurveyResult) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SurveyResult) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *SurveyResult) GetPlanet() string {
+	if x != nil {
+		return x.Planet
+	}
+	return ""
+}
+
+func (x *SurveyResult) GetConcentration() float64 {
+	if x != nil {
+		return x.Concentration
+	}
+	return 0
+}
+
+type Waypoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Planet        string                 `protobuf:"bytes,1,opt,name=planet,proto3" json:"planet,omitempty"`
+	X             float64                `protobuf:"fixed64,2,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,3,opt,name=y,proto3" json:"y,omitempty"`
+	Z             float64                `protobuf:"fixed64,4,opt,name=z,proto3" json:"z,omitempty"`
+	Name          string                 `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Waypoint) Reset() {
+	*x = Waypoint{}
+	mi := &file_mailbatch_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Waypoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Waypoint) ProtoMessage() {}
+
+func (x *Waypoint) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Waypoint.ProtoReflect.Descriptor instead.
+func (*Waypoint) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Waypoint) GetPlanet() string {
+	if x != nil {
+		return x.Planet
+	}
+	return ""
+}
+
+func (x *Waypoint) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Waypoint) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Waypoint) GetZ() float64 {
+	if x != nil {
+		return x.Z
+	}
+	return 0
+}
+
+func (x *Waypoint) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type MailStats struct {
+	state                        protoimpl.MessageState  `protogen:"open.v1"`
+	TotalMails                   int64                   `protobuf:"varint,1,opt,name=total_mails,json=totalMails,proto3" json:"total_mails,omitempty"`
+	SaleNotifications            int64                   `protobuf:"varint,2,opt,name=sale_notifications,json=saleNotifications,proto3" json:"sale_notifications,omitempty"`
+	DateRange                    *DateRange              `protobuf:"bytes,3,opt,name=date_range,json=dateRange,proto3" json:"date_range,omitempty"`
+	Senders                      map[string]int64        `protobuf:"bytes,4,rep,name=senders,proto3" json:"senders,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	MailsBySubsystem             map[string]int64        `protobuf:"bytes,5,rep,name=mails_by_subsystem,json=mailsBySubsystem,proto3" json:"mails_by_subsystem,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	SubjectClusters              map[string]int64        `protobuf:"bytes,6,rep,name=subject_clusters,json=subjectClusters,proto3" json:"subject_clusters,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	MailsByCategory              map[string]int64        `protobuf:"bytes,7,rep,name=mails_by_category,json=mailsByCategory,proto3" json:"mails_by_category,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	MailsByType                  map[string]int64        `protobuf:"bytes,8,rep,name=mails_by_type,json=mailsByType,proto3" json:"mails_by_type,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	TotalRevenue                 int64                   `protobuf:"varint,9,opt,name=total_revenue,json=totalRevenue,proto3" json:"total_revenue,omitempty"`
+	KnownSystemMails             int64                   `protobuf:"varint,10,opt,name=known_system_mails,json=knownSystemMails,proto3" json:"known_system_mails,omitempty"`
+	UnknownSenderMails           int64                   `protobuf:"varint,11,opt,name=unknown_sender_mails,json=unknownSenderMails,proto3" json:"unknown_sender_mails,omitempty"`
+	VendorRevenue                int64                   `protobuf:"varint,12,opt,name=vendor_revenue,json=vendorRevenue,proto3" json:"vendor_revenue,omitempty"`
+	BazaarRevenue                int64                   `protobuf:"varint,13,opt,name=bazaar_revenue,json=bazaarRevenue,proto3" json:"bazaar_revenue,omitempty"`
+	VendorSaleCount              int64                   `protobuf:"varint,14,opt,name=vendor_sale_count,json=vendorSaleCount,proto3" json:"vendor_sale_count,omitempty"`
+	BazaarSaleCount              int64                   `protobuf:"varint,15,opt,name=bazaar_sale_count,json=bazaarSaleCount,proto3" json:"bazaar_sale_count,omitempty"`
+	VendorToBazaarRatio          float64                 `protobuf:"fixed64,16,opt,name=vendor_to_bazaar_ratio,json=vendorToBazaarRatio,proto3" json:"vendor_to_bazaar_ratio,omitempty"`
+	BidSaleCount                 int64                   `protobuf:"varint,17,opt,name=bid_sale_count,json=bidSaleCount,proto3" json:"bid_sale_count,omitempty"`
+	BuyNowSaleCount              int64                   `protobuf:"varint,18,opt,name=buy_now_sale_count,json=buyNowSaleCount,proto3" json:"buy_now_sale_count,omitempty"`
+	AvgBidPrice                  int64                   `protobuf:"varint,19,opt,name=avg_bid_price,json=avgBidPrice,proto3" json:"avg_bid_price,omitempty"`
+	AvgBuyNowPrice               int64                   `protobuf:"varint,20,opt,name=avg_buy_now_price,json=avgBuyNowPrice,proto3" json:"avg_buy_now_price,omitempty"`
+	PurchaseCount                int64                   `protobuf:"varint,21,opt,name=purchase_count,json=purchaseCount,proto3" json:"purchase_count,omitempty"`
+	PurchaseSpending             int64                   `protobuf:"varint,22,opt,name=purchase_spending,json=purchaseSpending,proto3" json:"purchase_spending,omitempty"`
+	OtherIncome                  int64                   `protobuf:"varint,23,opt,name=other_income,json=otherIncome,proto3" json:"other_income,omitempty"`
+	OtherIncomeBySource          map[string]int64        `protobuf:"bytes,24,rep,name=other_income_by_source,json=otherIncomeBySource,proto3" json:"other_income_by_source,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	AuctionsWon                  int64                   `protobuf:"varint,25,opt,name=auctions_won,json=auctionsWon,proto3" json:"auctions_won,omitempty"`
+	AuctionsOutbid               int64                   `protobuf:"varint,26,opt,name=auctions_outbid,json=auctionsOutbid,proto3" json:"auctions_outbid,omitempty"`
+	AuctionWonSpending           int64                   `protobuf:"varint,27,opt,name=auction_won_spending,json=auctionWonSpending,proto3" json:"auction_won_spending,omitempty"`
+	ExpiredCount                 int64                   `protobuf:"varint,28,opt,name=expired_count,json=expiredCount,proto3" json:"expired_count,omitempty"`
+	FactoryRuns                  int64                   `protobuf:"varint,29,opt,name=factory_runs,json=factoryRuns,proto3" json:"factory_runs,omitempty"`
+	UnitsProducedByItem          map[string]int64        `protobuf:"bytes,30,rep,name=units_produced_by_item,json=unitsProducedByItem,proto3" json:"units_produced_by_item,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Vendors                      map[string]*VendorStats `protobuf:"bytes,31,rep,name=vendors,proto3" json:"vendors,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SalesByItemCategory          map[string]int64        `protobuf:"bytes,32,rep,name=sales_by_item_category,json=salesByItemCategory,proto3" json:"sales_by_item_category,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RevenueByItemCategory        map[string]int64        `protobuf:"bytes,33,rep,name=revenue_by_item_category,json=revenueByItemCategory,proto3" json:"revenue_by_item_category,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	GoalProgress                 float64                 `protobuf:"fixed64,34,opt,name=goal_progress,json=goalProgress,proto3" json:"goal_progress,omitempty"`
+	AvgInterSaleIntervalHours    float64                 `protobuf:"fixed64,35,opt,name=avg_inter_sale_interval_hours,json=avgInterSaleIntervalHours,proto3" json:"avg_inter_sale_interval_hours,omitempty"`
+	MedianInterSaleIntervalHours float64                 `protobuf:"fixed64,36,opt,name=median_inter_sale_interval_hours,json=medianInterSaleIntervalHours,proto3" json:"median_inter_sale_interval_hours,omitempty"`
+	ItemDemandIndex              map[string]float64      `protobuf:"bytes,37,rep,name=item_demand_index,json=itemDemandIndex,proto3" json:"item_demand_index,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	MailCountByPlanet            map[string]int64        `protobuf:"bytes,38,rep,name=mail_count_by_planet,json=mailCountByPlanet,proto3" json:"mail_count_by_planet,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RevenueByPlanet              map[string]int64        `protobuf:"bytes,39,rep,name=revenue_by_planet,json=revenueByPlanet,proto3" json:"revenue_by_planet,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	MailCountByCity              map[string]int64        `protobuf:"bytes,40,rep,name=mail_count_by_city,json=mailCountByCity,proto3" json:"mail_count_by_city,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RevenueByCity                map[string]int64        `protobuf:"bytes,41,rep,name=revenue_by_city,json=revenueByCity,proto3" json:"revenue_by_city,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	MailsByGalaxy                map[string]int64        `protobuf:"bytes,42,rep,name=mails_by_galaxy,json=mailsByGalaxy,proto3" json:"mails_by_galaxy,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RevenueByGalaxy              map[string]int64        `protobuf:"bytes,43,rep,name=revenue_by_galaxy,json=revenueByGalaxy,proto3" json:"revenue_by_galaxy,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	MailsBySource                map[string]int64        `protobuf:"bytes,44,rep,name=mails_by_source,json=mailsBySource,proto3" json:"mails_by_source,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	MailCountByCharacter         map[string]int64        `protobuf:"bytes,45,rep,name=mail_count_by_character,json=mailCountByCharacter,proto3" json:"mail_count_by_character,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RevenueByCharacter           map[string]int64        `protobuf:"bytes,46,rep,name=revenue_by_character,json=revenueByCharacter,proto3" json:"revenue_by_character,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	AnnouncementsCollapsed       int64                   `protobuf:"varint,47,opt,name=announcements_collapsed,json=announcementsCollapsed,proto3" json:"announcements_collapsed,omitempty"`
+	RetriedFiles                 int64                   `protobuf:"varint,48,opt,name=retried_files,json=retriedFiles,proto3" json:"retried_files,omitempty"`
+	RecoveredMails               int64                   `protobuf:"varint,49,opt,name=recovered_mails,json=recoveredMails,proto3" json:"recovered_mails,omitempty"`
+	DuplicateMails               int64                   `protobuf:"varint,50,opt,name=duplicate_mails,json=duplicateMails,proto3" json:"duplicate_mails,omitempty"`
+	SequentialGapCount           int64                   `protobuf:"varint,51,opt,name=sequential_gap_count,json=sequentialGapCount,proto3" json:"sequential_gap_count,omitempty"`
+	MissingIdCount               int64                   `protobuf:"varint,52,opt,name=missing_id_count,json=missingIdCount,proto3" json:"missing_id_count,omitempty"`
+	ContentDuplicateMails        int64                   `protobuf:"varint,53,opt,name=content_duplicate_mails,json=contentDuplicateMails,proto3" json:"content_duplicate_mails,omitempty"`
+	unknownFields                protoimpl.UnknownFields
+	sizeCache                    protoimpl.SizeCache
+}
+
+func (x *MailStats) Reset() {
+	*x = MailStats{}
+	mi := &file_mailbatch_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MailStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MailStats) ProtoMessage() {}
+
+func (x *MailStats) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MailStats.ProtoReflect.Descriptor instead.
+func (*MailStats) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MailStats) GetTotalMails() int64 {
+	if x != nil {
+		return x.TotalMails
+	}
+	return 0
+}
+
+func (x *MailStats) GetSaleNotifications() int64 {
+	if x != nil {
+		return x.SaleNotifications
+	}
+	return 0
+}
+
+func (x *MailStats) GetDateRange() *DateRange {
+	if x != nil {
+		return x.DateRange
+	}
+	return nil
+}
+
+func (x *MailStats) GetSenders() map[string]int64 {
+	if x != nil {
+		return x.Senders
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailsBySubsystem() map[string]int64 {
+	if x != nil {
+		return x.MailsBySubsystem
+	}
+	return nil
+}
+
+func (x *MailStats) GetSubjectClusters() map[string]int64 {
+	if x != nil {
+		return x.SubjectClusters
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailsByCategory() map[string]int64 {
+	if x != nil {
+		return x.MailsByCategory
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailsByType() map[string]int64 {
+	if x != nil {
+		return x.MailsByType
+	}
+	return nil
+}
+
+func (x *MailStats) GetTotalRevenue() int64 {
+	if x != nil {
+		return x.TotalRevenue
+	}
+	return 0
+}
+
+func (x *MailStats) GetKnownSystemMails() int64 {
+	if x != nil {
+		return x.KnownSystemMails
+	}
+	return 0
+}
+
+func (x *MailStats) GetUnknownSenderMails() int64 {
+	if x != nil {
+		return x.UnknownSenderMails
+	}
+	return 0
+}
+
+func (x *MailStats) GetVendorRevenue() int64 {
+	if x != nil {
+		return x.VendorRevenue
+	}
+	return 0
+}
+
+func (x *MailStats) GetBazaarRevenue() int64 {
+	if x != nil {
+		return x.BazaarRevenue
+	}
+	return 0
+}
+
+func (x *MailStats) GetVendorSaleCount() int64 {
+	if x != nil {
+		return x.VendorSaleCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetBazaarSaleCount() int64 {
+	if x != nil {
+		return x.BazaarSaleCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetVendorToBazaarRatio() float64 {
+	if x != nil {
+		return x.VendorToBazaarRatio
+	}
+	return 0
+}
+
+func (x *MailStats) GetBidSaleCount() int64 {
+	if x != nil {
+		return x.BidSaleCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetBuyNowSaleCount() int64 {
+	if x != nil {
+		return x.BuyNowSaleCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetAvgBidPrice() int64 {
+	if x != nil {
+		return x.AvgBidPrice
+	}
+	return 0
+}
+
+func (x *MailStats) GetAvgBuyNowPrice() int64 {
+	if x != nil {
+		return x.AvgBuyNowPrice
+	}
+	return 0
+}
+
+func (x *MailStats) GetPurchaseCount() int64 {
+	if x != nil {
+		return x.PurchaseCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetPurchaseSpending() int64 {
+	if x != nil {
+		return x.PurchaseSpending
+	}
+	return 0
+}
+
+func (x *MailStats) GetOtherIncome() int64 {
+	if x != nil {
+		return x.OtherIncome
+	}
+	return 0
+}
+
+func (x *MailStats) GetOtherIncomeBySource() map[string]int64 {
+	if x != nil {
+		return x.OtherIncomeBySource
+	}
+	return nil
+}
+
+func (x *MailStats) GetAuctionsWon() int64 {
+	if x != nil {
+		return x.AuctionsWon
+	}
+	return 0
+}
+
+func (x *MailStats) GetAuctionsOutbid() int64 {
+	if x != nil {
+		return x.AuctionsOutbid
+	}
+	return 0
+}
+
+func (x *MailStats) GetAuctionWonSpending() int64 {
+	if x != nil {
+		return x.AuctionWonSpending
+	}
+	return 0
+}
+
+func (x *MailStats) GetExpiredCount() int64 {
+	if x != nil {
+		return x.ExpiredCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetFactoryRuns() int64 {
+	if x != nil {
+		return x.FactoryRuns
+	}
+	return 0
+}
+
+func (x *MailStats) GetUnitsProducedByItem() map[string]int64 {
+	if x != nil {
+		return x.UnitsProducedByItem
+	}
+	return nil
+}
+
+func (x *MailStats) GetVendors() map[string]*VendorStats {
+	if x != nil {
+		return x.Vendors
+	}
+	return nil
+}
+
+func (x *MailStats) GetSalesByItemCategory() map[string]int64 {
+	if x != nil {
+		return x.SalesByItemCategory
+	}
+	return nil
+}
+
+func (x *MailStats) GetRevenueByItemCategory() map[string]int64 {
+	if x != nil {
+		return x.RevenueByItemCategory
+	}
+	return nil
+}
+
+func (x *MailStats) GetGoalProgress() float64 {
+	if x != nil {
+		return x.GoalProgress
+	}
+	return 0
+}
+
+func (x *MailStats) GetAvgInterSaleIntervalHours() float64 {
+	if x != nil {
+		return x.AvgInterSaleIntervalHours
+	}
+	return 0
+}
+
+func (x *MailStats) GetMedianInterSaleIntervalHours() float64 {
+	if x != nil {
+		return x.MedianInterSaleIntervalHours
+	}
+	return 0
+}
+
+func (x *MailStats) GetItemDemandIndex() map[string]float64 {
+	if x != nil {
+		return x.ItemDemandIndex
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailCountByPlanet() map[string]int64 {
+	if x != nil {
+		return x.MailCountByPlanet
+	}
+	return nil
+}
+
+func (x *MailStats) GetRevenueByPlanet() map[string]int64 {
+	if x != nil {
+		return x.RevenueByPlanet
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailCountByCity() map[string]int64 {
+	if x != nil {
+		return x.MailCountByCity
+	}
+	return nil
+}
+
+func (x *MailStats) GetRevenueByCity() map[string]int64 {
+	if x != nil {
+		return x.RevenueByCity
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailsByGalaxy() map[string]int64 {
+	if x != nil {
+		return x.MailsByGalaxy
+	}
+	return nil
+}
+
+func (x *MailStats) GetRevenueByGalaxy() map[string]int64 {
+	if x != nil {
+		return x.RevenueByGalaxy
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailsBySource() map[string]int64 {
+	if x != nil {
+		return x.MailsBySource
+	}
+	return nil
+}
+
+func (x *MailStats) GetMailCountByCharacter() map[string]int64 {
+	if x != nil {
+		return x.MailCountByCharacter
+	}
+	return nil
+}
+
+func (x *MailStats) GetRevenueByCharacter() map[string]int64 {
+	if x != nil {
+		return x.RevenueByCharacter
+	}
+	return nil
+}
+
+func (x *MailStats) GetAnnouncementsCollapsed() int64 {
+	if x != nil {
+		return x.AnnouncementsCollapsed
+	}
+	return 0
+}
+
+func (x *MailStats) GetRetriedFiles() int64 {
+	if x != nil {
+		return x.RetriedFiles
+	}
+	return 0
+}
+
+func (x *MailStats) GetRecoveredMails() int64 {
+	if x != nil {
+		return x.RecoveredMails
+	}
+	return 0
+}
+
+func (x *MailStats) GetDuplicateMails() int64 {
+	if x != nil {
+		return x.DuplicateMails
+	}
+	return 0
+}
+
+func (x *MailStats) GetSequentialGapCount() int64 {
+	if x != nil {
+		return x.SequentialGapCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetMissingIdCount() int64 {
+	if x != nil {
+		return x.MissingIdCount
+	}
+	return 0
+}
+
+func (x *MailStats) GetContentDuplicateMails() int64 {
+	if x != nil {
+		return x.ContentDuplicateMails
+	}
+	return 0
+}
+
+type DateRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StartDate     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate       *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DateRange) Reset() {
+	*x = DateRange{}
+	mi := &file_mailbatch_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DateRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DateRange) ProtoMessage() {}
+
+func (x *DateRange) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DateRange.ProtoReflect.Descriptor instead.
+func (*DateRange) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DateRange) GetStartDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartDate
+	}
+	return nil
+}
+
+func (x *DateRange) GetEndDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndDate
+	}
+	return nil
+}
+
+type VendorStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MailCount     int64                  `protobuf:"varint,1,opt,name=mail_count,json=mailCount,proto3" json:"mail_count,omitempty"`
+	TotalCredits  int64                  `protobuf:"varint,2,opt,name=total_credits,json=totalCredits,proto3" json:"total_credits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VendorStats) Reset() {
+	*x = VendorStats{}
+	mi := &file_mailbatch_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VendorStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VendorStats) ProtoMessage() {}
+
+func (x *VendorStats) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VendorStats.ProtoReflect.Descriptor instead.
+func (*VendorStats) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *VendorStats) GetMailCount() int64 {
+	if x != nil {
+		return x.MailCount
+	}
+	return 0
+}
+
+func (x *VendorStats) GetTotalCredits() int64 {
+	if x != nil {
+		return x.TotalCredits
+	}
+	return 0
+}
+
+type MergeInfo struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	Inputs                   []string               `protobuf:"bytes,1,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	DuplicateIdsRemoved      int64                  `protobuf:"varint,2,opt,name=duplicate_ids_removed,json=duplicateIdsRemoved,proto3" json:"duplicate_ids_removed,omitempty"`
+	ContentDuplicatesRemoved int64                  `protobuf:"varint,3,opt,name=content_duplicates_removed,json=contentDuplicatesRemoved,proto3" json:"content_duplicates_removed,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *MergeInfo) Reset() {
+	*x = MergeInfo{}
+	mi := &file_mailbatch_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MergeInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MergeInfo) ProtoMessage() {}
+
+func (x *MergeInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_mailbatch_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MergeInfo.ProtoReflect.Descriptor instead.
+func (*MergeInfo) Descriptor() ([]byte, []int) {
+	return file_mailbatch_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MergeInfo) GetInputs() []string {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *MergeInfo) GetDuplicateIdsRemoved() int64 {
+	if x != nil {
+		return x.DuplicateIdsRemoved
+	}
+	return 0
+}
+
+func (x *MergeInfo) GetContentDuplicatesRemoved() int64 {
+	if x != nil {
+		return x.ContentDuplicatesRemoved
+	}
+	return 0
+}
+
+var File_mailbatch_proto protoreflect.FileDescriptor
+
+const file_mailbatch_proto_rawDesc = "" +
+	"\n" +
+	"\x0fmailbatch.proto\x12\x17swgcrafter.mailanalyzer\x1a\x1fgoogle/protobuf/timestamp.proto\"\xdf\x01\n" +
+	"\tMailBatch\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\x05R\rschemaVersion\x127\n" +
+	"\x05mails\x18\x02 \x03(\v2!.swgcrafter.mailanalyzer.MailDataR\x05mails\x128\n" +
+	"\x05stats\x18\x03 \x01(\v2\".swgcrafter.mailanalyzer.MailStatsR\x05stats\x128\n" +
+	"\x05merge\x18\x04 \x01(\v2\".swgcrafter.mailanalyzer.MergeInfoR\x05merge\"\xc9\r\n" +
+	"\bMailData\x12\x17\n" +
+	"\amail_id\x18\x01 \x01(\tR\x06mailId\x12\x16\n" +
+	"\x06sender\x18\x02 \x01(\tR\x06sender\x12\x18\n" +
+	"\asubject\x18\x03 \x01(\tR\asubject\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x12\n" +
+	"\x04body\x18\x05 \x01(\tR\x04body\x12\x1a\n" +
+	"\blocation\x18\x06 \x01(\tR\blocation\x12\x12\n" +
+	"\x04city\x18\a \x01(\tR\x04city\x12\x16\n" +
+	"\x06planet\x18\b \x01(\tR\x06planet\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x12,\n" +
+	"\x12mail_id_normalized\x18\n" +
+	" \x01(\tR\x10mailIdNormalized\x12!\n" +
+	"\fcontent_hash\x18\v \x01(\tR\vcontentHash\x12\x16\n" +
+	"\x06galaxy\x18\f \x01(\tR\x06galaxy\x12\x16\n" +
+	"\x06source\x18\r \x01(\tR\x06source\x12\x1c\n" +
+	"\trecovered\x18\x0e \x01(\bR\trecovered\x12\x1c\n" +
+	"\tcharacter\x18\x0f \x01(\tR\tcharacter\x12#\n" +
+	"\rmail_category\x18\x10 \x01(\tR\fmailCategory\x12\x1b\n" +
+	"\tmail_type\x18\x11 \x01(\tR\bmailType\x12%\n" +
+	"\x0ebroadcast_name\x18\x12 \x01(\tR\rbroadcastName\x12\x1b\n" +
+	"\tsale_type\x18\x13 \x01(\tR\bsaleType\x12-\n" +
+	"\x12normalized_subject\x18\x14 \x01(\tR\x11normalizedSubject\x12#\n" +
+	"\rsender_domain\x18\x15 \x01(\tR\fsenderDomain\x12)\n" +
+	"\x10sender_subsystem\x18\x16 \x01(\tR\x0fsenderSubsystem\x12!\n" +
+	"\fsender_label\x18\x17 \x01(\tR\vsenderLabel\x12\x1b\n" +
+	"\titem_name\x18\x18 \x01(\tR\bitemName\x12.\n" +
+	"\x13canonical_item_name\x18\x19 \x01(\tR\x11canonicalItemName\x12\x14\n" +
+	"\x05buyer\x18\x1a \x01(\tR\x05buyer\x12\x14\n" +
+	"\x05price\x18\x1b \x01(\x03R\x05price\x12\x19\n" +
+	"\bitem_key\x18\x1c \x01(\tR\aitemKey\x12#\n" +
+	"\ritem_category\x18\x1d \x01(\tR\fitemCategory\x12#\n" +
+	"\rserial_number\x18\x1e \x01(\tR\fserialNumber\x12\x1d\n" +
+	"\n" +
+	"unit_count\x18\x1f \x01(\x03R\tunitCount\x12$\n" +
+	"\x0eprice_per_unit\x18  \x01(\x01R\fpricePerUnit\x12\x1f\n" +
+	"\vvendor_name\x18! \x01(\tR\n" +
+	"vendorName\x12\x1d\n" +
+	"\n" +
+	"price_type\x18\" \x01(\tR\tpriceType\x121\n" +
+	"\x04sale\x18# \x01(\v2\x1d.swgcrafter.mailanalyzer.SaleR\x04sale\x12=\n" +
+	"\bpurchase\x18$ \x01(\v2!.swgcrafter.mailanalyzer.PurchaseR\bpurchase\x12:\n" +
+	"\aauction\x18% \x01(\v2 .swgcrafter.mailanalyzer.AuctionR\aauction\x12>\n" +
+	"\aexpired\x18& \x01(\v2$.swgcrafter.mailanalyzer.ExpiredItemR\aexpired\x12D\n" +
+	"\vfactory_run\x18' \x01(\v2#.swgcrafter.mailanalyzer.FactoryRunR\n" +
+	"factoryRun\x127\n" +
+	"\x06income\x18( \x01(\v2\x1f.swgcrafter.mailanalyzer.IncomeR\x06income\x12L\n" +
+	"\x0esurvey_results\x18) \x03(\v2%.swgcrafter.mailanalyzer.SurveyResultR\rsurveyResults\x12'\n" +
+	"\x0fhas_coordinates\x18* \x01(\bR\x0ehasCoordinates\x12\x1d\n" +
+	"\n" +
+	"location_x\x18+ \x01(\x01R\tlocationX\x12\x1d\n" +
+	"\n" +
+	"location_y\x18, \x01(\x01R\tlocationY\x12\x1d\n" +
+	"\n" +
+	"location_z\x18- \x01(\x01R\tlocationZ\x12?\n" +
+	"\twaypoints\x18. \x03(\v2!.swgcrafter.mailanalyzer.WaypointR\twaypoints\"\xa7\x02\n" +
+	"\x04Sale\x12\x1b\n" +
+	"\titem_name\x18\x01 \x01(\tR\bitemName\x12\x14\n" +
+	"\x05buyer\x18\x02 \x01(\tR\x05buyer\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x03R\x05price\x12!\n" +
+	"\fsale_channel\x18\x04 \x01(\tR\vsaleChannel\x12\x1f\n" +
+	"\vvendor_name\x18\x06 \x01(\tR\n" +
+	"vendorName\x12\x1a\n" +
+	"\bcategory\x18\a \x01(\tR\bcategory\x12#\n" +
+	"\rserial_number\x18\b \x01(\tR\fserialNumber\x12\x1d\n" +
+	"\n" +
+	"unit_count\x18\t \x01(\x03R\tunitCount\x12$\n" +
+	"\x0eprice_per_unit\x18\n" +
+	" \x01(\x01R\fpricePerUnitJ\x04\b\x05\x10\x06R\x06vendor\"q\n" +
+	"\bPurchase\x12\x1b\n" +
+	"\titem_name\x18\x01 \x01(\tR\bitemName\x12\x16\n" +
+	"\x06seller\x18\x02 \x01(\tR\x06seller\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x03R\x05price\x12\x1a\n" +
+	"\blocation\x18\x04 \x01(\tR\blocation\"N\n" +
+	"\aAuction\x12\x14\n" +
+	"\x05event\x18\x01 \x01(\tR\x05event\x12\x1b\n" +
+	"\titem_name\x18\x02 \x01(\tR\bitemName\x12\x10\n" +
+	"\x03bid\x18\x03 \x01(\x03R\x03bid\"F\n" +
+	"\vExpiredItem\x12\x1b\n" +
+	"\titem_name\x18\x01 \x01(\tR\bitemName\x12\x1a\n" +
+	"\blocation\x18\x02 \x01(\tR\blocation\"z\n" +
+	"\n" +
+	"FactoryRun\x12\x18\n" +
+	"\afactory\x18\x01 \x01(\tR\afactory\x12\x1b\n" +
+	"\titem_name\x18\x02 \x01(\tR\bitemName\x12\x19\n" +
+	"\bitem_key\x18\x03 \x01(\tR\aitemKey\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x03R\bquantity\":\n" +
+	"\x06Income\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x18\n" +
+	"\acredits\x18\x02 \x01(\x03R\acredits\"h\n" +
+	"\fSurveyResult\x12\x1a\n" +
+	"\bresource\x18\x01 \x01(\tR\bresource\x12\x16\n" +
+	"\x06planet\x18\x02 \x01(\tR\x06planet\x12$\n" +
+	"\rconcentration\x18\x03 \x01(\x01R\rconcentration\"`\n" +
+	"\bWaypoint\x12\x16\n" +
+	"\x06planet\x18\x01 \x01(\tR\x06planet\x12\f\n" +
+	"\x01x\x18\x02 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x03 \x01(\x01R\x01y\x12\f\n" +
+	"\x01z\x18\x04 \x01(\x01R\x01z\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\"\xb8&\n" +
+	"\tMailStats\x12\x1f\n" +
+	"\vtotal_mails\x18\x01 \x01(\x03R\n" +
+	"totalMails\x12-\n" +
+	"\x12sale_notifications\x18\x02 \x01(\x03R\x11saleNotifications\x12A\n" +
+	"\n" +
+	"date_range\x18\x03 \x01(\v2\".swgcrafter.mailanalyzer.DateRangeR\tdateRange\x12I\n" +
+	"\asenders\x18\x04 \x03(\v2/.swgcrafter.mailanalyzer.MailStats.SendersEntryR\asenders\x12f\n" +
+	"\x12mails_by_subsystem\x18\x05 \x03(\v28.swgcrafter.mailanalyzer.MailStats.MailsBySubsystemEntryR\x10mailsBySubsystem\x12b\n" +
+	"\x10subject_clusters\x18\x06 \x03(\v27.swgcrafter.mailanalyzer.MailStats.SubjectClustersEntryR\x0fsubjectClusters\x12c\n" +
+	"\x11mails_by_category\x18\a \x03(\v27.swgcrafter.mailanalyzer.MailStats.MailsByCategoryEntryR\x0fmailsByCategory\x12W\n" +
+	"\rmails_by_type\x18\b \x03(\v23.swgcrafter.mailanalyzer.MailStats.MailsByTypeEntryR\vmailsByType\x12#\n" +
+	"\rtotal_revenue\x18\t \x01(\x03R\ftotalRevenue\x12,\n" +
+	"\x12known_system_mails\x18\n" +
+	" \x01(\x03R\x10knownSystemMails\x120\n" +
+	"\x14unknown_sender_mails\x18\v \x01(\x03R\x12unknownSenderMails\x12%\n" +
+	"\x0evendor_revenue\x18\f \x01(\x03R\rvendorRevenue\x12%\n" +
+	"\x0ebazaar_revenue\x18\r \x01(\x03R\rbazaarRevenue\x12*\n" +
+	"\x11vendor_sale_count\x18\x0e \x01(\x03R\x0fvendorSaleCount\x12*\n" +
+	"\x11bazaar_sale_count\x18\x0f \x01(\x03R\x0fbazaarSaleCount\x123\n" +
+	"\x16vendor_to_bazaar_ratio\x18\x10 \x01(\x01R\x13vendorToBazaarRatio\x12$\n" +
+	"\x0ebid_sale_count\x18\x11 \x01(\x03R\fbidSaleCount\x12+\n" +
+	"\x12buy_now_sale_count\x18\x12 \x01(\x03R\x0fbuyNowSaleCount\x12\"\n" +
+	"\ravg_bid_price\x18\x13 \x01(\x03R\vavgBidPrice\x12)\n" +
+	"\x11avg_buy_now_price\x18\x14 \x01(\x03R\x0eavgBuyNowPrice\x12%\n" +
+	"\x0epurchase_count\x18\x15 \x01(\x03R\rpurchaseCount\x12+\n" +
+	"\x11purchase_spending\x18\x16 \x01(\x03R\x10purchaseSpending\x12!\n" +
+	"\fother_income\x18\x17 \x01(\x03R\votherIncome\x12p\n" +
+	"\x16other_income_by_source\x18\x18 \x03(\v2;.swgcrafter.mailanalyzer.MailStats.OtherIncomeBySourceEntryR\x13otherIncomeBySource\x12!\n" +
+	"\fauctions_won\x18\x19 \x01(\x03R\vauctionsWon\x12'\n" +
+	"\x0fauctions_outbid\x18\x1a \x01(\x03R\x0eauctionsOutbid\x120\n" +
+	"\x14auction_won_spending\x18\x1b \x01(\x03R\x12auctionWonSpending\x12#\n" +
+	"\rexpired_count\x18\x1c \x01(\x03R\fexpiredCount\x12!\n" +
+	"\ffactory_runs\x18\x1d \x01(\x03R\vfactoryRuns\x12p\n" +
+	"\x16units_produced_by_item\x18\x1e \x03(\v2;.swgcrafter.mailanalyzer.MailStats.UnitsProducedByItemEntryR\x13unitsProducedByItem\x12I\n" +
+	"\avendors\x18\x1f \x03(\v2/.swgcrafter.mailanalyzer.MailStats.VendorsEntryR\avendors\x12p\n" +
+	"\x16sales_by_item_category\x18  \x03(\v2;.swgcrafter.mailanalyzer.MailStats.SalesByItemCategoryEntryR\x13salesByItemCategory\x12v\n" +
+	"\x18revenue_by_item_category\x18! \x03(\v2=.swgcrafter.mailanalyzer.MailStats.RevenueByItemCategoryEntryR\x15revenueByItemCategory\x12#\n" +
+	"\rgoal_progress\x18\" \x01(\x01R\fgoalProgress\x12@\n" +
+	"\x1davg_inter_sale_interval_hours\x18# \x01(\x01R\x19avgInterSaleIntervalHours\x12F\n" +
+	" median_inter_sale_interval_hours\x18$ \x01(\x01R\x1cmedianInterSaleIntervalHours\x12c\n" +
+	"\x11item_demand_index\x18% \x03(\v27.swgcrafter.mailanalyzer.MailStats.ItemDemandIndexEntryR\x0fitemDemandIndex\x12j\n" +
+	"\x14mail_count_by_planet\x18& \x03(\v29.swgcrafter.mailanalyzer.MailStats.MailCountByPlanetEntryR\x11mailCountByPlanet\x12c\n" +
+	"\x11revenue_by_planet\x18' \x03(\v27.swgcrafter.mailanalyzer.MailStats.RevenueByPlanetEntryR\x0frevenueByPlanet\x12d\n" +
+	"\x12mail_count_by_city\x18( \x03(\v27.swgcrafter.mailanalyzer.MailStats.MailCountByCityEntryR\x0fmailCountByCity\x12]\n" +
+	"\x0frevenue_by_city\x18) \x03(\v25.swgcrafter.mailanalyzer.MailStats.RevenueByCityEntryR\rrevenueByCity\x12]\n" +
+	"\x0fmails_by_galaxy\x18* \x03(\v25.swgcrafter.mailanalyzer.MailStats.MailsByGalaxyEntryR\rmailsByGalaxy\x12c\n" +
+	"\x11revenue_by_galaxy\x18+ \x03(\v27.swgcrafter.mailanalyzer.MailStats.RevenueByGalaxyEntryR\x0frevenueByGalaxy\x12]\n" +
+	"\x0fmails_by_source\x18, \x03(\v25.swgcrafter.mailanalyzer.MailStats.MailsBySourceEntryR\rmailsBySource\x12s\n" +
+	"\x17mail_count_by_character\x18- \x03(\v2<.swgcrafter.mailanalyzer.MailStats.MailCountByCharacterEntryR\x14mailCountByCharacter\x12l\n" +
+	"\x14revenue_by_character\x18. \x03(\v2:.swgcrafter.mailanalyzer.MailStats.RevenueByCharacterEntryR\x12revenueByCharacter\x127\n" +
+	"\x17announcements_collapsed\x18/ \x01(\x03R\x16announcementsCollapsed\x12#\n" +
+	"\rretried_files\x180 \x01(\x03R\fretriedFiles\x12'\n" +
+	"\x0frecovered_mails\x181 \x01(\x03R\x0erecoveredMails\x12'\n" +
+	"\x0fduplicate_mails\x182 \x01(\x03R\x0eduplicateMails\x120\n" +
+	"\x14sequential_gap_count\x183 \x01(\x03R\x12sequentialGapCount\x12(\n" +
+	"\x10missing_id_count\x184 \x01(\x03R\x0emissingIdCount\x126\n" +
+	"\x17content_duplicate_mails\x185 \x01(\x03R\x15contentDuplicateMails\x1a:\n" +
+	"\fSendersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aC\n" +
+	"\x15MailsBySubsystemEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aB\n" +
+	"\x14SubjectClustersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aB\n" +
+	"\x14MailsByCategoryEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a>\n" +
+	"\x10MailsByTypeEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aF\n" +
+	"\x18OtherIncomeBySourceEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aF\n" +
+	"\x18UnitsProducedByItemEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a`\n" +
+	"\fVendorsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12:\n" +
+	"\x05value\x18\x02 \x01(\v2$.swgcrafter.mailanalyzer.VendorStatsR\x05value:\x028\x01\x1aF\n" +
+	"\x18SalesByItemCategoryEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aH\n" +
+	"\x1aRevenueByItemCategoryEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aB\n" +
+	"\x14ItemDemandIndexEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1aD\n" +
+	"\x16MailCountByPlanetEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aB\n" +
+	"\x14RevenueByPlanetEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aB\n" +
+	"\x14MailCountByCityEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a@\n" +
+	"\x12RevenueByCityEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a@\n" +
+	"\x12MailsByGalaxyEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aB\n" +
+	"\x14RevenueByGalaxyEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a@\n" +
+	"\x12MailsBySourceEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aG\n" +
+	"\x19MailCountByCharacterEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aE\n" +
+	"\x17RevenueByCharacterEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"}\n" +
+	"\tDateRange\x129\n" +
+	"\n" +
+	"start_date\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\tstartDate\x125\n" +
+	"\bend_date\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\aendDate\"Q\n" +
+	"\vVendorStats\x12\x1d\n" +
+	"\n" +
+	"mail_count\x18\x01 \x01(\x03R\tmailCount\x12#\n" +
+	"\rtotal_credits\x18\x02 \x01(\x03R\ftotalCredits\"\x95\x01\n" +
+	"\tMergeInfo\x12\x16\n" +
+	"\x06inputs\x18\x01 \x03(\tR\x06inputs\x122\n" +
+	"\x15duplicate_ids_removed\x18\x02 \x01(\x03R\x13duplicateIdsRemoved\x12<\n" +
+	"\x1acontent_duplicates_removed\x18\x03 \x01(\x03R\x18contentDuplicatesRemovedB\x1bZ\x19mail-analyzer/mailbatchpbb\x06proto3"
+
+var (
+	file_mailbatch_proto_rawDescOnce sync.Once
+	file_mailbatch_proto_rawDescData []byte
+)
+
+func file_mailbatch_proto_rawDescGZIP() []byte {
+	file_mailbatch_proto_rawDescOnce.Do(func() {
+		file_mailbatch_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_mailbatch_proto_rawDesc), len(file_mailbatch_proto_rawDesc)))
+	})
+	return file_mailbatch_proto_rawDescData
+}
+
+var file_mailbatch_proto_msgTypes = make([]protoimpl.MessageInfo, 34)
+var file_mailbatch_proto_goTypes = []any{
+	(*MailBatch)(nil),             // 0: swgcrafter.mailanalyzer.MailBatch
+	(*MailData)(nil),              // 1: swgcrafter.mailanalyzer.MailData
+	(*Sale)(nil),                  // 2: swgcrafter.mailanalyzer.Sale
+	(*Purchase)(nil),              // 3: swgcrafter.mailanalyzer.Purchase
+	(*Auction)(nil),               // 4: swgcrafter.mailanalyzer.Auction
+	(*ExpiredItem)(nil),           // 5: swgcrafter.mailanalyzer.ExpiredItem
+	(*FactoryRun)(nil),            // 6: swgcrafter.mailanalyzer.FactoryRun
+	(*Income)(nil),                // 7: swgcrafter.mailanalyzer.Income
+	(*SurveyResult)(nil),          // 8: swgcrafter.mailanalyzer.SurveyResult
+	(*Waypoint)(nil),              // 9: swgcrafter.mailanalyzer.Waypoint
+	(*MailStats)(nil),             // 10: swgcrafter.mailanalyzer.MailStats
+	(*DateRange)(nil),             // 11: swgcrafter.mailanalyzer.DateRange
+	(*VendorStats)(nil),           // 12: swgcrafter.mailanalyzer.VendorStats
+	(*MergeInfo)(nil),             // 13: swgcrafter.mailanalyzer.MergeInfo
+	nil,                           // 14: swgcrafter.mailanalyzer.MailStats.SendersEntry
+	nil,                           // 15: swgcrafter.mailanalyzer.MailStats.MailsBySubsystemEntry
+	nil,                           // 16: swgcrafter.mailanalyzer.MailStats.SubjectClustersEntry
+	nil,                           // 17: swgcrafter.mailanalyzer.MailStats.MailsByCategoryEntry
+	nil,                           // 18: swgcrafter.mailanalyzer.MailStats.MailsByTypeEntry
+	nil,                           // 19: swgcrafter.mailanalyzer.MailStats.OtherIncomeBySourceEntry
+	nil,                           // 20: swgcrafter.mailanalyzer.MailStats.UnitsProducedByItemEntry
+	nil,                           // 21: swgcrafter.mailanalyzer.MailStats.VendorsEntry
+	nil,                           // 22: swgcrafter.mailanalyzer.MailStats.SalesByItemCategoryEntry
+	nil,                           // 23: swgcrafter.mailanalyzer.MailStats.RevenueByItemCategoryEntry
+	nil,                           // 24: swgcrafter.mailanalyzer.MailStats.ItemDemandIndexEntry
+	nil,                           // 25: swgcrafter.mailanalyzer.MailStats.MailCountByPlanetEntry
+	nil,                           // 26: swgcrafter.mailanalyzer.MailStats.RevenueByPlanetEntry
+	nil,                           // 27: swgcrafter.mailanalyzer.MailStats.MailCountByCityEntry
+	nil,                           // 28: swgcrafter.mailanalyzer.MailStats.RevenueByCityEntry
+	nil,                           // 29: swgcrafter.mailanalyzer.MailStats.MailsByGalaxyEntry
+	nil,                           // 30: swgcrafter.mailanalyzer.MailStats.RevenueByGalaxyEntry
+	nil,                           // 31: swgcrafter.mailanalyzer.MailStats.MailsBySourceEntry
+	nil,                           // 32: swgcrafter.mailanalyzer.MailStats.MailCountByCharacterEntry
+	nil,                           // 33: swgcrafter.mailanalyzer.MailStats.RevenueByCharacterEntry
+	(*timestamppb.Timestamp)(nil), // 34: google.protobuf.Timestamp
+}
+var file_mailbatch_proto_depIdxs = []int32{
+	1,  // 0: swgcrafter.mailanalyzer.MailBatch.mails:type_name -> swgcrafter.mailanalyzer.MailData
+	10, // 1: swgcrafter.mailanalyzer.MailBatch.stats:type_name -> swgcrafter.mailanalyzer.MailStats
+	13, // 2: swgcrafter.mailanalyzer.MailBatch.merge:type_name -> swgcrafter.mailanalyzer.MergeInfo
+	34, // 3: swgcrafter.mailanalyzer.MailData.timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 4: swgcrafter.mailanalyzer.MailData.sale:type_name -> swgcrafter.mailanalyzer.Sale
+	3,  // 5: swgcrafter.mailanalyzer.MailData.purchase:type_name -> swgcrafter.mailanalyzer.Purchase
+	4,  // 6: swgcrafter.mailanalyzer.MailData.auction:type_name -> swgcrafter.mailanalyzer.Auction
+	5,  // 7: swgcrafter.mailanalyzer.MailData.expired:type_name -> swgcrafter.mailanalyzer.ExpiredItem
+	6,  // 8: swgcrafter.mailanalyzer.MailData.factory_run:type_name -> swgcrafter.mailanalyzer.FactoryRun
+	7,  // 9: swgcrafter.mailanalyzer.MailData.income:type_name -> swgcrafter.mailanalyzer.Income
+	8,  // 10: swgcrafter.mailanalyzer.MailData.survey_results:type_name -> swgcrafter.mailanalyzer.SurveyResult
+	9,  // 11: swgcrafter.mailanalyzer.MailData.waypoints:type_name -> swgcrafter.mailanalyzer.Waypoint
+	11, // 12: swgcrafter.mailanalyzer.MailStats.date_range:type_name -> swgcrafter.mailanalyzer.DateRange
+	14, // 13: swgcrafter.mailanalyzer.MailStats.senders:type_name -> swgcrafter.mailanalyzer.MailStats.SendersEntry
+	15, // 14: swgcrafter.mailanalyzer.MailStats.mails_by_subsystem:type_name -> swgcrafter.mailanalyzer.MailStats.MailsBySubsystemEntry
+	16, // 15: swgcrafter.mailanalyzer.MailStats.subject_clusters:type_name -> swgcrafter.mailanalyzer.MailStats.SubjectClustersEntry
+	17, // 16: swgcrafter.mailanalyzer.MailStats.mails_by_category:type_name -> swgcrafter.mailanalyzer.MailStats.MailsByCategoryEntry
+	18, // 17: swgcrafter.mailanalyzer.MailStats.mails_by_type:type_name -> swgcrafter.mailanalyzer.MailStats.MailsByTypeEntry
+	19, // 18: swgcrafter.mailanalyzer.MailStats.other_income_by_source:type_name -> swgcrafter.mailanalyzer.MailStats.OtherIncomeBySourceEntry
+	20, // 19: swgcrafter.mailanalyzer.MailStats.units_produced_by_item:type_name -> swgcrafter.mailanalyzer.MailStats.UnitsProducedByItemEntry
+	21, // 20: swgcrafter.mailanalyzer.MailStats.vendors:type_name -> swgcrafter.mailanalyzer.MailStats.VendorsEntry
+	22, // 21: swgcrafter.mailanalyzer.MailStats.sales_by_item_category:type_name -> swgcrafter.mailanalyzer.MailStats.SalesByItemCategoryEntry
+	23, // 22: swgcrafter.mailanalyzer.MailStats.revenue_by_item_category:type_name -> swgcrafter.mailanalyzer.MailStats.RevenueByItemCategoryEntry
+	24, // 23: swgcrafter.mailanalyzer.MailStats.item_demand_index:type_name -> swgcrafter.mailanalyzer.MailStats.ItemDemandIndexEntry
+	25, // 24: swgcrafter.mailanalyzer.MailStats.mail_count_by_planet:type_name -> swgcrafter.mailanalyzer.MailStats.MailCountByPlanetEntry
+	26, // 25: swgcrafter.mailanalyzer.MailStats.revenue_by_planet:type_name -> swgcrafter.mailanalyzer.MailStats.RevenueByPlanetEntry
+	27, // 26: swgcrafter.mailanalyzer.MailStats.mail_count_by_city:type_name -> swgcrafter.mailanalyzer.MailStats.MailCountByCityEntry
+	28, // 27: swgcrafter.mailanalyzer.MailStats.revenue_by_city:type_name -> swgcrafter.mailanalyzer.MailStats.RevenueByCityEntry
+	29, // 28: swgcrafter.mailanalyzer.MailStats.mails_by_galaxy:type_name -> swgcrafter.mailanalyzer.MailStats.MailsByGalaxyEntry
+	30, // 29: swgcrafter.mailanalyzer.MailStats.revenue_by_galaxy:type_name -> swgcrafter.mailanalyzer.MailStats.RevenueByGalaxyEntry
+	31, // 30: swgcrafter.mailanalyzer.MailStats.mails_by_source:type_name -> swgcrafter.mailanalyzer.MailStats.MailsBySourceEntry
+	32, // 31: swgcrafter.mailanalyzer.MailStats.mail_count_by_character:type_name -> swgcrafter.mailanalyzer.MailStats.MailCountByCharacterEntry
+	33, // 32: swgcrafter.mailanalyzer.MailStats.revenue_by_character:type_name -> swgcrafter.mailanalyzer.MailStats.RevenueByCharacterEntry
+	34, // 33: swgcrafter.mailanalyzer.DateRange.start_date:type_name -> google.protobuf.Timestamp
+	34, // 34: swgcrafter.mailanalyzer.DateRange.end_date:type_name -> google.protobuf.Timestamp
+	12, // 35: swgcrafter.mailanalyzer.MailStats.VendorsEntry.value:type_name -> swgcrafter.mailanalyzer.VendorStats
+	36, // [36:36] is the sub-list for method output_type
+	36, // [36:36] is the sub-list for method input_type
+	36, // [36:36] is the sub-list for extension type_name
+	36, // [36:36] is the sub-list for extension extendee
+	0,  // [0:36] is the sub-list for field type_name
+}
+
+func init() { file_mailbatch_proto_init() }
+func file_mailbatch_proto_init() {
+	if File_mailbatch_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mailbatch_proto_rawDesc), len(file_mailbatch_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   34,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_mailbatch_proto_goTypes,
+		DependencyIndexes: file_mailbatch_proto_depIdxs,
+		MessageInfos:      file_mailbatch_proto_msgTypes,
+	}.Build()
+	File_mailbatch_proto = out.File
+	file_mailbatch_proto_goTypes = nil
+	file_mailbatch_proto_depIdxs = nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// isMailsaveDump reports whether path is a text file produced by the
+// in-game /mailsave command rather than a directory or archive of mail files
+func isMailsaveDump(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && !isMailArchive(path) && !strings.HasSuffix(path, ".mail")
+}
+
+// walkMailsaveDump splits a /mailsave dump into its mails and calls fn with
+// the content of each, named after the dump and the line the mail starts
+// on, e.g. "mailsave.txt:42". A dump is the concatenation of mails in the
+// .mail format; a mail starts three lines before each TIMESTAMP line, with
+// its mail ID, sender and subject. Blank lines between mails are dropped.
+func walkMailsaveDump(path string, fn func(name string, data []byte, modTime time.Time) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read mailsave dump: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read mailsave dump: %w", err)
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(toUTF8(data)), "\r\n", "\n"), "\n")
+	var starts []int
+	for i := 3; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "TIMESTAMP:") {
+			starts = append(starts, i-3)
+		}
+	}
+
+	for n, start := range starts {
+		end := len(lines)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+
+		mail := strings.TrimRight(strings.Join(lines[start:end], "\n"), " \t\n")
+		if err := fn(fmt.Sprintf("%s:%d", path, start+1), []byte(mail+"\n"), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testMailsaveDump is a /mailsave dump of three mails, one of them with a
+// multi-line body, separated by blank lines and with Windows line endings
+const testMailsaveDump = "1\r\nSWG.Restoration.auctioner\r\nVendor Sale Complete\r\nTIMESTAMP: 1705312800\r\nVendor: Crafter has sold Rifle to Han for 1000 credits.\r\n\r\n" +
+	"2\r\nHan Solo\r\nRe: Rifle\r\nTIMESTAMP: 1705399200\r\nThanks for the rifle!\r\nSee you on Tatooine.\r\n\r\n\r\n" +
+	"3\r\nSWG.Restoration.auctioner\r\nVendor Sale Complete\r\nTIMESTAMP: 1705917600\r\nVendor: Crafter has sold Pistol to Leia for 500 credits.\r\n"
+
+func TestIsMailsaveDump(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"mailsave.txt", "1.mail", "mails.zip"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"mailsave.txt", true},
+		{"1.mail", false},
+		{"mails.zip", false},
+		{".", false},
+		{"missing.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isMailsaveDump(filepath.Join(dir, tt.name)); got != tt.want {
+				t.Errorf("isMailsaveDump(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkMailsaveDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mailsave.txt")
+	if err := os.WriteFile(path, []byte(testMailsaveDump), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var names, contents []string
+	err := walkMailsaveDump(path, func(name string, data []byte, modTime time.Time) error {
+		names = append(names, name)
+		contents = append(contents, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{path + ":1", path + ":7", path + ":15"}; !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if want := "2\nHan Solo\nRe: Rifle\nTIMESTAMP: 1705399200\nThanks for the rifle!\nSee you on Tatooine.\n"; len(contents) < 2 || contents[1] != want {
+		t.Errorf("mails = %q, want the second to be %q", contents, want)
+	}
+}
+
+func TestParseMailsaveDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mailsave.txt")
+	if err := os.WriteFile(path, []byte(testMailsaveDump), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := parseMailFromDirectories(context.Background(), []string{path}, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mailIDs(result.Mails), []string{"1", "2", "3"}; !slices.Equal(got, want) {
+		t.Fatalf("parsed mails = %v, want %v", got, want)
+	}
+	if got, want := result.Mails[1].Body, "Thanks for the rifle!\nSee you on Tatooine."; got != want {
+		t.Errorf("Body = %q, want %q", got, want)
+	}
+	var revenue int64
+	for _, mail := range result.Mails {
+		if mail.Sale != nil {
+			revenue += mail.Sale.Price
+		}
+	}
+	if revenue != 1500 {
+		t.Errorf("revenue = %d, want 1500", revenue)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MailType is what a mail is about, assigned by the mail classifiers
+type MailType string
+
+// Mail types assigned by classifyMailType
+const (
+	MailTypeSale     MailType = "sale"
+	MailTypePurchase MailType = "purchase"
+	MailTypeExpired  MailType = "expired"
+	MailTypeOutbid   MailType = "outbid"
+	MailTypeFactory  MailType = "factory"
+	MailTypeMission  MailType = "mission"
+	MailTypeGCW      MailType = "gcw"
+	MailTypeCity     MailType = "city"
+	MailTypeGuild    MailType = "guild"
+	MailTypeSpam     MailType = "spam"
+	MailTypeOther    MailType = "other"
+)
+
+// mailTypes lists all mail types, e.g. to validate --type-filter
+var mailTypes = []MailType{
+	MailTypeSale,
+	MailTypePurchase,
+	MailTypeExpired,
+	MailTypeOutbid,
+	MailTypeFactory,
+	MailTypeMission,
+	MailTypeGCW,
+	MailTypeCity,
+	MailTypeGuild,
+	MailTypeSpam,
+	MailTypeOther,
+}
+
+// MailClassifier assigns a mail type to the mails it recognizes. Classify
+// reports false for mails it has no opinion on, leaving them to the next
+// classifier.
+type MailClassifier interface {
+	Classify(mail *MailData) (MailType, bool)
+}
+
+// MailClassifierFunc adapts a function to the MailClassifier interface
+type MailClassifierFunc func(mail *MailData) (MailType, bool)
+
+// Classify calls f(mail)
+func (f MailClassifierFunc) Classify(mail *MailData) (MailType, bool) {
+	return f(mail)
+}
+
+// Mission mails mention a mission in their subject, e.g. "Mission Complete"
+var missionPattern = regexp.MustCompile(`(?i)\bmissions?\b`)
+
+// City and guild broadcasts name their city or guild in the subject, e.g.
+// "City Update: Bestine Hills" or "Guild News - Crafters Union", or in the
+// body, e.g. "The city of Bestine Hills has elected a new mayor."
+var (
+	broadcastSubjectPattern = regexp.MustCompile(`(?i)^\s*(city|guild)\s*(?:update|news|announcement|broadcast|mail|message)?\s*[:-]\s*(.+?)\s*$`)
+	broadcastBodyPattern    = regexp.MustCompile(`(?i)\b(city|guild) of ([^.,;:!\n]+?)(?:\s+(?:has|have|is|are|was|will|would)\b|[.,;:!\n]|$)`)
+	guildTagPattern         = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+)
+
+// isBroadcast reports whether a mail is a broadcast of the given kind,
+// "city" or "guild", by its sender subsystem or subject
+func isBroadcast(mail *MailData, kind string) bool {
+	if strings.EqualFold(mail.SenderSubsystem, kind) {
+		return true
+	}
+	matches := broadcastSubjectPattern.FindStringSubmatch(mail.Subject)
+	return matches != nil && strings.EqualFold(matches[1], kind)
+}
+
+// parseBroadcastName extracts the name of the city or guild of a city or
+// guild broadcast, or returns "" if the mail does not name it. Guild mails
+// fall back to a "[TAG]" subject prefix.
+func parseBroadcastName(mail *MailData) string {
+	kind := string(mail.MailType)
+	if matches := broadcastSubjectPattern.FindStringSubmatch(mail.Subject); matches != nil && strings.EqualFold(matches[1], kind) {
+		return matches[2]
+	}
+	for _, matches := range broadcastBodyPattern.FindAllStringSubmatch(mail.Body, -1) {
+		if strings.EqualFold(matches[1], kind) {
+			return strings.TrimSpace(matches[2])
+		}
+	}
+	if mail.MailType == MailTypeGuild {
+		if matches := guildTagPattern.FindStringSubmatch(mail.Subject); matches != nil {
+			return strings.TrimSpace(matches[1])
+		}
+	}
+	return ""
+}
+
+// Unsolicited player mails advertising credit sellers and websites
+var spamPattern = regexp.MustCompile(`(?i)\b(?:cheap credits|buy credits|credits for sale|best prices?)\b|\bwww\.|https?://`)
+
+// defaultMailClassifiers recognize the mail types from the fields
+// parseMailFile extracts. The first classifier to recognize a mail wins.
+var defaultMailClassifiers = []MailClassifier{
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeSale, mail.MailCategory == CategorySale
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		won := mail.Auction != nil && mail.Auction.Event == AuctionEventWon
+		return MailTypePurchase, mail.Purchase != nil || won
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeExpired, mail.Expired != nil
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeOutbid, mail.Auction != nil && mail.Auction.Event == AuctionEventOutbid
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeFactory, mail.FactoryRun != nil
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeGCW, mail.Income != nil && mail.Income.Source == IncomeSourceGCW
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeMission, missionPattern.MatchString(mail.Subject) || mail.Income != nil
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeCity, isBroadcast(mail, "city")
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeGuild, isBroadcast(mail, "guild")
+	}),
+	MailClassifierFunc(func(mail *MailData) (MailType, bool) {
+		return MailTypeSpam, mail.MailCategory == CategoryPlayer && spamPattern.MatchString(mail.Subject+"\n"+mail.Body)
+	}),
+}
+
+// classifyMailType returns the type assigned by the first classifier that
+// recognizes the mail, or MailTypeOther
+func classifyMailType(mail *MailData, classifiers []MailClassifier) MailType {
+	for _, classifier := range classifiers {
+		if mailType, ok := classifier.Classify(mail); ok {
+			return mailType
+		}
+	}
+	return MailTypeOther
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/urfave/cli/v3"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
@@ -22,17 +36,27 @@ func main() {
 		Authors: []any{
 			"SWG Crafter Team <dev@swg-crafter.local>",
 		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "credits-format",
+				Usage: "Format of credit amounts in summaries and reports: plain, comma or abbrev",
+				Value: CreditsFormatComma,
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			return ctx, setCreditsFormat(cmd.String("credits-format"))
+		},
 		Commands: []*cli.Command{
 			{
 				Name:    "parse",
 				Aliases: []string{"p"},
 				Usage:   "Parse mail files and extract raw mail data",
-				Flags: []cli.Flag{
-					&cli.StringFlag{
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringSliceFlag{
 						Name:    "input",
 						Aliases: []string{"i"},
-						Usage:   "Input directory containing .mail files",
-						Value:   "./testdata",
+						Usage:   "Input directory containing .mail files, a .zip, .tar or .tar.gz archive of them, or a /mailsave dump (repeatable)",
+						Value:   []string{"./testdata"},
 					},
 					&cli.StringFlag{
 						Name:    "output",
@@ -46,107 +70,2545 @@ func main() {
 						Usage:   "Enable verbose output",
 						Value:   false,
 					},
+					&cli.BoolFlag{
+						Name:    "quiet",
+						Aliases: []string{"q"},
+						Usage:   "Do not print a progress line every few seconds during long parses",
+					},
+				}, filterFlags(), []cli.Flag{
+					&cli.IntFlag{
+						Name:  "scanner-buffer-size",
+						Usage: fmt.Sprintf("Maximum line length in bytes accepted in mail files (max %d)", maxScannerBufferSize),
+						Value: defaultScannerBufferSize,
+					},
+					&cli.Int64Flag{
+						Name:  "max-file-size",
+						Usage: "Maximum size in bytes of a mail file; larger files are skipped and listed in the errors report",
+						Value: defaultMaxFileSize,
+					},
+					&cli.IntFlag{
+						Name:  "recover-headers",
+						Usage: "Search the first N lines of mails with a malformed header for the mail ID, sender, subject and TIMESTAMP line instead of dropping them; 0 disables recovery",
+					},
+					&cli.DurationFlag{
+						Name:  "parse-timeout",
+						Usage: "Skip mail files that take longer than this to parse (e.g., 5s); 0 disables the timeout",
+					},
+					&cli.IntFlag{
+						Name:  "parse-workers",
+						Usage: "Number of mail files parsed and top-level directories scanned concurrently (default: the number of CPUs)",
+					},
+					&cli.IntFlag{
+						Name:  "max-retries",
+						Usage: "Retry transient read errors of a mail file up to this many times",
+						Value: 3,
+					},
+					&cli.DurationFlag{
+						Name:  "retry-backoff",
+						Usage: "Wait before the first retry of a mail file, doubled for each further retry",
+						Value: 100 * time.Millisecond,
+					},
 					&cli.StringFlag{
-						Name:  "sender-filter",
-						Usage: "Filter by sender (e.g., 'SWG.Restoration.auctioner')",
+						Name:  "timestamp-format",
+						Usage: "Format of the TIMESTAMP line: 'unix' or a Go time layout (e.g., '2006-01-02T15:04:05Z07:00')",
+						Value: "unix",
 					},
 					&cli.StringFlag{
-						Name:  "subject-filter",
-						Usage: "Filter by subject pattern (e.g., 'Sale Complete')",
+						Name:  "item-db",
+						Usage: "JSON file mapping raw item names to canonical item names",
 					},
-				},
+					&cli.StringFlag{
+						Name:  "item-key-rules",
+						Usage: "JSON file with item key normalization rules ([{\"pattern\": ..., \"replace\": ...}]) replacing the built-in ones",
+					},
+					&cli.StringFlag{
+						Name:  "galaxy",
+						Usage: "Galaxy to stamp on every mail instead of detecting it from the system senders",
+					},
+					&cli.StringSliceFlag{
+						Name:  "tag",
+						Usage: "Tag to stamp on every mail, e.g. the account or backup it was read from, for --tag-filter (repeatable)",
+					},
+					&cli.StringFlag{
+						Name:  "patterns-file",
+						Usage: "JSON file with the auctioneer senders and sale patterns of a server other than SWG Restoration",
+					},
+					&cli.StringFlag{
+						Name:  "item-category-rules",
+						Usage: "JSON file with item category rules ([{\"category\": ..., \"pattern\": ...}]) replacing the built-in ones",
+					},
+					&cli.StringFlag{
+						Name:  "system-senders-file",
+						Usage: "JSON file mapping senders to labels, replacing the built-in list of system senders",
+					},
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "Report mails missing their ID, sender or subject, and sale mails missing location, item name or price",
+					},
+					&cli.BoolFlag{
+						Name:  "fail-on-error",
+						Usage: "Fail on the first mail file that cannot be parsed instead of skipping it",
+					},
+					&cli.StringFlag{
+						Name:  "id-format",
+						Usage: "Mail ID scheme: opaque, or sequential to count gaps in the IDs",
+						Value: "opaque",
+					},
+					&cli.StringFlag{
+						Name:  "body",
+						Usage: "Mail bodies in the output: full, summary (first line, truncated) or none",
+						Value: BodyModeFull,
+					},
+					&cli.BoolFlag{
+						Name:  "omit-body",
+						Usage: "Leave out mail bodies from the output, shorthand for --body none",
+					},
+					&cli.BoolFlag{
+						Name:  "strip-source",
+						Usage: "Leave out the input directory of each mail from the output, for privacy",
+					},
+					&cli.BoolFlag{
+						Name:  "infer-character-from-dir",
+						Usage: "Set each mail's character from its directory: a mail_<character> directory or the top-level subdirectory",
+					},
+					&cli.BoolFlag{
+						Name:  "normalize-ids",
+						Usage: "Add mail_id_normalized, the mail ID as 16-digit hex, and deduplicate by it",
+					},
+					&cli.BoolFlag{
+						Name:  "strict-ids",
+						Usage: "Fail when two mail files share the same mail ID instead of keeping the first",
+					},
+					&cli.BoolFlag{
+						Name:  "no-dedup-content",
+						Usage: "Keep mails whose content matches an earlier mail with a different ID, e.g. copies in backups",
+					},
+					&cli.BoolFlag{
+						Name:  "markdown-report",
+						Usage: "Also write a Markdown summary to <output>_report.md",
+					},
+					&cli.StringFlag{
+						Name:  "markdown-template",
+						Usage: "Custom text/template file for the Markdown report",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format: json, csv for one row per mail plus a CSV of the sales, xlsx for an Excel workbook, parquet for the sales as a Parquet file, proto for a Protocol Buffers MailBatch, sqlite for a SQLite database, or ndjson to stream one mail per line as it is parsed",
+						Value: "json",
+					},
+					&cli.StringFlag{
+						Name:  "key-case",
+						Usage: "Key casing of the JSON output: snake or camel",
+						Value: KeyCaseSnake,
+					},
+					&cli.BoolFlag{
+						Name:  "self-describing",
+						Usage: "Add a _schema key describing the mail and stats fields to the JSON output",
+					},
+					&cli.BoolFlag{
+						Name:  "append",
+						Usage: "Merge new mails into an existing output file instead of overwriting it",
+					},
+					&cli.StringFlag{
+						Name:  "state-file",
+						Usage: "Record processed mail files and mails in this file (e.g. .mail-analyzer-state.json) and only output new mails on later runs",
+					},
+					&cli.BoolFlag{
+						Name:  "full",
+						Usage: "Ignore the --state-file of earlier runs and process all mail files again",
+					},
+					&cli.StringFlag{
+						Name:  "cache-dir",
+						Usage: "Cache parsed mails in this directory by the hash of their mail file, so unchanged files are not parsed again on later runs",
+					},
+					&cli.IntFlag{
+						Name:  "checkpoint-every",
+						Usage: "Save the progress to <output>_checkpoint.ndjson after this many mail files; 0 disables checkpoints",
+						Value: 10000,
+					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Continue an interrupted parse from its checkpoint",
+					},
+					&cli.Int64Flag{
+						Name:  "goal",
+						Usage: "Revenue goal in credits; prints progress and an ETA",
+					},
+					&cli.IntFlag{
+						Name:  "flag-short-body",
+						Usage: "Report mails whose body is shorter than this many bytes in short_body_mails",
+					},
+				}, announcementFlags(), compressFlags()),
 				Action: parseMailFiles,
 			},
+			{
+				Name:    "filter",
+				Aliases: []string{"search"},
+				Usage:   "Filter and re-sort an existing mail batch without re-parsing",
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:     "output",
+						Aliases:  []string{"o"},
+						Usage:    "Output file for the filtered JSON batch",
+						Required: true,
+					},
+				}, filterFlags(), tagFlags(), []cli.Flag{
+					&cli.StringFlag{
+						Name:  "sort-by",
+						Usage: "Sort field: timestamp, id, sender, subject or price",
+						Value: "timestamp",
+					},
+					&cli.BoolFlag{
+						Name:  "sort-desc",
+						Usage: "Sort in descending order",
+					},
+				}, compressFlags()),
+				Action: filterBatch,
+			},
+			{
+				Name:  "weekly-report",
+				Usage: "Summarize a parsed mail batch by ISO week",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output file for the report (default: stdout)",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Report format: text or json",
+						Value: "text",
+					},
+				},
+				Action: weeklyReport,
+			},
+			{
+				Name:  "price-history",
+				Usage: "Output the price history of an item as JSON for charting",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:     "item",
+						Usage:    "Item name to report on (exact name or regular expression)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "bucket",
+						Usage: "Time bucket: day, week, month or quarter",
+						Value: "week",
+					},
+				},
+				Action: priceHistory,
+			},
+			{
+				Name:  "price-trend",
+				Usage: "Show how the average price of an item changes from period to period",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:     "item",
+						Usage:    "Item name to report on (exact name or regular expression)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "bucket",
+						Usage: "Time bucket: week, month or quarter",
+						Value: "week",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format: text or json",
+						Value: "text",
+					},
+				},
+				Action: priceTrend,
+			},
+			{
+				Name:  "gaps",
+				Usage: "List gaps in sequential mail IDs, e.g. deleted or lost mails",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:  "id-format",
+						Usage: "Mail ID scheme; only sequential is supported",
+						Value: "sequential",
+					},
+				},
+				Action: idGaps,
+			},
+			{
+				Name:  "balance-history",
+				Usage: "Output the running credit balance after each mail as JSON",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.Int64Flag{
+						Name:  "initial-balance",
+						Usage: "Credit balance before the first mail",
+					},
+				},
+				Action: balanceHistory,
+			},
+			{
+				Name:  "compare",
+				Usage: "Compare the statistics of two date ranges of a mail batch",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:     "period-a-start",
+						Usage:    "Start date of the first period (YYYY-MM-DD)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "period-a-end",
+						Usage:    "End date of the first period (YYYY-MM-DD)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "period-b-start",
+						Usage:    "Start date of the second period (YYYY-MM-DD)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "period-b-end",
+						Usage:    "End date of the second period (YYYY-MM-DD)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Report format: text or json",
+						Value: "text",
+					},
+					&cli.BoolFlag{
+						Name:  "allow-overlap",
+						Usage: "Do not warn when the periods overlap",
+					},
+				},
+				Action: compareBatch,
+			},
+			{
+				Name:  "merge",
+				Usage: "Merge several mail batches into one, dropping duplicate mails",
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringSliceFlag{
+						Name:     "input",
+						Aliases:  []string{"i"},
+						Usage:    "Input JSON batch file (repeatable)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "output",
+						Aliases:  []string{"o"},
+						Usage:    "Output file for the merged JSON batch",
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "merge-workers",
+						Usage: "Number of input batches to load concurrently (default: number of inputs; capped at 8)",
+					},
+					&cli.BoolFlag{
+						Name:  "dedup-by-content",
+						Usage: "Also drop mails whose sender, subject, timestamp and body match an earlier mail with a different ID",
+					},
+				}, announcementFlags(), compressFlags()),
+				Action: mergeBatchFiles,
+			},
+			{
+				Name:  "archive",
+				Usage: "Zip the mail files that parse successfully and optionally delete them",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "input-dir",
+						Usage:    "Directory containing .mail files",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "output-archive",
+						Usage:    "Zip file to write",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "delete-source",
+						Usage: "Delete the archived mail files once the archive is written",
+					},
+					&cli.StringFlag{
+						Name:  "timestamp-format",
+						Usage: "Format of the TIMESTAMP line: 'unix' or a Go time layout",
+						Value: "unix",
+					},
+					&cli.StringFlag{
+						Name:  "checkpoint",
+						Usage: "Archive the mail files parsed according to this checkpoint of an earlier parse of --input-dir instead of parsing them",
+					},
+				},
+				Action: archiveMailFiles,
+			},
+			{
+				Name:  "convert",
+				Usage: "Convert a mail batch between output formats",
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "input",
+						Aliases:  []string{"i"},
+						Usage:    "Input batch file",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "input-format",
+						Usage: "Input format: json, csv or ndjson",
+						Value: "json",
+					},
+					&cli.StringFlag{
+						Name:     "output",
+						Aliases:  []string{"o"},
+						Usage:    "Output file",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "output-format",
+						Usage:    "Output format: json, csv, ndjson, xml, xlsx, parquet, proto or sqlite",
+						Required: true,
+					},
+				}, compressFlags()),
+				Action: convertBatch,
+			},
+			{
+				Name:  "generate",
+				Usage: "Write .mail files from a mail batch",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "from-json",
+						Usage:    "Input JSON batch file whose mails are written as .mail files",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "output-dir",
+						Usage:    "Directory to write the .mail files to",
+						Required: true,
+					},
+				},
+				Action: generateMailFiles,
+			},
+			{
+				Name:  "tree",
+				Usage: "Print the sender hierarchy of a mail batch as a tree",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+				},
+				Action: senderTree,
+			},
+			{
+				Name:  "validate",
+				Usage: "Check a batch for missing fields, migrating batches of older schema versions",
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse, or - for stdin",
+						Value:   "mail_data.json",
+					},
+				}, tagFlags()),
+				Action: validateBatch,
+			},
+			{
+				Name:  "export",
+				Usage: "Export a mail batch for other tools, such as InfluxDB",
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse, or - for stdin",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output file, or - for stdout",
+						Value:   "-",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format (json, csv, ndjson, xml, xlsx, parquet, proto, sqlite, influx)",
+						Value: "json",
+					},
+					&cli.StringFlag{
+						Name:  "body",
+						Usage: "Mail bodies in the output: full, summary (first line, truncated) or none",
+						Value: BodyModeFull,
+					},
+					&cli.BoolFlag{
+						Name:  "omit-body",
+						Usage: "Leave out mail bodies from the output, shorthand for --body none",
+					},
+					&cli.BoolFlag{
+						Name:  "exclude-broadcasts",
+						Usage: "Leave out city and guild broadcasts and recompute the statistics without them",
+					},
+				}, influxFlags(), compressFlags()),
+				Action: exportBatch,
+			},
+			{
+				Name:  "serve",
+				Usage: "Serve a mail batch over HTTP",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input JSON batch file produced by parse",
+						Value:   "mail_data.json",
+					},
+					&cli.StringFlag{
+						Name:  "addr",
+						Usage: "Address to listen on",
+						Value: ":8080",
+					},
+				},
+				Action: serveBatch,
+			},
+			{
+				Name:  "watch",
+				Usage: "Watch a mail directory and output new mails as they arrive",
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Mail directory of the SWG client to watch",
+						Value:   ".",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output file new mails are appended to, or - for stdout",
+						Value:   "-",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format: ndjson or influx",
+						Value: "ndjson",
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Usage: "How often to check new mail files, or to scan the directory if file system notifications are unavailable",
+						Value: 2 * time.Second,
+					},
+					&cli.BoolFlag{
+						Name:  "include-existing",
+						Usage: "Also output the mail files present when watching starts",
+					},
+					&cli.StringFlag{
+						Name:  "addr",
+						Usage: "Also serve the mails output so far and their statistics over HTTP on this address, as for serve",
+					},
+					&cli.DurationFlag{
+						Name:  "parse-timeout",
+						Usage: "Skip mail files that take longer than this to parse, so a malformed file cannot stall watching; 0 disables the timeout",
+						Value: 5 * time.Second,
+					},
+					&cli.StringFlag{
+						Name:  "timestamp-format",
+						Usage: "Format of the TIMESTAMP line: unix or a Go time layout",
+						Value: "unix",
+					},
+				}, filterFlags(), influxFlags()),
+				Action: watchMails,
+			},
+			{
+				Name:  "daemon",
+				Usage: "Keep running and rescan mail directories at a fixed interval",
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringSliceFlag{
+						Name:    "input",
+						Aliases: []string{"i"},
+						Usage:   "Input directory containing .mail files (repeatable)",
+						Value:   []string{"./testdata"},
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output file rewritten with all mails after every scan",
+						Value:   "mail_data.json",
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Usage: "Time between two scans",
+						Value: 5 * time.Minute,
+					},
+					&cli.StringFlag{
+						Name:  "state-file",
+						Usage: "Record processed mail files and mails in this file, so a restarted daemon does not parse them again",
+					},
+					&cli.StringFlag{
+						Name:  "addr",
+						Usage: "Also serve the mails and statistics over HTTP on this address, as for serve",
+					},
+					&cli.StringFlag{
+						Name:  "timestamp-format",
+						Usage: "Format of the TIMESTAMP line: unix or a Go time layout",
+						Value: "unix",
+					},
+				}, filterFlags()),
+				Action: runDaemon,
+			},
+		},
+	}
+
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseMailFiles(ctx context.Context, cmd *cli.Command) error {
+	inputDirs := cmd.StringSlice("input")
+	outputFile := cmd.String("output")
+	verbose := cmd.Bool("verbose")
+	status := statusOutput(outputFile)
+
+	filterOpts, err := filterOptsFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts := ParseOptions{
+		FilterOpts:  filterOpts,
+		Verbose:     verbose,
+		StrictIDs:   cmd.Bool("strict-ids"),
+		Strict:      cmd.Bool("strict"),
+		FailOnError: cmd.Bool("fail-on-error"),
+
+		NoDedupContent:        cmd.Bool("no-dedup-content"),
+		NormalizeIDs:          cmd.Bool("normalize-ids"),
+		InferCharacterFromDir: cmd.Bool("infer-character-from-dir"),
+		Galaxy:                cmd.String("galaxy"),
+		Tags:                  cmd.StringSlice("tag"),
+
+		TimestampFormat:   cmd.String("timestamp-format"),
+		ScannerBufferSize: int(cmd.Int("scanner-buffer-size")),
+		MaxFileSize:       cmd.Int64("max-file-size"),
+		RecoverHeaders:    int(cmd.Int("recover-headers")),
+		ParseTimeout:      cmd.Duration("parse-timeout"),
+		Workers:           int(cmd.Int("parse-workers")),
+		MaxRetries:        int(cmd.Int("max-retries")),
+		RetryBackoff:      cmd.Duration("retry-backoff"),
+	}
+
+	if sendersFile := cmd.String("system-senders-file"); sendersFile != "" {
+		systemSenders, err := loadSystemSenders(sendersFile)
+		if err != nil {
+			return err
+		}
+		opts.SystemSenders = systemSenders
+	}
+
+	if itemDBFile := cmd.String("item-db"); itemDBFile != "" {
+		itemDB, err := loadItemDB(itemDBFile)
+		if err != nil {
+			return err
+		}
+		opts.ItemDB = itemDB
+	}
+
+	if rulesFile := cmd.String("item-key-rules"); rulesFile != "" {
+		rules, err := loadItemKeyRules(rulesFile)
+		if err != nil {
+			return err
+		}
+		opts.ItemKeyRules = rules
+	}
+
+	if patternsFile := cmd.String("patterns-file"); patternsFile != "" {
+		patterns, err := loadServerPatterns(patternsFile)
+		if err != nil {
+			return err
+		}
+		opts.Patterns = patterns
+	}
+
+	if rulesFile := cmd.String("item-category-rules"); rulesFile != "" {
+		rules, err := loadItemCategoryRules(rulesFile)
+		if err != nil {
+			return err
+		}
+		opts.ItemCategoryRules = rules
+	}
+
+	idFormat := cmd.String("id-format")
+	if idFormat != "opaque" && idFormat != "sequential" {
+		return fmt.Errorf("unsupported --id-format %q, expected opaque or sequential", idFormat)
+	}
+
+	if outputFile == "-" && (cmd.Bool("append") || cmd.Bool("markdown-report")) {
+		return fmt.Errorf("--append and --markdown-report require an output file")
+	}
+
+	keyCase := cmd.String("key-case")
+	if keyCase != KeyCaseSnake && keyCase != KeyCaseCamel {
+		return fmt.Errorf("unsupported --key-case %q, expected snake or camel", keyCase)
+	}
+	if keyCase == KeyCaseCamel && cmd.Bool("append") {
+		return fmt.Errorf("--append requires --key-case snake")
+	}
+
+	compression, err := compressionFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+	if compression != CompressionNone && cmd.Bool("append") {
+		return fmt.Errorf("--append cannot be used with --compress")
+	}
+
+	if opts.ScannerBufferSize <= 0 || opts.ScannerBufferSize > maxScannerBufferSize {
+		return fmt.Errorf("--scanner-buffer-size must be between 1 and %d", maxScannerBufferSize)
+	}
+	if opts.MaxFileSize <= 0 {
+		return fmt.Errorf("--max-file-size must be positive")
+	}
+	if opts.RecoverHeaders < 0 {
+		return fmt.Errorf("--recover-headers must not be negative")
+	}
+	if opts.Workers < 0 {
+		return fmt.Errorf("--parse-workers must not be negative")
+	}
+	bodyMode, err := bodyModeFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	stateFile := cmd.String("state-file")
+	if cmd.Bool("full") && stateFile == "" {
+		return fmt.Errorf("--full requires --state-file")
+	}
+	if stateFile != "" {
+		if cmd.Bool("full") {
+			opts.State = newParseState()
+		} else if opts.State, err = loadParseState(stateFile); err != nil {
+			return err
+		}
+	}
+
+	if cacheDir := cmd.String("cache-dir"); cacheDir != "" {
+		if opts.Cache, err = openParseCache(cacheDir, cmd); err != nil {
+			return err
+		}
+	}
+
+	format := cmd.String("format")
+	switch format {
+	case "json":
+	case "csv", "xlsx", "parquet", "proto", "sqlite":
+		// JSON only
+		for _, name := range []string{"append", "self-describing"} {
+			if cmd.IsSet(name) {
+				return fmt.Errorf("--%s requires --format json", name)
+			}
+		}
+		if keyCase != KeyCaseSnake {
+			return fmt.Errorf("--format %s requires --key-case snake", format)
+		}
+	case "ndjson":
+		// These need all mails at once, which streaming avoids
+		for _, name := range []string{"append", "markdown-report", "self-describing", "dedup-announcements", "goal", "flag-short-body"} {
+			if cmd.IsSet(name) {
+				return fmt.Errorf("--%s requires --format json", name)
+			}
+		}
+		if keyCase != KeyCaseSnake {
+			return fmt.Errorf("--format ndjson requires --key-case snake")
+		}
+	default:
+		return fmt.Errorf("unsupported --format %q, expected json, csv, xlsx, parquet, proto, sqlite or ndjson", format)
+	}
+
+	// Checkpoints hold the partial batch, streamed output has none
+	checkpointEvery := int(cmd.Int("checkpoint-every"))
+	if checkpointEvery < 0 {
+		return fmt.Errorf("--checkpoint-every must not be negative")
+	}
+	if cmd.Bool("resume") && (outputFile == "-" || format == "ndjson" || checkpointEvery == 0) {
+		return fmt.Errorf("--resume requires an output file, --format json or csv and checkpoints")
+	}
+	if checkpointEvery > 0 && outputFile != "-" && format != "ndjson" {
+		checkpointFile := checkpointPath(outputFile)
+		if cmd.Bool("resume") {
+			if opts.Checkpoint, err = loadParseCheckpoint(checkpointFile, inputDirs, checkpointEvery); err != nil {
+				return err
+			}
+			if opts.Checkpoint != nil {
+				fmt.Fprintf(status, "Resuming from %s with %d processed mail files\n", checkpointFile, len(opts.Checkpoint.Processed))
+			} else {
+				fmt.Fprintf(status, "No checkpoint found at %s, starting over\n", checkpointFile)
+			}
+		}
+		if opts.Checkpoint == nil {
+			if opts.Checkpoint, err = newParseCheckpoint(checkpointFile, inputDirs, checkpointEvery); err != nil {
+				return err
+			}
+		}
+	}
+
+	if verbose {
+		fmt.Fprintf(status, "Parsing mail files from: %s\n", strings.Join(inputDirs, ", "))
+		fmt.Fprintf(status, "Output file: %s\n", outputFile)
+	}
+	if format == "ndjson" {
+		return streamParsedMails(ctx, cmd, inputDirs, opts, bodyMode, compression)
+	}
+
+	// Print the progress periodically and on SIGUSR1 (or SIGINFO) during long runs
+	opts.Progress = newProgressTracker()
+	go opts.Progress.countMailFiles(inputDirs...)
+	stopProgress := reportProgress(opts.Progress, parseProgressInterval(cmd), os.Stderr)
+
+	result, err := parseMailFromDirectories(ctx, inputDirs, opts)
+	stopProgress()
+	if err != nil {
+		return fmt.Errorf("failed to parse mail files: %w", err)
+	}
+	mailData := result.Mails
+	parsedCount := len(mailData)
+
+	// Merge with the previous output in append mode
+	if cmd.Bool("append") {
+		existing, err := loadExistingBatch(outputFile)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			mailData = mergeBatches(*existing, MailBatch{Mails: mailData}).Mails
+			if verbose {
+				fmt.Fprintf(status, "Merged with %d existing mails from %s\n", len(existing.Mails), outputFile)
+			}
+		}
+	}
+
+	var announcementsCollapsed int
+	if cmd.Bool("dedup-announcements") {
+		mailData, announcementsCollapsed = collapseAnnouncements(mailData, cmd.String("announcement-sender"))
+	}
+
+	if opts.Galaxy == "" {
+		fillGalaxies(mailData)
+	}
+
+	// Drop the local paths before they end up in the output or the stats
+	if cmd.Bool("strip-source") {
+		for i := range mailData {
+			mailData[i].Source = ""
+		}
+	}
+
+	// Generate statistics
+	stats := generateMailStats(mailData)
+	stats.AnnouncementsCollapsed = announcementsCollapsed
+	stats.UnreadableDirectories = result.UnreadableDirectories
+	stats.RetriedFiles = result.RetriedFiles
+	stats.DuplicateMails = result.DuplicateMails
+	stats.ContentDuplicateMails = result.ContentDuplicates
+	stats.ValidationFailures = result.ValidationFailures
+	if idFormat == "sequential" {
+		gaps, _ := findSequentialGaps(mailData)
+		stats.SequentialGapCount = len(gaps)
+		for _, gap := range gaps {
+			stats.MissingIDCount += gap.MissingCount
+		}
+	}
+	if opts.ItemDB != nil {
+		stats.UnrecognizedItems = findUnrecognizedItems(mailData, opts.ItemDB)
+	}
+	if n := int(cmd.Int("flag-short-body")); n > 0 {
+		stats.ShortBodyMails = findShortBodyMails(mailData, n)
+	}
+	goal := cmd.Int64("goal")
+	if goal > 0 {
+		stats.GoalProgress = sanitizeFloat(goalProgressPercent(stats.TotalRevenue, goal))
+	}
+
+	// Bodies are only dropped now, after all statistics are computed
+	applyBodyMode(mailData, bodyMode)
+
+	// Create batch for export
+	batch := MailBatch{
+		Mails: mailData,
+		Stats: stats,
+	}
+
+	switch format {
+	case "csv":
+		err = writeParsedCSV(status, outputFile, batch, compression)
+	case "xlsx":
+		err = writeOutputFile(outputFile, compression, func(w io.Writer) error {
+			return writeXLSX(w, batch)
+		})
+	case "parquet":
+		err = writeOutputFile(outputFile, compression, func(w io.Writer) error {
+			return writeParquet(w, batch.Mails)
+		})
+	case "proto":
+		err = writeOutputFile(outputFile, compression, func(w io.Writer) error {
+			return writeProto(w, batch)
+		})
+	case "sqlite":
+		err = writeOutputFile(outputFile, compression, func(w io.Writer) error {
+			return writeSQLite(w, batch)
+		})
+	default:
+		err = writeBatchFileWithOptions(outputFile, batch, jsonOptions{
+			KeyCase:        keyCase,
+			SelfDescribing: cmd.Bool("self-describing"),
+		}, compression)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The state is only saved once the new mails are safely written
+	if opts.State != nil {
+		if err := opts.State.save(stateFile); err != nil {
+			return err
+		}
+	}
+	if opts.Checkpoint != nil {
+		if err := opts.Checkpoint.remove(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(status, "Successfully parsed %d mail files\n", parsedCount)
+	if result.DuplicateMails > 0 {
+		fmt.Fprintf(status, "Duplicate mails dropped: %d (%d by content)\n", result.DuplicateMails, result.ContentDuplicates)
+	}
+	if result.UnchangedFiles > 0 || result.KnownMails > 0 {
+		fmt.Fprintf(status, "Already processed: %d unchanged files, %d known mails\n", result.UnchangedFiles, result.KnownMails)
+	}
+	if opts.Cache != nil {
+		fmt.Fprintf(status, "Served from cache: %d mail files\n", opts.Cache.hits.Load())
+	}
+	if len(mailData) != parsedCount {
+		fmt.Fprintf(status, "Total mails in output: %d\n", len(mailData))
+	}
+	fmt.Fprintf(status, "Sale notifications: %d\n", stats.SaleNotifications)
+	fmt.Fprintf(status, "Total revenue: %s\n", FormatCredits(stats.TotalRevenue))
+	if stats.VendorSaleCount > 0 || stats.BazaarSaleCount > 0 {
+		fmt.Fprintf(status, "Vendor revenue: %s (%d sales), bazaar revenue: %s (%d sales)\n",
+			FormatCredits(stats.VendorRevenue), stats.VendorSaleCount,
+			FormatCredits(stats.BazaarRevenue), stats.BazaarSaleCount)
+	}
+	fmt.Fprintf(status, "Results written to: %s\n", outputFile)
+
+	if err := reportSkippedFiles(status, outputFile, result.SkippedFiles); err != nil {
+		return err
+	}
+
+	if goal > 0 {
+		eta, hasETA := estimateGoalETA(mailData, stats, goal)
+		fmt.Fprintln(status, formatGoalProgress(goal, stats.TotalRevenue, eta, hasETA))
+	}
+
+	if cmd.Bool("markdown-report") {
+		tmpl, err := loadMarkdownTemplate(cmd.String("markdown-template"))
+		if err != nil {
+			return err
+		}
+
+		var report bytes.Buffer
+		if err := renderMarkdownReport(&report, tmpl, batch, cmd.Root().Version); err != nil {
+			return fmt.Errorf("failed to render markdown report: %w", err)
+		}
+
+		reportFile := markdownReportPath(outputFile)
+		if err := writeFileAtomic(reportFile, report.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write markdown report: %w", err)
+		}
+
+		fmt.Fprintf(status, "Markdown report written to: %s\n", reportFile)
+	}
+
+	return nil
+}
+
+// parseProgressInterval returns the interval of the progress lines printed
+// during a parse, 0 for none with --quiet
+func parseProgressInterval(cmd *cli.Command) time.Duration {
+	if cmd.Bool("quiet") {
+		return 0
+	}
+	return progressInterval
+}
+
+// streamParsedMails implements parse --format ndjson: each mail is written
+// as one JSON line as soon as it is parsed rather than collected into a
+// batch, so mails are in directory order, galaxies are not inferred from
+// other mails and no statistics are computed
+func streamParsedMails(ctx context.Context, cmd *cli.Command, inputDirs []string, opts ParseOptions, bodyMode string, compression string) error {
+	outputFile := cmd.String("output")
+	status := statusOutput(outputFile)
+	stripSource := cmd.Bool("strip-source")
+
+	// Only the aggregates are kept rather than the mails. What still grows
+	// with the input is small per mail file: its path until parsing starts,
+	// the keys used to drop duplicates, and the sale times for the
+	// inter-sale intervals.
+	streamed := 0
+	acc := newStatsAccumulator()
+	parse := func(w io.Writer) (*ParseResult, error) {
+		encoder := json.NewEncoder(w)
+		opts.OnMail = func(mail MailData) error {
+			if stripSource {
+				mail.Source = ""
+			}
+			acc.add(&mail)
+			mails := []MailData{mail}
+			applyBodyMode(mails, bodyMode)
+			if err := encoder.Encode(mails[0]); err != nil {
+				return fmt.Errorf("failed to write mail %s: %w", mail.MailID, err)
+			}
+			streamed++
+			return nil
+		}
+
+		opts.Progress = newProgressTracker()
+		go opts.Progress.countMailFiles(inputDirs...)
+		stopProgress := reportProgress(opts.Progress, parseProgressInterval(cmd), os.Stderr)
+		defer stopProgress()
+		return parseMailFromDirectories(ctx, inputDirs, opts)
+	}
+
+	var result *ParseResult
+	write := compressed(compression, func(w io.Writer) (err error) {
+		result, err = parse(w)
+		return err
+	})
+	var err error
+	if outputFile == "-" {
+		err = write(os.Stdout)
+	} else {
+		// Renamed into place once complete, like the JSON output
+		err = writeFileAtomicFunc(outputFile, 0644, write)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse mail files: %w", err)
+	}
+
+	if opts.State != nil {
+		if err := opts.State.save(cmd.String("state-file")); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(status, "Streamed %d mails\n", streamed)
+	if outputFile != "-" {
+		stats := acc.stats()
+		stats.UnreadableDirectories = result.UnreadableDirectories
+		stats.RetriedFiles = result.RetriedFiles
+		stats.DuplicateMails = result.DuplicateMails
+		stats.ContentDuplicateMails = result.ContentDuplicates
+		stats.ValidationFailures = result.ValidationFailures
+
+		statsFile := statsReportPath(outputFile)
+		if err := writeStatsReport(statsFile, stats); err != nil {
+			return err
+		}
+		fmt.Fprintf(status, "Statistics written to: %s\n", statsFile)
+	}
+	if result.DuplicateMails > 0 {
+		fmt.Fprintf(status, "Duplicate mails dropped: %d (%d by content)\n", result.DuplicateMails, result.ContentDuplicates)
+	}
+	if result.UnchangedFiles > 0 || result.KnownMails > 0 {
+		fmt.Fprintf(status, "Already processed: %d unchanged files, %d known mails\n", result.UnchangedFiles, result.KnownMails)
+	}
+	if opts.Cache != nil {
+		fmt.Fprintf(status, "Served from cache: %d mail files\n", opts.Cache.hits.Load())
+	}
+	fmt.Fprintf(status, "Results written to: %s\n", outputFile)
+	return reportSkippedFiles(status, outputFile, result.SkippedFiles)
+}
+
+func filterBatch(ctx context.Context, cmd *cli.Command) error {
+	filterOpts, err := filterOptsFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+	compression, err := compressionFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	mails := ApplyFilters(batch.Mails, filterOpts)
+	if err := sortMails(mails, cmd.String("sort-by"), cmd.Bool("sort-desc")); err != nil {
+		return err
+	}
+
+	outputFile := cmd.String("output")
+	status := statusOutput(outputFile)
+	if err := writeBatchFile(outputFile, MailBatch{
+		Mails: mails,
+		Stats: generateMailStats(mails),
+	}, compression); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(status, "Kept %d of %d mails\n", len(mails), len(batch.Mails))
+	fmt.Fprintf(status, "Results written to: %s\n", outputFile)
+
+	return nil
+}
+
+func weeklyReport(ctx context.Context, cmd *cli.Command) error {
+	format := cmd.String("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q, expected text or json", format)
+	}
+
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	summaries := computeWeeklySummaries(batch.Mails)
+
+	var out bytes.Buffer
+	if format == "json" {
+		jsonData, err := json.MarshalIndent(summaries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		out.Write(jsonData)
+		out.WriteString("\n")
+	} else {
+		w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WEEK\tMAILS\tREVENUE\tTOP ITEM\tTOP BUYER\tYOY GROWTH")
+		for _, summary := range summaries {
+			growth := "-"
+			if summary.YearOverYearGrowth != nil {
+				growth = fmt.Sprintf("%+.1f%%", *summary.YearOverYearGrowth)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", summary.Week, summary.MailCount,
+				FormatCredits(summary.TotalRevenue), summary.TopItem, summary.TopBuyer, growth)
+		}
+		w.Flush()
+	}
+
+	outputFile := cmd.String("output")
+	if outputFile == "" {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+
+	if err := writeFileAtomic(outputFile, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+func priceHistory(ctx context.Context, cmd *cli.Command) error {
+	bucket := cmd.String("bucket")
+	if _, err := periodLabel(time.Time{}, bucket); err != nil {
+		return fmt.Errorf("%w, expected day, week, month or quarter", err)
+	}
+
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	buckets := PriceHistoryBuckets(batch.Mails, cmd.String("item"), bucket)
+
+	jsonData, err := json.MarshalIndent(buckets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+// loadItemDB reads a JSON object mapping raw item names to canonical item names
+func loadItemDB(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item database: %w", err)
+	}
+
+	itemDB := make(map[string]string)
+	if err := json.Unmarshal(data, &itemDB); err != nil {
+		return nil, fmt.Errorf("failed to parse item database: %w", err)
+	}
+
+	return itemDB, nil
+}
+
+// loadSystemSenders reads a JSON object mapping senders to labels
+func loadSystemSenders(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system senders file: %w", err)
+	}
+
+	systemSenders := make(map[string]string)
+	if err := json.Unmarshal(data, &systemSenders); err != nil {
+		return nil, fmt.Errorf("failed to parse system senders file: %w", err)
+	}
+
+	return systemSenders, nil
+}
+
+func priceTrend(ctx context.Context, cmd *cli.Command) error {
+	bucket := cmd.String("bucket")
+	if bucket != "week" && bucket != "month" && bucket != "quarter" {
+		return fmt.Errorf("unsupported bucket %q, expected week, month or quarter", bucket)
+	}
+
+	format := cmd.String("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q, expected text or json", format)
+	}
+
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	points := ComputePriceTrend(batch.Mails, cmd.String("item"), bucket)
+
+	if format == "json" {
+		jsonData, err := json.MarshalIndent(points, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PERIOD\tAVG PRICE\tSALES\tCHANGE\tTREND")
+	for _, point := range points {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%+.1f%%\t%s\n", point.Period, FormatCredits(point.AvgPrice),
+			point.Count, point.WeekOverWeekChangePct, point.Trend)
+	}
+	return w.Flush()
+}
+
+func idGaps(ctx context.Context, cmd *cli.Command) error {
+	if idFormat := cmd.String("id-format"); idFormat != "sequential" {
+		return fmt.Errorf("unsupported --id-format %q, expected sequential", idFormat)
+	}
+
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	gaps, skipped := findSequentialGaps(batch.Mails)
+	for _, id := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: Skipping non-integer mail ID %q\n", id)
+	}
+	if gaps == nil {
+		gaps = []IDGap{}
+	}
+
+	jsonData, err := json.MarshalIndent(gaps, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+func balanceHistory(ctx context.Context, cmd *cli.Command) error {
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	points := ComputeBalanceSeries(batch.Mails, cmd.Int64("initial-balance"))
+
+	jsonData, err := json.MarshalIndent(points, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+func compareBatch(ctx context.Context, cmd *cli.Command) error {
+	format := cmd.String("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q, expected text or json", format)
+	}
+
+	var dates [4]time.Time
+	for i, name := range []string{"period-a-start", "period-a-end", "period-b-start", "period-b-end"} {
+		date, err := parseFilterDate(cmd.String(name))
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", name, err)
+		}
+		dates[i] = date
+	}
+
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	report := buildComparisonReport(batch.Mails, dates[0], dates[1], dates[2], dates[3])
+	if report.OverlapWarning && !cmd.Bool("allow-overlap") {
+		warnPeriodOverlap(batch.Mails, dates[0], dates[1], dates[2], dates[3])
+	}
+
+	if format == "json" {
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	return renderComparisonReport(os.Stdout, report)
+}
+
+func mergeBatchFiles(ctx context.Context, cmd *cli.Command) error {
+	inputs := cmd.StringSlice("input")
+
+	workers := int(cmd.Int("merge-workers"))
+	if workers == 0 {
+		workers = len(inputs)
+	}
+	if workers < 1 {
+		return fmt.Errorf("--merge-workers must be at least 1")
+	}
+	workers = min(workers, maxMergeWorkers)
+	compression, err := compressionFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	batches, err := readBatchFiles(ctx, inputs, workers)
+	if err != nil {
+		return err
+	}
+
+	mails, duplicateIDs, contentDuplicates := mergeMails(batches, cmd.Bool("dedup-by-content"))
+
+	var announcementsCollapsed int
+	if cmd.Bool("dedup-announcements") {
+		mails, announcementsCollapsed = collapseAnnouncements(mails, cmd.String("announcement-sender"))
+	}
+
+	stats := generateMailStats(mails)
+	stats.AnnouncementsCollapsed = announcementsCollapsed
+
+	outputFile := cmd.String("output")
+	status := statusOutput(outputFile)
+	if err := writeBatchFile(outputFile, MailBatch{
+		Mails: mails,
+		Stats: stats,
+		Merge: &MergeInfo{
+			Inputs:                   inputs,
+			DuplicateIDsRemoved:      duplicateIDs,
+			ContentDuplicatesRemoved: contentDuplicates,
+		},
+	}, compression); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(status, "Merged %d mails from %d batches\n", len(mails), len(inputs))
+	if cmd.Bool("dedup-by-content") {
+		fmt.Fprintf(status, "Content duplicates removed: %d\n", contentDuplicates)
+	}
+	fmt.Fprintf(status, "Results written to: %s\n", outputFile)
+
+	return nil
+}
+
+func archiveMailFiles(ctx context.Context, cmd *cli.Command) error {
+	inputDir := cmd.String("input-dir")
+	archivePath := cmd.String("output-archive")
+
+	files, err := mailFilesToArchive(ctx, inputDir, cmd.String("checkpoint"), cmd.String("timestamp-format"))
+	if err != nil {
+		return err
+	}
+
+	// The archive is written completely before any source file is removed
+	if err := writeMailArchive(archivePath, inputDir, files); err != nil {
+		return err
+	}
+	fmt.Printf("Archived %d mail files to: %s\n", len(files), archivePath)
+
+	if cmd.Bool("delete-source") {
+		for _, path := range files {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to delete source file: %w", err)
+			}
+		}
+		fmt.Printf("Deleted %d source files\n", len(files))
+	}
+
+	return nil
+}
+
+// mailFilesToArchive returns the mail files below inputDir that parse
+// successfully, including duplicates of other mails, which are archived
+// like the rest. With checkpointFile, the files parsed by the checkpoint of
+// an earlier parse of inputDir are returned instead of parsing again.
+func mailFilesToArchive(ctx context.Context, inputDir, checkpointFile, timestampFormat string) ([]string, error) {
+	if checkpointFile != "" {
+		checkpoint, err := loadParseCheckpoint(checkpointFile, []string{inputDir}, 1)
+		if err != nil {
+			return nil, err
+		}
+		if checkpoint == nil {
+			return nil, fmt.Errorf("no checkpoint found at %s", checkpointFile)
+		}
+		return checkpoint.parsedFiles(), nil
+	}
+
+	// Duplicates parse like any other mail, so their content hashes need
+	// not be compared
+	result, err := parseMailFromDirectory(ctx, inputDir, ParseOptions{TimestampFormat: timestampFormat, NoDedupContent: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mail files: %w", err)
+	}
+	return result.ParsedFiles, nil
+}
+
+func convertBatch(ctx context.Context, cmd *cli.Command) error {
+	compression, err := compressionFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if inputFile := cmd.String("input"); inputFile != "-" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	batch, err := readBatch(input, cmd.String("input-format"))
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := writeBatch(&out, *batch, cmd.String("output-format")); err != nil {
+		return err
+	}
+
+	outputFile := cmd.String("output")
+	status := statusOutput(outputFile)
+	err = writeOutputFile(outputFile, compression, func(w io.Writer) error {
+		_, err := w.Write(out.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(status, "Converted %d mails to %s\n", len(batch.Mails), outputFile)
+	return nil
+}
+
+func generateMailFiles(ctx context.Context, cmd *cli.Command) error {
+	batch, err := readBatchFile(cmd.String("from-json"))
+	if err != nil {
+		return err
+	}
+
+	outputDir := cmd.String("output-dir")
+	if err := writeMailFiles(batch.Mails, outputDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d mail files to: %s\n", len(batch.Mails), outputDir)
+	return nil
+}
+
+func senderTree(ctx context.Context, cmd *cli.Command) error {
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+
+	// Rebuild the tree from the sender counts, as decoded JSON leaves are no longer ints
+	renderSenderTree(os.Stdout, buildSenderTree(batch.Stats.Senders))
+	return nil
+}
+
+func validateBatch(ctx context.Context, cmd *cli.Command) error {
+	inputFile := cmd.String("input")
+	var data []byte
+	var err error
+	if inputFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(inputFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	version, err := detectSchemaVersion(data)
+	if err != nil {
+		return err
+	}
+	batch, err := MigrateBatch(data)
+	if err != nil {
+		return err
+	}
+	if version < CurrentSchemaVersion {
+		fmt.Printf("Migrated batch from schema version %d to %d\n", version, CurrentSchemaVersion)
+	}
+
+	mails := ApplyFilters(batch.Mails, FilterOpts{
+		TagFilter: cmd.StringSlice("tag-filter"),
+		TagAny:    cmd.Bool("tag-any"),
+		NotTags:   cmd.StringSlice("not-tag"),
+	})
+
+	var failures int
+	for i := range mails {
+		for _, validationErr := range validateMail(inputFile, &mails[i]) {
+			fmt.Printf("Mail %s: %s: %v\n", mails[i].MailID, validationErr.Field, validationErr.Err)
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d validation failures in %d mails", failures, len(mails))
+	}
+	fmt.Printf("Batch is valid: %d mails, schema version %d\n", len(mails), CurrentSchemaVersion)
+	return nil
+}
+
+func exportBatch(ctx context.Context, cmd *cli.Command) error {
+	format := cmd.String("format")
+	influxURL := cmd.String("influx-url")
+	if influxURL != "" && format != "influx" {
+		return fmt.Errorf("--influx-url requires --format influx")
+	}
+	bodyMode, err := bodyModeFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+	compression, err := compressionFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+	if influxURL != "" && compression != CompressionNone {
+		return fmt.Errorf("--compress cannot be used with --influx-url")
+	}
+
+	batch, err := readBatchFile(cmd.String("input"))
+	if err != nil {
+		return err
+	}
+	if cmd.Bool("exclude-broadcasts") {
+		batch.Mails = slices.DeleteFunc(batch.Mails, func(mail MailData) bool {
+			return mail.MailType == MailTypeCity || mail.MailType == MailTypeGuild
+		})
+		batch.Stats = generateMailStats(batch.Mails)
+	}
+
+	applyBodyMode(batch.Mails, bodyMode)
+
+	var out bytes.Buffer
+	if err := writeBatch(&out, *batch, format); err != nil {
+		return err
+	}
+
+	if influxURL != "" {
+		if err := pushInflux(ctx, influxURL, cmd.String("influx-org"), cmd.String("influx-bucket"), cmd.String("influx-token"), out.Bytes()); err != nil {
+			return err
+		}
+		fmt.Printf("Pushed %d mails to %s\n", len(batch.Mails), influxURL)
+		return nil
+	}
+
+	outputFile := cmd.String("output")
+	err = writeOutputFile(outputFile, compression, func(w io.Writer) error {
+		_, err := w.Write(out.Bytes())
+		return err
+	})
+	if err != nil || outputFile == "-" {
+		return err
+	}
+	fmt.Printf("Exported %d mails to %s\n", len(batch.Mails), outputFile)
+	return nil
+}
+
+func serveBatch(ctx context.Context, cmd *cli.Command) error {
+	server := &batchServer{}
+	go server.load(cmd.String("input"))
+
+	addr := cmd.String("addr")
+	fmt.Printf("Listening on %s\n", addr)
+	return http.ListenAndServe(addr, server.handler())
+}
+
+func watchMails(ctx context.Context, cmd *cli.Command) error {
+	format := cmd.String("format")
+	if format != "ndjson" && format != "influx" {
+		return fmt.Errorf("unsupported --format %q, expected ndjson or influx", format)
+	}
+	influxURL := cmd.String("influx-url")
+	if influxURL != "" && format != "influx" {
+		return fmt.Errorf("--influx-url requires --format influx")
+	}
+	interval := cmd.Duration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	filterOpts, err := filterOptsFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions{
+		TimestampFormat: cmd.String("timestamp-format"),
+		ParseTimeout:    cmd.Duration("parse-timeout"),
+	}
+
+	inputDir := cmd.String("input")
+	watcher, err := newMailWatcher(inputDir, cmd.Bool("include-existing"))
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", inputDir, err)
+	}
+	defer watcher.close()
+	if err := watcher.notifyError(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: No file system notifications for %s (%v), scanning it every %s instead\n", inputDir, err, interval)
+	}
+
+	outputFile := cmd.String("output")
+	status := statusOutput(outputFile)
+	var out io.Writer = os.Stdout
+	if outputFile != "-" && influxURL == "" {
+		file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	// Stop cleanly on Ctrl+C, after the mails of the current poll are written
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	fmt.Fprintf(status, "Watching %s for new mail files, press Ctrl+C to stop\n", inputDir)
+
+	// Scan the whole directory on demand, in case notifications missed a file
+	rescan := make(chan os.Signal, 1)
+	if signals := rescanSignals(); len(signals) > 0 {
+		signal.Notify(rescan, signals...)
+		defer signal.Stop(rescan)
+	}
+
+	// The mails output so far are also served over HTTP with --addr. Every
+	// poll with new mails swaps in a new batch, so requests never see one
+	// that is being updated.
+	addr := cmd.String("addr")
+	var served []MailData
+	server := &batchServer{}
+	var serveErr <-chan error
+	if addr != "" {
+		server.set(&MailBatch{Stats: generateMailStats(nil)})
+		var httpServer *http.Server
+		httpServer, serveErr = server.listen(addr)
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+	}
+
+	seenIDs := make(map[string]bool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-serveErr:
+			return fmt.Errorf("failed to serve: %w", err)
+		case <-rescan:
+			fmt.Fprintf(status, "Scanning %s for missed mail files\n", inputDir)
+			watcher.rescan()
+		case <-ticker.C:
+		}
+
+		paths, err := watcher.poll()
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %w", inputDir, err)
+		}
+
+		var mails []MailData
+		for _, path := range paths {
+			mail, err := parseMailFileWithTimeout(ctx, path, opts)
+			if errors.Is(err, context.DeadlineExceeded) {
+				fmt.Fprintf(os.Stderr, "Warning: Timed out after %s parsing %s\n", opts.ParseTimeout, path)
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to parse %s: %v\n", path, err)
+				continue
+			}
+			// Files rewritten by the client still hold the same mail
+			id := dedupID(*mail)
+			if seenIDs[id] {
+				continue
+			}
+			seenIDs[id] = true
+
+			if matchesFilters(*mail, filterOpts) {
+				mails = append(mails, *mail)
+			}
+		}
+		if len(mails) == 0 {
+			continue
+		}
+
+		if addr != "" {
+			served = append(served, mails...)
+			server.set(&MailBatch{Mails: slices.Clip(served), Stats: generateMailStats(served)})
+		}
+
+		var buf bytes.Buffer
+		if err := writeBatch(&buf, MailBatch{Mails: mails}, format); err != nil {
+			return err
+		}
+		if influxURL != "" {
+			// A failed push must not end a play session, the next mails may get through
+			if err := pushInflux(ctx, influxURL, cmd.String("influx-org"), cmd.String("influx-bucket"), cmd.String("influx-token"), buf.Bytes()); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to push %d mails: %v\n", len(mails), err)
+				continue
+			}
+		} else if _, err := out.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		fmt.Fprintf(status, "%s: %d new mails\n", time.Now().Format(time.TimeOnly), len(mails))
+	}
+}
+
+func runDaemon(ctx context.Context, cmd *cli.Command) error {
+	interval := cmd.Duration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	filterOpts, err := filterOptsFromCommand(cmd)
+	if err != nil {
+		return err
+	}
+
+	inputDirs := cmd.StringSlice("input")
+	outputFile := cmd.String("output")
+	stateFile := cmd.String("state-file")
+	opts := ParseOptions{
+		FilterOpts:      filterOpts,
+		TimestampFormat: cmd.String("timestamp-format"),
+		State:           newParseState(),
+	}
+	if stateFile != "" {
+		if opts.State, err = loadParseState(stateFile); err != nil {
+			return err
+		}
+	}
+
+	// The mails of earlier runs are kept, the state only lets scans skip them
+	batch := MailBatch{}
+	existing, err := loadExistingBatch(outputFile)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		batch.Mails = existing.Mails
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &batchServer{}
+	var httpServer *http.Server
+	var serveErr <-chan error
+	if addr := cmd.String("addr"); addr != "" {
+		httpServer, serveErr = server.listen(addr)
+	}
+
+	// A scan is never interrupted, shutdown waits for it to complete
+	scan := func() error {
+		result, err := parseMailFromDirectories(context.WithoutCancel(ctx), inputDirs, opts)
+		if err != nil {
+			return fmt.Errorf("failed to parse mail files: %w", err)
+		}
+
+		batch = mergeBatches(batch, MailBatch{Mails: result.Mails})
+		if err := writeBatchFile(outputFile, batch, CompressionNone); err != nil {
+			return err
+		}
+		if stateFile != "" {
+			if err := opts.State.save(stateFile); err != nil {
+				return err
+			}
+		}
+		// The server gets its own copy, the next scan replaces batch
+		snapshot := batch
+		server.set(&snapshot)
+
+		slog.Info("scan complete", "new_mails", len(result.Mails), "total_mails", len(batch.Mails),
+			"skipped_files", len(result.SkippedFiles), "output", outputFile)
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := scan(); err != nil {
+			slog.Error("scan failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("shutting down")
+			if httpServer != nil {
+				shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+				defer cancel()
+				return httpServer.Shutdown(shutdownCtx)
+			}
+			return nil
+		case err := <-serveErr:
+			return fmt.Errorf("failed to serve: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// announcementFlags returns the announcement deduplication flags shared by parse and merge
+func announcementFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dedup-announcements",
+			Usage: "Keep only the first of several server announcements with the same body",
+		},
+		&cli.StringFlag{
+			Name:  "announcement-sender",
+			Usage: "Sender prefix identifying server announcements",
+			Value: defaultAnnouncementSender,
 		},
 	}
+}
 
-	if err := cmd.Run(context.Background(), os.Args); err != nil {
-		log.Fatal(err)
+// influxFlags returns the InfluxDB flags shared by export and watch
+func influxFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "influx-url",
+			Usage: "Push the influx lines to the InfluxDB server at this URL instead of writing them",
+		},
+		&cli.StringFlag{
+			Name:  "influx-org",
+			Usage: "InfluxDB organization to write to",
+		},
+		&cli.StringFlag{
+			Name:  "influx-bucket",
+			Usage: "InfluxDB bucket to write to",
+		},
+		&cli.StringFlag{
+			Name:    "influx-token",
+			Usage:   "InfluxDB API token",
+			Sources: cli.EnvVars("INFLUX_TOKEN"),
+		},
 	}
 }
 
-func parseMailFiles(ctx context.Context, cmd *cli.Command) error {
-	inputDir := cmd.String("input")
-	outputFile := cmd.String("output")
-	verbose := cmd.Bool("verbose")
-	senderFilter := cmd.String("sender-filter")
-	subjectFilter := cmd.String("subject-filter")
+// compressFlags returns the --compress flag of the commands writing batches
+func compressFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "compress",
+			Usage: "Compress the output with gzip or zstd",
+		},
+	}
+}
 
-	if verbose {
-		fmt.Printf("Parsing mail files from: %s\n", inputDir)
-		fmt.Printf("Output file: %s\n", outputFile)
+// filterFlags returns the mail filter flags shared by parse and filter
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "sender-filter",
+			Usage: "Filter by sender (e.g., 'SWG.Restoration.auctioner')",
+		},
+		&cli.StringFlag{
+			Name:  "sender-domain",
+			Usage: "Filter by sender domain, the first segment of the sender (e.g., 'SWG')",
+		},
+		&cli.StringFlag{
+			Name:  "subject-filter",
+			Usage: "Filter by subject pattern (e.g., 'Sale Complete')",
+		},
+		&cli.BoolFlag{
+			Name:  "use-normalized-subject",
+			Usage: "Match --subject-filter against the subject without prefixes like '[AUTO]'",
+		},
+		&cli.StringFlag{
+			Name:  "category-filter",
+			Usage: "Filter by mail category: sale, purchase, auction, expired, factory, survey, player or unknown",
+		},
+		&cli.StringFlag{
+			Name:  "type-filter",
+			Usage: "Filter by mail type: sale, purchase, expired, outbid, factory, mission, gcw, city, guild, spam or other",
+		},
+		&cli.StringFlag{
+			Name:  "sale-type-filter",
+			Usage: "Filter sale mails by sale type: vendor, bazaar or unknown",
+		},
+		&cli.StringFlag{
+			Name:  "start-date",
+			Usage: "Only keep mails received on or after this date (YYYY-MM-DD)",
+		},
+		&cli.StringFlag{
+			Name:  "end-date",
+			Usage: "Only keep mails received on or before this date (YYYY-MM-DD)",
+		},
 	}
+}
 
-	mailData, err := parseMailFromDirectory(inputDir, verbose, senderFilter, subjectFilter)
-	if err != nil {
-		return fmt.Errorf("failed to parse mail files: %w", err)
+// tagFlags returns the flags selecting mails by their tags. The parser does
+// not tag mails, so they are only offered by commands reading batches, whose
+// mails may have been tagged since.
+func tagFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "tag-filter",
+			Usage: "Only keep mails carrying all of these tags (repeatable)",
+		},
+		&cli.BoolFlag{
+			Name:  "tag-any",
+			Usage: "Keep mails carrying any of the --tag-filter tags instead of all",
+		},
+		&cli.StringSliceFlag{
+			Name:  "not-tag",
+			Usage: "Drop mails carrying any of these tags (repeatable)",
+		},
 	}
+}
 
-	// Generate statistics
-	stats := generateMailStats(mailData)
+// bodyModeFromCommand returns the --body mode of cmd; --omit-body is
+// shorthand for --body none
+func bodyModeFromCommand(cmd *cli.Command) (string, error) {
+	mode := cmd.String("body")
+	if mode != BodyModeFull && mode != BodyModeSummary && mode != BodyModeNone {
+		return "", fmt.Errorf("unsupported --body %q, expected full, summary or none", mode)
+	}
+	if cmd.Bool("omit-body") {
+		if cmd.IsSet("body") && mode != BodyModeNone {
+			return "", fmt.Errorf("--omit-body conflicts with --body %s", mode)
+		}
+		mode = BodyModeNone
+	}
+	return mode, nil
+}
 
-	// Create batch for export
-	batch := MailBatch{
-		Mails: mailData,
-		Stats: stats,
+// filterOptsFromCommand builds filter options from the flags defined by filterFlags
+func filterOptsFromCommand(cmd *cli.Command) (FilterOpts, error) {
+	startDate, err := parseFilterDate(cmd.String("start-date"))
+	if err != nil {
+		return FilterOpts{}, fmt.Errorf("invalid --start-date: %w", err)
 	}
 
-	// Write to JSON file
-	jsonData, err := json.MarshalIndent(batch, "", "  ")
+	endDate, err := parseFilterDate(cmd.String("end-date"))
 	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+		return FilterOpts{}, fmt.Errorf("invalid --end-date: %w", err)
+	}
+
+	saleTypeFilter := cmd.String("sale-type-filter")
+	switch saleTypeFilter {
+	case "", SaleTypeVendor, SaleTypeBazaar, SaleTypeUnknown:
+	default:
+		return FilterOpts{}, fmt.Errorf("invalid --sale-type-filter %q, expected vendor, bazaar or unknown", saleTypeFilter)
+	}
+
+	mailType := MailType(cmd.String("type-filter"))
+	if mailType != "" && !slices.Contains(mailTypes, mailType) {
+		return FilterOpts{}, fmt.Errorf("invalid --type-filter %q, expected sale, purchase, expired, outbid, mission, city, guild, spam or other", mailType)
+	}
+
+	return FilterOpts{
+		SenderFilter:  cmd.String("sender-filter"),
+		SenderDomain:  cmd.String("sender-domain"),
+		SubjectFilter: cmd.String("subject-filter"),
+		Category:      cmd.String("category-filter"),
+		MailType:      mailType,
+		SaleType:      saleTypeFilter,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		TagFilter:     cmd.StringSlice("tag-filter"),
+		TagAny:        cmd.Bool("tag-any"),
+		NotTags:       cmd.StringSlice("not-tag"),
+
+		UseNormalizedSubject: cmd.Bool("use-normalized-subject"),
+	}, nil
+}
+
+// loadExistingBatch reads a previously written batch, returning nil if the file does not exist
+func loadExistingBatch(path string) (*MailBatch, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return readBatchFile(path)
+}
+
+// maxMergeWorkers caps the number of batch files merge loads at once
+const maxMergeWorkers = 8
+
+// readBatchFiles reads and decodes batch files concurrently, with at most
+// workers batches in flight at once. The batches are returned in the order
+// of paths, regardless of which finishes loading first.
+func readBatchFiles(ctx context.Context, paths []string, workers int) ([]MailBatch, error) {
+	batches := make([]MailBatch, len(paths))
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(workers)
+	for i, path := range paths {
+		group.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			batch, err := readBatchFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			batches[i] = *batch
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// mergeBatches combines the mails of several batches, keeping one mail per
+// mail ID as mergeMails does, and regenerates the statistics
+func mergeBatches(batches ...MailBatch) MailBatch {
+	mails, _, _ := mergeMails(batches, false)
+	return MailBatch{
+		Mails: mails,
+		Stats: generateMailStats(mails),
+	}
+}
+
+// mergeMails combines the mails of several batches sorted by timestamp,
+// mail ID and content hash. Of several mails with the same mail ID, the
+// first in that order is kept. If byContent is set, mails whose content
+// hash was already seen under a different ID are dropped too. Mails are
+// only compared by their fields, so the result does not depend on the
+// order of the batches.
+func mergeMails(batches []MailBatch, byContent bool) (mails []MailData, duplicateIDs, contentDuplicates int) {
+	type hashedMail struct {
+		mail *MailData
+		hash string
+	}
+
+	var all []hashedMail
+	for i := range batches {
+		for j := range batches[i].Mails {
+			mail := &batches[i].Mails[j]
+			hash := mail.ContentHash
+			if hash == "" {
+				hash = contentHash(*mail)
+			}
+			all = append(all, hashedMail{mail, hash})
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if !a.mail.Timestamp.Equal(b.mail.Timestamp) {
+			return a.mail.Timestamp.Before(b.mail.Timestamp)
+		}
+		if a.mail.MailID != b.mail.MailID {
+			return a.mail.MailID < b.mail.MailID
+		}
+		return a.hash < b.hash
+	})
+
+	seenIDs := make(map[string]bool)
+	seenContent := make(map[string]bool)
+	for _, m := range all {
+		id := dedupID(*m.mail)
+		if seenIDs[id] {
+			duplicateIDs++
+			continue
+		}
+		seenIDs[id] = true
+
+		if byContent {
+			if seenContent[m.hash] {
+				contentDuplicates++
+				continue
+			}
+			seenContent[m.hash] = true
+		}
+
+		mails = append(mails, *m.mail)
 	}
 
-	if err := os.WriteFile(outputFile, jsonData, 0644); err != nil {
+	return mails, duplicateIDs, contentDuplicates
+}
+
+// writeBatchFile writes a mail batch as indented JSON, compressed with
+// compression
+func writeBatchFile(path string, batch MailBatch, compression string) error {
+	return writeBatchFileWithOptions(path, batch, jsonOptions{KeyCase: KeyCaseSnake}, compression)
+}
+
+// writeBatchFileWithOptions writes a mail batch as indented JSON encoded
+// according to opts and compressed with compression. The path "-" writes
+// to stdout.
+func writeBatchFileWithOptions(path string, batch MailBatch, opts jsonOptions, compression string) error {
+	return writeOutputFile(path, compression, func(w io.Writer) error {
+		return writeBatchToWriter(w, &batch, opts)
+	})
+}
+
+// writeOutputFile writes the output of write to path, or to stdout for the
+// path "-", compressed with compression
+func writeOutputFile(path string, compression string, write func(w io.Writer) error) error {
+	write = compressed(compression, write)
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	if err := writeFileAtomicFunc(path, 0644, write); err != nil {
 		return fmt.Errorf("failed to write output file: %w", err)
 	}
+	return nil
+}
+
+// writeParsedCSV writes the mails of a parsed batch as CSV to path, and
+// its sales and statistics next to it. Both CSV files are compressed with
+// compression. The path "-" writes only the mails to stdout.
+func writeParsedCSV(status io.Writer, path string, batch MailBatch, compression string) error {
+	err := writeOutputFile(path, compression, func(w io.Writer) error {
+		return writeCSV(w, batch.Mails)
+	})
+	if err != nil || path == "-" {
+		return err
+	}
 
-	fmt.Printf("Successfully parsed %d mail files\n", len(mailData))
-	fmt.Printf("Sale notifications: %d\n", stats.SaleNotifications)
-	fmt.Printf("Results written to: %s\n", outputFile)
+	salesFile := salesCSVPath(path) + compressionExt(compression)
+	err = writeFileAtomicFunc(salesFile, 0644, compressed(compression, func(w io.Writer) error {
+		return writeSalesCSV(w, batch.Mails)
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to write sales: %w", err)
+	}
+	fmt.Fprintf(status, "Sales written to: %s\n", salesFile)
 
+	statsFile := statsReportPath(path)
+	if err := writeStatsReport(statsFile, batch.Stats); err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "Statistics written to: %s\n", statsFile)
 	return nil
 }
 
-func parseMailFromDirectory(inputDir string, verbose bool, senderFilter, subjectFilter string) ([]MailData, error) {
+// writeBatchToWriter writes a mail batch as indented JSON followed by a newline
+func writeBatchToWriter(w io.Writer, b *MailBatch, opts jsonOptions) error {
+	if err := writeJSONWithOptions(w, *b, opts); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// readBatchFile reads a mail batch previously written by the parse command.
+// The path "-" reads from stdin.
+func readBatchFile(path string) (*MailBatch, error) {
+	if path == "-" {
+		return readBatchFromReader(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+	defer file.Close()
+
+	return readBatchFromReader(file)
+}
+
+// readBatchFromReader reads a JSON mail batch
+func readBatchFromReader(r io.Reader) (*MailBatch, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	batch, err := MigrateBatch(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input file: %w", err)
+	}
+
+	return batch, nil
+}
+
+// statusOutput returns where progress messages go: stderr when the results
+// themselves are written to stdout, stdout otherwise
+func statusOutput(outputFile string) io.Writer {
+	if outputFile == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// parseMailFromDirectory parses the mail files below a single directory
+func parseMailFromDirectory(ctx context.Context, inputDir string, opts ParseOptions) (*ParseResult, error) {
+	return parseMailFromDirectories(ctx, []string{inputDir}, opts)
+}
+
+// parseMailFromDirectories parses the mail files below all input
+// directories, deduplicating mail IDs across them
+func parseMailFromDirectories(ctx context.Context, inputDirs []string, opts ParseOptions) (*ParseResult, error) {
 	var allMails []MailData
+	var unreadable []string
+	var validationFailures []ValidationFailure
+	var parsedFiles []string
+	parsedCount := 0
+	var skipped []SkippedFile
+
+	// Track the file each mail ID was first seen in to detect duplicates
+	seenIDs := make(map[string]string)
+	duplicateIDs := make(map[string][]string)
+	seenContent := make(map[string]string)
+	duplicateMails := 0
+	contentDuplicates := 0
+	unchangedFiles := 0
+	knownMails := 0
+
+	// skip lists a file left out in the errors report
+	skip := func(file SkippedFile) {
+		skipped = append(skipped, file)
+		if opts.Progress != nil {
+			opts.Progress.skipped.Add(1)
+		}
+	}
+
+	// Files read after retries; timed out parses may still report them late
+	var retriedMu sync.Mutex
+	retried := make(map[string]bool)
+	retriedFiles := 0
+	opts.OnRetriedRead = func(filename string) {
+		retriedMu.Lock()
+		defer retriedMu.Unlock()
+		retried[filename] = true
+	}
+
+	// A resumed parse continues with the mails of its checkpoint
+	if opts.Checkpoint != nil {
+		allMails = slices.Clone(opts.Checkpoint.Mails)
+		skipped = slices.Clone(opts.Checkpoint.Skipped)
+		duplicateMails = opts.Checkpoint.DuplicateMails
+		contentDuplicates = opts.Checkpoint.ContentDuplicates
+		for _, mail := range allMails {
+			seenIDs[dedupID(mail)] = mail.Source
+			seenContent[mail.ContentHash] = mail.Source
+		}
+	}
 
-	err := filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
+	for _, inputDir := range inputDirs {
+		source, err := filepath.Abs(inputDir)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if !strings.HasSuffix(path, ".mail") {
+		// collectMail parses a single mail file, found at path below
+		// inputDir or in the archive inputDir, and collects its mail
+		collectMail := func(path string, parse func() (*MailData, error)) error {
+			if opts.Verbose {
+				fmt.Fprintf(os.Stderr, "Processing: %s\n", path)
+			}
+
+			mailData, err := parse()
+			if err != nil {
+				if opts.FailOnError {
+					return fmt.Errorf("failed to parse %s: %w", path, err)
+				}
+				if errors.Is(err, context.DeadlineExceeded) {
+					fmt.Fprintf(os.Stderr, "Warning: Timed out after %s parsing %s\n", opts.ParseTimeout, path)
+					skip(SkippedFile{File: path, Reason: fmt.Sprintf("timed out after %s", opts.ParseTimeout)})
+				} else {
+					if opts.Verbose {
+						fmt.Fprintf(os.Stderr, "Warning: Failed to parse %s: %v\n", path, err)
+					}
+					skip(SkippedFile{File: path, Reason: err.Error()})
+				}
+				return nil // Continue processing other files
+			}
+
+			// Streamed parses do not keep a list that grows with the input
+			parsedCount++
+			if opts.OnMail == nil {
+				parsedFiles = append(parsedFiles, path)
+			}
+			if opts.Progress != nil {
+				opts.Progress.parsed.Add(1)
+			}
+			mailData.Source = source
+
+			if opts.InferCharacterFromDir {
+				mailData.Character = characterFromPath(inputDir, path)
+			}
+			if len(opts.Tags) > 0 {
+				mailData.Tags = slices.Clone(opts.Tags)
+			}
+
+			id := dedupID(*mailData)
+			if firstPath, ok := seenIDs[id]; ok {
+				if len(duplicateIDs[id]) == 0 {
+					duplicateIDs[id] = []string{firstPath}
+				}
+				duplicateIDs[id] = append(duplicateIDs[id], path)
+				if opts.Verbose {
+					fmt.Fprintf(os.Stderr, "Warning: Skipping %s, mail ID %s already seen in %s\n", path, id, firstPath)
+				}
+				skip(SkippedFile{
+					File:      path,
+					Reason:    fmt.Sprintf("duplicate mail ID %s, already seen in %s", id, firstPath),
+					Duplicate: true,
+				})
+				duplicateMails++
+				return nil
+			}
+			seenIDs[id] = path
+
+			// The same mail copied under another mail ID, e.g. from a backup
+			if !opts.NoDedupContent {
+				if firstPath, ok := seenContent[mailData.ContentHash]; ok {
+					if opts.Verbose {
+						fmt.Fprintf(os.Stderr, "Warning: Skipping %s, same content as %s\n", path, firstPath)
+					}
+					skip(SkippedFile{
+						File:      path,
+						Reason:    fmt.Sprintf("duplicate content, already seen in %s", firstPath),
+						Duplicate: true,
+					})
+					duplicateMails++
+					contentDuplicates++
+					return nil
+				}
+				seenContent[mailData.ContentHash] = path
+			}
+
+			if opts.State != nil {
+				if opts.State.seen(*mailData, !opts.NoDedupContent) {
+					knownMails++
+					return nil
+				}
+				opts.State.recordMail(*mailData)
+			}
+
+			if opts.Strict {
+				for _, validationErr := range validateMail(path, mailData) {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", validationErr)
+					validationFailures = append(validationFailures, ValidationFailure{
+						MailID:    mailData.MailID,
+						FieldName: validationErr.Field,
+						Reason:    validationErr.Err.Error(),
+					})
+				}
+			}
+
+			retriedMu.Lock()
+			if retried[path] {
+				retriedFiles++
+			}
+			retriedMu.Unlock()
+
+			// Apply filters
+			if !matchesFilters(*mailData, opts.FilterOpts) {
+				return nil
+			}
+
+			if opts.OnMail != nil {
+				if err := opts.OnMail(*mailData); err != nil {
+					return err
+				}
+			} else {
+				allMails = append(allMails, *mailData)
+			}
+			if opts.Progress != nil {
+				opts.Progress.mails.Add(1)
+			}
 			return nil
 		}
 
-		if verbose {
-			fmt.Printf("Processing: %s\n", path)
+		// addMail is collectMail for mail files not yet processed before
+		// the checkpoint of a resumed parse
+		addMail := func(path string, parse func() (*MailData, error)) error {
+			if opts.Checkpoint == nil {
+				return collectMail(path, parse)
+			}
+			if opts.Checkpoint.done(path) {
+				return nil
+			}
+			if err := collectMail(path, parse); err != nil {
+				return err
+			}
+			return opts.Checkpoint.record(path, allMails, skipped, duplicateMails, contentDuplicates)
 		}
 
-		mailData, err := parseMailFile(path)
-		if err != nil {
-			if verbose {
-				fmt.Printf("Warning: Failed to parse %s: %v\n", path, err)
+		// Archives and dumps are skipped as a whole if unchanged since an
+		// earlier run, see --state-file
+		var inputInfo os.FileInfo
+		if opts.State != nil && (isMailArchive(inputDir) || isMailsaveDump(inputDir)) {
+			if inputInfo, err = os.Stat(inputDir); err != nil {
+				return nil, err
+			}
+			if opts.State.unchanged(inputDir, inputInfo) {
+				unchangedFiles++
+				continue
 			}
-			return nil // Continue processing other files
 		}
 
-		// Apply filters
-		if senderFilter != "" && !strings.Contains(mailData.Sender, senderFilter) {
-			return nil
-		}
+		if isMailArchive(inputDir) {
+			// Archive entries are parsed in memory, without --parse-timeout
+			// and --max-retries, which only apply to reading files
+			err = walkMailArchive(inputDir, maxFileSize(opts), func(name string, data []byte, modTime time.Time) error {
+				path := filepath.Join(inputDir, filepath.FromSlash(name))
+				if opts.Progress != nil {
+					defer opts.Progress.files.Add(1)
+				}
+				return addMail(path, func() (*MailData, error) {
+					return parseMailData(path, data, modTime, opts)
+				})
+			})
+		} else if isMailsaveDump(inputDir) {
+			err = walkMailsaveDump(inputDir, func(name string, data []byte, modTime time.Time) error {
+				if opts.Progress != nil {
+					defer opts.Progress.files.Add(1)
+				}
+				return addMail(name, func() (*MailData, error) {
+					return parseMailData(name, data, modTime, opts)
+				})
+			})
+		} else {
+			var files []walkedFile
+			err = walkMailFiles(inputDir, parseWorkers(opts), func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					// Skip directories we are not allowed to read but keep walking their siblings
+					if os.IsPermission(err) {
+						fmt.Fprintf(os.Stderr, "Warning: Skipping unreadable path %s: %v\n", path, err)
+						unreadable = append(unreadable, path)
+						skip(SkippedFile{File: path, Reason: err.Error()})
+						return nil
+					}
+					return err
+				}
 
-		if subjectFilter != "" && !strings.Contains(mailData.Subject, subjectFilter) {
-			return nil
+				// Only incremental parses need the size and modification time
+				var info os.FileInfo
+				if opts.State != nil {
+					if info, err = entry.Info(); err != nil {
+						skip(SkippedFile{File: path, Reason: err.Error()})
+						return nil
+					}
+				}
+				if opts.State != nil && opts.State.unchanged(path, info) {
+					unchangedFiles++
+					if opts.Progress != nil {
+						opts.Progress.files.Add(1)
+						opts.Progress.skipped.Add(1)
+					}
+					return nil
+				}
+				if opts.Checkpoint != nil && opts.Checkpoint.done(path) {
+					if opts.Progress != nil {
+						opts.Progress.files.Add(1)
+					}
+					return nil
+				}
+				files = append(files, walkedFile{Path: path, Info: info})
+				return nil
+			})
+
+			if err == nil {
+				err = parseFilesConcurrently(ctx, files, opts, func(file walkedFile, mail *MailData, parseErr error) error {
+					// Only files that parsed are recorded, others are retried next run
+					parsed := parsedCount
+					if err := addMail(file.Path, func() (*MailData, error) {
+						return mail, parseErr
+					}); err != nil {
+						return err
+					}
+					if opts.State != nil && parsedCount > parsed {
+						opts.State.recordFile(file.Path, file.Info)
+					}
+					return nil
+				})
+			}
 		}
 
-		allMails = append(allMails, *mailData)
-		return nil
-	})
+		if err != nil {
+			return nil, err
+		}
+		if inputInfo != nil {
+			opts.State.recordFile(inputDir, inputInfo)
+		}
+	}
 
-	if err != nil {
-		return nil, err
+	if opts.StrictIDs && len(duplicateIDs) > 0 {
+		return nil, duplicateIDsError(duplicateIDs)
 	}
 
 	// Sort by timestamp
@@ -154,40 +2616,137 @@ func parseMailFromDirectory(inputDir string, verbose bool, senderFilter, subject
 		return allMails[i].Timestamp.Before(allMails[j].Timestamp)
 	})
 
-	return allMails, nil
+	return &ParseResult{
+		Mails:                 allMails,
+		UnreadableDirectories: unreadable,
+		RetriedFiles:          retriedFiles,
+		ValidationFailures:    validationFailures,
+		ParsedFiles:           parsedFiles,
+		SkippedFiles:          skipped,
+		DuplicateMails:        duplicateMails,
+		ContentDuplicates:     contentDuplicates,
+		UnchangedFiles:        unchangedFiles,
+		KnownMails:            knownMails,
+	}, nil
 }
 
-func generateMailStats(mails []MailData) MailStats {
-	stats := MailStats{
-		TotalMails: len(mails),
-		Senders:    make(map[string]int),
-	}
+// walkedFile is a mail file found while walking an input directory. Info is
+// only set for parses with a state file.
+type walkedFile struct {
+	Path string
+	Info os.FileInfo
+}
 
-	if len(mails) == 0 {
-		return stats
+// parseFilesConcurrently parses mail files with up to opts.Workers parses
+// in flight and calls fn with the result of each file, in the order of
+// files and never concurrently. Results are handed over as soon as all
+// earlier files are done, and parses only start while fewer than
+// parseWindowFactor times opts.Workers results are waiting to be handed
+// over, so a slow file does not pile up the results of the files after it.
+// It stops at the first error fn returns.
+func parseFilesConcurrently(ctx context.Context, files []walkedFile, opts ParseOptions, fn func(file walkedFile, mail *MailData, err error) error) error {
+	type result struct {
+		file walkedFile
+		mail *MailData
+		err  error
+		done chan struct{}
 	}
 
-	// Initialize date range
-	stats.DateRange.StartDate = mails[0].Timestamp
-	stats.DateRange.EndDate = mails[0].Timestamp
+	workers := parseWorkers(opts)
+
+	// Cancelled when fn fails, so the remaining files are not parsed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The results in order; its capacity bounds the results held at once
+	window := make(chan *result, parseWindowFactor*workers)
 
-	for _, mail := range mails {
-		// Update date range
-		if mail.Timestamp.Before(stats.DateRange.StartDate) {
-			stats.DateRange.StartDate = mail.Timestamp
+	var group errgroup.Group
+	group.SetLimit(workers)
+	go func() {
+		defer close(window)
+		for _, file := range files {
+			r := &result{file: file, done: make(chan struct{})}
+			select {
+			case window <- r:
+			case <-ctx.Done():
+				return
+			}
+			group.Go(func() error {
+				defer close(r.done)
+				if err := ctx.Err(); err != nil {
+					r.err = err
+					return nil
+				}
+				r.mail, r.err = parseMailFileWithTimeout(ctx, r.file.Path, opts)
+				if opts.Progress != nil {
+					opts.Progress.files.Add(1)
+				}
+				return nil
+			})
 		}
-		if mail.Timestamp.After(stats.DateRange.EndDate) {
-			stats.DateRange.EndDate = mail.Timestamp
+	}()
+
+	var err error
+	for r := range window {
+		<-r.done
+		if err == nil {
+			if err = fn(r.file, r.mail, r.err); err != nil {
+				cancel()
+			}
 		}
+	}
+
+	group.Wait()
+	return err
+}
+
+// parseWindowFactor times the number of workers is the number of parsed
+// files whose results parseFilesConcurrently holds at most
+const parseWindowFactor = 4
+
+// parseWorkers returns the number of mail files parsed and directories
+// walked concurrently, see --parse-workers
+func parseWorkers(opts ParseOptions) int {
+	if opts.Workers > 0 {
+		return opts.Workers
+	}
+	return runtime.NumCPU()
+}
 
-		// Count senders
-		stats.Senders[mail.Sender]++
+// characterFromPath returns the character a mail file belongs to, judging
+// by its directory below inputDir. The SWG client stores mails in
+// "mail_<character>" directories, so such a directory names the character;
+// otherwise the top-level subdirectory does, as in "<character>/*.mail".
+// Mails directly in inputDir have no character.
+func characterFromPath(inputDir, path string) string {
+	rel, err := filepath.Rel(inputDir, filepath.Dir(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
 
-		// Count sale notifications
-		if mail.Sender == "SWG.Restoration.auctioner" && strings.Contains(mail.Subject, "Sale Complete") {
-			stats.SaleNotifications++
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if name, ok := strings.CutPrefix(segments[i], "mail_"); ok && name != "" {
+			return name
 		}
 	}
+	return segments[0]
+}
+
+// duplicateIDsError describes all duplicate mail IDs and the files they were found in
+func duplicateIDsError(duplicateIDs map[string][]string) error {
+	ids := make([]string, 0, len(duplicateIDs))
+	for id := range duplicateIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "found %d duplicate mail IDs:", len(ids))
+	for _, id := range ids {
+		fmt.Fprintf(&sb, "\n  %s: %s", id, strings.Join(duplicateIDs[id], ", "))
+	}
 
-	return stats
+	return errors.New(sb.String())
 }
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseMailFromDirectoryStrictIDs(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestMail(t, dir, filepath.Join("alice", "1.mail"), "42", "Player", "Hello", 1700000000, "First copy")
+	second := writeTestMail(t, dir, filepath.Join("bob", "1.mail"), "42", "Player", "Hello", 1700000100, "Second copy")
+	writeTestMail(t, dir, "2.mail", "43", "Player", "Other", 1700000200, "Unique")
+
+	tests := []struct {
+		name      string
+		strictIDs bool
+		wantErr   bool
+	}{
+		{"soft dedup", false, false},
+		{"strict", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{StrictIDs: tt.strictIDs})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseMailFromDirectory() error = nil, want duplicate ID error")
+				}
+				for _, want := range []string{"42", first, second} {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q does not mention %s", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMailFromDirectory() error = %v", err)
+			}
+			if len(result.Mails) != 2 || result.DuplicateMails != 1 {
+				t.Errorf("got %d mails and %d duplicates, want 2 and 1", len(result.Mails), result.DuplicateMails)
+			}
+		})
+	}
+}
+
+func TestParseMailFromDirectoryUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	tests := []struct {
+		name       string
+		unreadable []string
+		wantMails  int
+	}{
+		{"all readable", nil, 2},
+		{"unreadable subdirectory", []string{"locked"}, 1},
+		{"nested unreadable subdirectory", []string{filepath.Join("open", "locked")}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestMail(t, dir, "1.mail", "1", "Player", "Readable", 1700000000, "Hello")
+			name := "2.mail"
+			if len(tt.unreadable) > 0 {
+				name = filepath.Join(tt.unreadable[0], "2.mail")
+			}
+			writeTestMail(t, dir, name, "2", "Player", "Locked", 1700000100, "Hidden")
+
+			var want []string
+			for _, sub := range tt.unreadable {
+				path := filepath.Join(dir, sub)
+				if err := os.Chmod(path, 0000); err != nil {
+					t.Fatal(err)
+				}
+				// Lets t.TempDir clean up
+				t.Cleanup(func() { os.Chmod(path, 0755) })
+				want = append(want, path)
+			}
+
+			result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{})
+			if err != nil {
+				t.Fatalf("parseMailFromDirectory() error = %v", err)
+			}
+			if len(result.Mails) != tt.wantMails {
+				t.Errorf("got %d mails, want %d", len(result.Mails), tt.wantMails)
+			}
+			if !slices.Equal(result.UnreadableDirectories, want) {
+				t.Errorf("UnreadableDirectories = %v, want %v", result.UnreadableDirectories, want)
+			}
+		})
+	}
+}
+
+// flakyReader fails the first failures reads with err, then reads from r
+type flakyReader struct {
+	r        io.Reader
+	failures int
+	err      error
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.failures > 0 {
+		f.failures--
+		return 0, f.err
+	}
+	return f.r.Read(p)
+}
+
+func TestParseMailFromDirectoryRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantMails    int
+		wantRetried  int
+		wantAttempts int
+	}{
+		{"no failures", 0, io.ErrUnexpectedEOF, 2, 0, 1},
+		{"fails twice", 2, io.ErrUnexpectedEOF, 2, 1, 3},
+		{"EAGAIN", 1, syscall.EAGAIN, 2, 1, 2},
+		{"fails beyond max retries", 4, io.ErrUnexpectedEOF, 1, 0, 4},
+		{"not retryable", 1, os.ErrPermission, 1, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestMail(t, dir, "1.mail", "1", "Player", "Hello", 1700000000, "Body")
+			flaky := writeTestMail(t, dir, "2.mail", "2", "Player", "Again", 1700000100, "Body")
+
+			data, err := os.ReadFile(flaky)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader := &flakyReader{r: bytes.NewReader(data), failures: tt.failures, err: tt.err}
+			attempts := 0
+
+			opts := ParseOptions{
+				MaxRetries:   3,
+				RetryBackoff: time.Millisecond,
+				ReadFile: func(filename string, maxSize int64) ([]byte, error) {
+					if filename != flaky {
+						return readMailFile(filename, maxSize)
+					}
+					attempts++
+					data, err := io.ReadAll(reader)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read file: %w", err)
+					}
+					return data, nil
+				},
+			}
+			result, err := parseMailFromDirectory(context.Background(), dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Mails) != tt.wantMails {
+				t.Errorf("parsed %d mails, want %d", len(result.Mails), tt.wantMails)
+			}
+			if result.RetriedFiles != tt.wantRetried {
+				t.Errorf("RetriedFiles = %d, want %d", result.RetriedFiles, tt.wantRetried)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("read %s %d times, want %d", flaky, attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestCharacterFromPath(t *testing.T) {
+	inputDir := filepath.FromSlash("/mails")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/mails/1.mail", ""},
+		{"/mails/Han/1.mail", "Han"},
+		{"/mails/Han/2024/1.mail", "Han"},
+		// The SWG client stores mails in mail_<character> directories
+		{"/mails/mail_Leia/1.mail", "Leia"},
+		{"/mails/profiles/mail_Leia/1.mail", "Leia"},
+		{"/mails/mail_Leia/mail_Luke/1.mail", "Luke"},
+		{"/mails/Han/mail_/1.mail", "Han"},
+		{"/other/Han/1.mail", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := characterFromPath(inputDir, filepath.FromSlash(tt.path)); got != tt.want {
+				t.Errorf("characterFromPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInferCharacterFromDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTestMail(t, dir, filepath.Join("Han", "1.mail"), "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Greedo for 1000 credits.")
+	writeTestMail(t, dir, filepath.Join("Han", "2.mail"), "2", "Leia Organa", "Hello", 1705312801, "Hello Han")
+	writeTestMail(t, dir, filepath.Join("profiles", "mail_Leia", "3.mail"), "3", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312802,
+		"Vendor: Crafter has sold Pistol to Luke for 500 credits.")
+	writeTestMail(t, dir, "4.mail", "4", "Han Solo", "Hello", 1705312803, "Hello")
+
+	tests := []struct {
+		infer       bool
+		wantCounts  map[string]int
+		wantRevenue map[string]int64
+	}{
+		{false, nil, nil},
+		{true, map[string]int{"Han": 2, "Leia": 1}, map[string]int64{"Han": 1000, "Leia": 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("infer %v", tt.infer), func(t *testing.T) {
+			result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{InferCharacterFromDir: tt.infer})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stats := generateMailStats(result.Mails)
+			if !maps.Equal(stats.MailCountByCharacter, tt.wantCounts) || !maps.Equal(stats.RevenueByCharacter, tt.wantRevenue) {
+				t.Errorf("MailCountByCharacter, RevenueByCharacter = %v, %v, want %v, %v",
+					stats.MailCountByCharacter, stats.RevenueByCharacter, tt.wantCounts, tt.wantRevenue)
+			}
+		})
+	}
+}
+
+func TestParseMailFromDirectoriesSource(t *testing.T) {
+	current := t.TempDir()
+	archived := t.TempDir()
+	writeTestMail(t, current, "1.mail", "1", "Han Solo", "Hello", 1705312800, "Current")
+	writeTestMail(t, current, "2.mail", "2", "Han Solo", "Hello", 1705312801, "Current")
+	writeTestMail(t, archived, "3.mail", "3", "Han Solo", "Hello", 1705312802, "Archived")
+	// Mail IDs are deduplicated across the directories
+	writeTestMail(t, archived, "1.mail", "1", "Han Solo", "Hello", 1705312800, "Current")
+
+	result, err := parseMailFromDirectories(context.Background(), []string{current, archived}, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSources := map[string]string{"1": current, "2": current, "3": archived}
+	for _, mail := range result.Mails {
+		if mail.Source != wantSources[mail.MailID] {
+			t.Errorf("mail %s has Source %q, want %q", mail.MailID, mail.Source, wantSources[mail.MailID])
+		}
+	}
+	if len(result.Mails) != len(wantSources) {
+		t.Errorf("parsed %d mails, want %d", len(result.Mails), len(wantSources))
+	}
+
+	want := map[string]int{current: 2, archived: 1}
+	if got := generateMailStats(result.Mails).MailsBySource; !maps.Equal(got, want) {
+		t.Errorf("MailsBySource = %v, want %v", got, want)
+	}
+}
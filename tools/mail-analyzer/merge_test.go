@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// writeTestBatch writes the mails as a batch file to dir/name and returns
+// its path
+func writeTestBatch(t *testing.T, dir, name string, mails ...MailData) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	batch := &MailBatch{Mails: mails, Stats: generateMailStats(mails)}
+	if err := writeBatchToWriter(file, batch, jsonOptions{KeyCase: KeyCaseSnake}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMergeBatchFilesLoadOrder(t *testing.T) {
+	dir := t.TempDir()
+	var mails []MailData
+	for i, sale := range []struct {
+		day   int
+		item  string
+		buyer string
+	}{{1, "Rifle", "Han"}, {2, "Pistol", "Leia"}, {2, "Armor", "Luke"}, {3, "Food", "Chewbacca"}} {
+		mail := testSale(strconv.Itoa(i+1), date(2024, time.January, sale.day), sale.item, sale.buyer, 100)
+		mail.Body = "Vendor: Crafter has sold " + sale.item + " to " + sale.buyer + " for 100 credits."
+		mails = append(mails, mail)
+	}
+	rifle, pistol, armor, food := mails[0], mails[1], mails[2], mails[3]
+	// The same sale exported again under another mail ID
+	foodAgain := food
+	foodAgain.MailID = "5"
+
+	// Exports of overlapping time ranges share some mails
+	paths := []string{
+		writeTestBatch(t, dir, "first.json", rifle, pistol),
+		writeTestBatch(t, dir, "second.json", pistol, armor, food),
+		writeTestBatch(t, dir, "third.json", foodAgain, rifle),
+	}
+
+	tests := []struct {
+		name      string
+		byContent bool
+		wantIDs   []string
+	}{
+		{"by mail ID", false, []string{"1", "2", "3", "4", "5"}},
+		{"by content", true, []string{"1", "2", "3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []MailData
+			for _, order := range [][]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}} {
+				for _, workers := range []int{1, len(paths)} {
+					ordered := make([]string, len(order))
+					for i, j := range order {
+						ordered[i] = paths[j]
+					}
+
+					batches, err := readBatchFiles(context.Background(), ordered, workers)
+					if err != nil {
+						t.Fatal(err)
+					}
+					mails, _, _ := mergeMails(batches, tt.byContent)
+
+					if want == nil {
+						want = mails
+						if got := mailIDs(mails); !slices.Equal(got, tt.wantIDs) {
+							t.Fatalf("merged mails = %v, want %v", got, tt.wantIDs)
+						}
+						continue
+					}
+					if !reflect.DeepEqual(mails, want) {
+						t.Errorf("merging %v with %d workers = %v, want %v", order, workers, mailIDs(mails), mailIDs(want))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMergeMailsSameIDDifferentContent(t *testing.T) {
+	original := testSale("1", date(2024, time.January, 1), "Rifle", "Han", 100)
+	original.Body = "Vendor: Crafter has sold Rifle to Han for 100 credits."
+	// A later export of the same mail ID with an edited body
+	edited := original
+	edited.Body = "Vendor: Crafter has sold Rifle to Han for 120 credits."
+	// The same mail ID received a day later
+	later := edited
+	later.Timestamp = original.Timestamp.Add(24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		mails []MailData
+		want  MailData
+	}{
+		{"earlier timestamp wins", []MailData{later, original}, original},
+		{"lower content hash wins on equal timestamps", []MailData{edited, original}, original},
+	}
+	if contentHash(edited) < contentHash(original) {
+		tests[1].want = edited
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, order := range [][]int{{0, 1}, {1, 0}} {
+				batches := []MailBatch{
+					{Mails: []MailData{tt.mails[order[0]]}},
+					{Mails: []MailData{tt.mails[order[1]]}},
+				}
+				mails, duplicateIDs, _ := mergeMails(batches, false)
+				if len(mails) != 1 || duplicateIDs != 1 {
+					t.Fatalf("merging %v = %d mails with %d duplicate IDs, want 1 and 1", order, len(mails), duplicateIDs)
+				}
+				if mails[0].Body != tt.want.Body || !mails[0].Timestamp.Equal(tt.want.Timestamp) {
+					t.Errorf("merging %v kept %q at %v, want %q at %v", order, mails[0].Body, mails[0].Timestamp, tt.want.Body, tt.want.Timestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestAppendToExistingBatch(t *testing.T) {
+	firstRun := t.TempDir()
+	writeTestMail(t, firstRun, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	writeTestMail(t, firstRun, "2.mail", "2", "Leia Organa", "Hello", 1705399200, "Hello")
+	result, err := parseMailFromDirectory(context.Background(), firstRun, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := writeTestBatch(t, t.TempDir(), "mail_data.json", result.Mails...)
+
+	// The next export still holds mail 2
+	secondRun := t.TempDir()
+	writeTestMail(t, secondRun, "2.mail", "2", "Leia Organa", "Hello", 1705399200, "Hello")
+	writeTestMail(t, secondRun, "3.mail", "3", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705917600,
+		"Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+	result, err = parseMailFromDirectory(context.Background(), secondRun, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	existing, err := loadExistingBatch(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing == nil {
+		t.Fatal("existing batch not found")
+	}
+	merged := mergeBatches(*existing, MailBatch{Mails: result.Mails})
+
+	if got, want := mailIDs(merged.Mails), []string{"1", "2", "3"}; !slices.Equal(got, want) {
+		t.Errorf("merged mails = %v, want %v", got, want)
+	}
+	if merged.Stats.TotalMails != 3 || merged.Stats.TotalRevenue != 1500 {
+		t.Errorf("merged stats have %d mails and revenue %d, want 3 and 1500", merged.Stats.TotalMails, merged.Stats.TotalRevenue)
+	}
+
+	// The first run of --append has nothing to merge with
+	if batch, err := loadExistingBatch(filepath.Join(t.TempDir(), "missing.json")); batch != nil || err != nil {
+		t.Errorf("loadExistingBatch() of a missing file = %v, %v, want nil, nil", batch, err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentSchemaVersion is the schema version of batches written by this
+// version. Batches written before versioning was introduced have no
+// schema_version and are treated as version 0.
+const CurrentSchemaVersion = 2
+
+// migrationStep upgrades a raw batch object by one schema version
+type migrationStep func(batch map[string]json.RawMessage) error
+
+// migrations[v] migrates a batch from schema version v to v+1. Adding a
+// schema version only requires appending a step and bumping
+// CurrentSchemaVersion.
+var migrations = []migrationStep{
+	migrateV0ToV1,
+	migrateV1ToV2,
+}
+
+// schemaVersion reads the schema version of a raw batch object
+func schemaVersion(batch map[string]json.RawMessage) (int, error) {
+	raw, ok := batch["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil {
+		return 0, fmt.Errorf("invalid schema_version: %w", err)
+	}
+	return version, nil
+}
+
+// detectSchemaVersion returns the schema version of a JSON batch without
+// decoding the mails
+func detectSchemaVersion(data []byte) (int, error) {
+	var batch map[string]json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return 0, fmt.Errorf("failed to decode batch: %w", err)
+	}
+	return schemaVersion(batch)
+}
+
+// MigrateBatch decodes a JSON batch of any supported schema version,
+// applying the migration steps needed to bring it to CurrentSchemaVersion
+func MigrateBatch(data []byte) (*MailBatch, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode batch: %w", err)
+	}
+
+	version, err := schemaVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if version < 0 {
+		return nil, fmt.Errorf("batch has invalid schema version %d", version)
+	}
+	if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("batch has schema version %d, but only versions up to %d are supported", version, CurrentSchemaVersion)
+	}
+
+	if version < CurrentSchemaVersion {
+		for v := version; v < CurrentSchemaVersion; v++ {
+			if err := migrations[v](raw); err != nil {
+				return nil, fmt.Errorf("failed to migrate batch from schema version %d to %d: %w", v, v+1, err)
+			}
+		}
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("failed to encode migrated batch: %w", err)
+		}
+	}
+
+	var batch MailBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to decode batch: %w", err)
+	}
+	batch.SchemaVersion = CurrentSchemaVersion
+	return &batch, nil
+}
+
+// migrateMails applies migrate to every mail of a raw batch object
+func migrateMails(batch map[string]json.RawMessage, migrate func(mail map[string]json.RawMessage) error) error {
+	rawMails, ok := batch["mails"]
+	if !ok || string(rawMails) == "null" {
+		return nil
+	}
+
+	var mails []map[string]json.RawMessage
+	if err := json.Unmarshal(rawMails, &mails); err != nil {
+		return fmt.Errorf("invalid mails: %w", err)
+	}
+	for _, mail := range mails {
+		if err := migrate(mail); err != nil {
+			return err
+		}
+	}
+
+	encoded, err := json.Marshal(mails)
+	if err != nil {
+		return err
+	}
+	batch["mails"] = encoded
+	return nil
+}
+
+// migrateV0ToV1 renames the id field of unversioned mails to mail_id.
+// Mails already using mail_id are left unchanged.
+func migrateV0ToV1(batch map[string]json.RawMessage) error {
+	err := migrateMails(batch, func(mail map[string]json.RawMessage) error {
+		id, ok := mail["id"]
+		if !ok {
+			return nil
+		}
+		if _, ok := mail["mail_id"]; !ok {
+			mail["mail_id"] = id
+		}
+		delete(mail, "id")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch["schema_version"] = json.RawMessage("1")
+	return nil
+}
+
+// migrateV1ToV2 drops the vendor flag of sale details, which duplicated
+// sale_channel. Sales written before sale_channel existed get the sale_type
+// of their mail, or "vendor" if the flag was set.
+func migrateV1ToV2(batch map[string]json.RawMessage) error {
+	err := migrateMails(batch, func(mail map[string]json.RawMessage) error {
+		rawSale, ok := mail["sale"]
+		if !ok || string(rawSale) == "null" {
+			return nil
+		}
+
+		var sale map[string]json.RawMessage
+		if err := json.Unmarshal(rawSale, &sale); err != nil {
+			return fmt.Errorf("invalid sale of mail %s: %w", mail["mail_id"], err)
+		}
+		rawVendor, ok := sale["vendor"]
+		if !ok {
+			return nil
+		}
+		delete(sale, "vendor")
+
+		if _, ok := sale["sale_channel"]; !ok {
+			var vendor bool
+			if err := json.Unmarshal(rawVendor, &vendor); err != nil {
+				return fmt.Errorf("invalid vendor of mail %s: %w", mail["mail_id"], err)
+			}
+			switch saleType, ok := mail["sale_type"]; {
+			case vendor:
+				sale["sale_channel"] = json.RawMessage(`"` + SaleTypeVendor + `"`)
+			case ok:
+				sale["sale_channel"] = saleType
+			default:
+				sale["sale_channel"] = json.RawMessage(`"` + SaleTypeUnknown + `"`)
+			}
+		}
+
+		encoded, err := json.Marshal(sale)
+		if err != nil {
+			return err
+		}
+		mail["sale"] = encoded
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch["schema_version"] = json.RawMessage("2")
+	return nil
+}
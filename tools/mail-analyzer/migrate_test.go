@@ -0,0 +1,122 @@
+package main
+
+import "testing"
+
+func TestMigrateBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantVersion int
+		wantID      string
+		wantChannel string
+		wantErr     bool
+	}{
+		{
+			name:        "v0 renames id to mail_id",
+			input:       `{"mails": [{"id": "42", "sender": "Han Solo"}]}`,
+			wantVersion: 0,
+			wantID:      "42",
+		},
+		{
+			name:        "v0 keeps an existing mail_id",
+			input:       `{"mails": [{"id": "41", "mail_id": "42"}]}`,
+			wantVersion: 0,
+			wantID:      "42",
+		},
+		{
+			name:        "v1 vendor flag becomes sale channel",
+			input:       `{"schema_version": 1, "mails": [{"mail_id": "42", "sale": {"item_name": "Rifle", "vendor": true}}]}`,
+			wantVersion: 1,
+			wantID:      "42",
+			wantChannel: SaleTypeVendor,
+		},
+		{
+			name:        "v1 sale type becomes sale channel",
+			input:       `{"schema_version": 1, "mails": [{"mail_id": "42", "sale_type": "bazaar", "sale": {"vendor": false}}]}`,
+			wantVersion: 1,
+			wantID:      "42",
+			wantChannel: SaleTypeBazaar,
+		},
+		{
+			name:        "v1 without sale type",
+			input:       `{"schema_version": 1, "mails": [{"mail_id": "42", "sale": {"vendor": false}}]}`,
+			wantVersion: 1,
+			wantID:      "42",
+			wantChannel: SaleTypeUnknown,
+		},
+		{
+			name:        "v1 keeps an existing sale channel",
+			input:       `{"schema_version": 1, "mails": [{"mail_id": "42", "sale": {"vendor": true, "sale_channel": "bazaar"}}]}`,
+			wantVersion: 1,
+			wantID:      "42",
+			wantChannel: SaleTypeBazaar,
+		},
+		{
+			name:        "v0 migrates through v1 to v2",
+			input:       `{"mails": [{"id": "42", "sale": {"vendor": true}}]}`,
+			wantVersion: 0,
+			wantID:      "42",
+			wantChannel: SaleTypeVendor,
+		},
+		{
+			name:        "current version is unchanged",
+			input:       `{"schema_version": 2, "mails": [{"mail_id": "42", "sale": {"sale_channel": "vendor"}}]}`,
+			wantVersion: 2,
+			wantID:      "42",
+			wantChannel: SaleTypeVendor,
+		},
+		{
+			name:        "newer version",
+			input:       `{"schema_version": 3, "mails": []}`,
+			wantVersion: 3,
+			wantErr:     true,
+		},
+		{
+			name:        "negative version",
+			input:       `{"schema_version": -1, "mails": []}`,
+			wantVersion: -1,
+			wantErr:     true,
+		},
+		{
+			name:    "invalid JSON",
+			input:   `{"mails": [`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if version, err := detectSchemaVersion([]byte(tt.input)); err == nil && version != tt.wantVersion {
+				t.Errorf("detectSchemaVersion() = %d, want %d", version, tt.wantVersion)
+			}
+
+			batch, err := MigrateBatch([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("MigrateBatch() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MigrateBatch() error = %v", err)
+			}
+
+			if batch.SchemaVersion != CurrentSchemaVersion {
+				t.Errorf("SchemaVersion = %d, want %d", batch.SchemaVersion, CurrentSchemaVersion)
+			}
+			if len(batch.Mails) != 1 {
+				t.Fatalf("got %d mails, want 1", len(batch.Mails))
+			}
+			mail := batch.Mails[0]
+			if mail.MailID != tt.wantID {
+				t.Errorf("MailID = %q, want %q", mail.MailID, tt.wantID)
+			}
+			if tt.wantChannel == "" {
+				return
+			}
+			if mail.Sale == nil || mail.Sale.SaleChannel != tt.wantChannel {
+				t.Errorf("Sale = %+v, want sale channel %q", mail.Sale, tt.wantChannel)
+			}
+		})
+	}
+}
@@ -0,0 +1,676 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mailColumn describes how a MailData field is flattened into a single
+// text value for tabular formats such as CSV and XML
+type mailColumn struct {
+	Name string
+	Type cellType
+	Get  func(m *MailData) string
+	Set  func(m *MailData, value string) error
+}
+
+// cellType is the type of a column in spreadsheets such as XLSX, where
+// numbers and times are not stored as text
+type cellType int
+
+const (
+	cellText cellType = iota
+	cellNumber
+	cellTime
+)
+
+// cell returns the value of the column for a spreadsheet, see
+// spreadsheetCell
+func (c mailColumn) cell(m *MailData) any {
+	return spreadsheetCell(c.Get(m), c.Type)
+}
+
+// spreadsheetCell converts the text of a column of the given type to a
+// float64 for numbers and a time.Time for RFC 3339 times. Text that does
+// not parse is kept as it is.
+func spreadsheetCell(text string, typ cellType) any {
+	switch typ {
+	case cellNumber:
+		if number, err := strconv.ParseFloat(text, 64); err == nil {
+			return number
+		}
+	case cellTime:
+		if t, err := time.Parse(time.RFC3339, text); err == nil {
+			return t
+		}
+	}
+	return text
+}
+
+// mailColumns lists the flattened MailData fields in output order
+var mailColumns = []mailColumn{
+	stringColumn("mail_id", func(m *MailData) *string { return &m.MailID }),
+	stringColumn("mail_id_normalized", func(m *MailData) *string { return &m.MailIDNormalized }),
+	stringColumn("content_hash", func(m *MailData) *string { return &m.ContentHash }),
+	stringColumn("galaxy", func(m *MailData) *string { return &m.Galaxy }),
+	stringColumn("source", func(m *MailData) *string { return &m.Source }),
+	{
+		Name: "recovered",
+		Get:  func(m *MailData) string { return strconv.FormatBool(m.Recovered) },
+		Set: func(m *MailData, value string) (err error) {
+			m.Recovered, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+	stringColumn("character", func(m *MailData) *string { return &m.Character }),
+	stringColumn("sender", func(m *MailData) *string { return &m.Sender }),
+	stringColumn("subject", func(m *MailData) *string { return &m.Subject }),
+	stringColumn("normalized_subject", func(m *MailData) *string { return &m.NormalizedSubject }),
+	{
+		Name: "timestamp",
+		Type: cellTime,
+		Get:  func(m *MailData) string { return m.Timestamp.Format(time.RFC3339) },
+		Set: func(m *MailData, value string) (err error) {
+			m.Timestamp, err = time.Parse(time.RFC3339, value)
+			return err
+		},
+	},
+	stringColumn("body", func(m *MailData) *string { return &m.Body }),
+	stringColumn("location", func(m *MailData) *string { return &m.Location }),
+	stringColumn("city", func(m *MailData) *string { return &m.City }),
+	stringColumn("planet", func(m *MailData) *string { return &m.Planet }),
+	stringColumn("mail_category", func(m *MailData) *string { return &m.MailCategory }),
+	stringColumn("mail_type", func(m *MailData) *string { return (*string)(&m.MailType) }),
+	stringColumn("broadcast_name", func(m *MailData) *string { return &m.BroadcastName }),
+	stringColumn("sale_type", func(m *MailData) *string { return &m.SaleType }),
+	{
+		Name: "tags",
+		Get:  func(m *MailData) string { return strings.Join(m.Tags, ";") },
+		Set: func(m *MailData, value string) error {
+			if value != "" {
+				m.Tags = strings.Split(value, ";")
+			}
+			return nil
+		},
+	},
+	stringColumn("sender_domain", func(m *MailData) *string { return &m.SenderDomain }),
+	stringColumn("sender_subsystem", func(m *MailData) *string { return &m.SenderSubsystem }),
+	stringColumn("sender_label", func(m *MailData) *string { return &m.SenderLabel }),
+	stringColumn("item_name", func(m *MailData) *string { return &m.ItemName }),
+	stringColumn("canonical_item_name", func(m *MailData) *string { return &m.CanonicalItemName }),
+	stringColumn("item_key", func(m *MailData) *string { return &m.ItemKey }),
+	stringColumn("item_category", func(m *MailData) *string { return &m.ItemCategory }),
+	stringColumn("serial_number", func(m *MailData) *string { return &m.SerialNumber }),
+	stringColumn("buyer", func(m *MailData) *string { return &m.Buyer }),
+	stringColumn("vendor_name", func(m *MailData) *string { return &m.VendorName }),
+	{
+		Name: "price",
+		Type: cellNumber,
+		Get:  func(m *MailData) string { return strconv.FormatInt(m.Price, 10) },
+		Set: func(m *MailData, value string) (err error) {
+			m.Price, err = strconv.ParseInt(value, 10, 64)
+			return err
+		},
+	},
+	stringColumn("price_type", func(m *MailData) *string { return &m.PriceType }),
+	{
+		Name: "unit_count",
+		Type: cellNumber,
+		Get:  func(m *MailData) string { return strconv.FormatInt(m.UnitCount, 10) },
+		Set: func(m *MailData, value string) (err error) {
+			m.UnitCount, err = strconv.ParseInt(value, 10, 64)
+			return err
+		},
+	},
+	floatColumn("price_per_unit", func(m *MailData) *float64 { return &m.PricePerUnit }),
+	{
+		Name: "has_coordinates",
+		Get:  func(m *MailData) string { return strconv.FormatBool(m.HasCoordinates) },
+		Set: func(m *MailData, value string) (err error) {
+			m.HasCoordinates, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+	floatColumn("location_x", func(m *MailData) *float64 { return &m.LocationX }),
+	floatColumn("location_y", func(m *MailData) *float64 { return &m.LocationY }),
+	floatColumn("location_z", func(m *MailData) *float64 { return &m.LocationZ }),
+}
+
+// stringColumn returns a column for a plain string field
+func stringColumn(name string, field func(m *MailData) *string) mailColumn {
+	return mailColumn{
+		Name: name,
+		Get:  func(m *MailData) string { return *field(m) },
+		Set: func(m *MailData, value string) error {
+			*field(m) = value
+			return nil
+		},
+	}
+}
+
+// floatColumn returns a column for a float field
+func floatColumn(name string, field func(m *MailData) *float64) mailColumn {
+	return mailColumn{
+		Name: name,
+		Type: cellNumber,
+		Get:  func(m *MailData) string { return strconv.FormatFloat(*field(m), 'f', -1, 64) },
+		Set: func(m *MailData, value string) (err error) {
+			*field(m), err = strconv.ParseFloat(value, 64)
+			return err
+		},
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicFunc(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFunc is writeFileAtomic for content produced
+// incrementally by write, e.g. while it is being parsed. The file is only
+// renamed into place if write succeeds.
+func writeFileAtomicFunc(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	buffered := bufio.NewWriter(tmp)
+	if err := write(buffered); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// writeBatch writes a batch in the given format: json, csv, ndjson, xml,
+// xlsx, parquet or influx
+func writeBatch(w io.Writer, batch MailBatch, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, batch)
+	case "csv":
+		return writeCSV(w, batch.Mails)
+	case "ndjson":
+		return writeNDJSON(w, batch.Mails)
+	case "xml":
+		return writeXML(w, batch)
+	case "xlsx":
+		return writeXLSX(w, batch)
+	case "parquet":
+		return writeParquet(w, batch.Mails)
+	case "proto":
+		return writeProto(w, batch)
+	case "sqlite":
+		return writeSQLite(w, batch)
+	case "influx":
+		return writeInflux(w, batch.Mails)
+	default:
+		return fmt.Errorf("unsupported output format %q, expected json, csv, ndjson, xml, xlsx, parquet, proto, sqlite or influx", format)
+	}
+}
+
+// Supported --body values
+const (
+	BodyModeFull    = "full"
+	BodyModeSummary = "summary"
+	BodyModeNone    = "none"
+)
+
+// bodySummaryLength is the maximum length in characters of summarized bodies
+const bodySummaryLength = 80
+
+// applyBodyMode drops or truncates the bodies of mails once their fields
+// have been extracted, to keep the output of large archives small
+func applyBodyMode(mails []MailData, mode string) {
+	if mode == BodyModeFull {
+		return
+	}
+	for i := range mails {
+		if mode == BodyModeNone {
+			mails[i].Body = ""
+		} else {
+			mails[i].Body = summarizeBody(mails[i].Body)
+		}
+	}
+}
+
+// summarizeBody returns the first non-empty line of body, truncated to
+// bodySummaryLength characters
+func summarizeBody(body string) string {
+	var line string
+	for l := range strings.Lines(body) {
+		if line = strings.TrimSpace(l); line != "" {
+			break
+		}
+	}
+
+	runes := []rune(line)
+	if len(runes) > bodySummaryLength {
+		return string(runes[:bodySummaryLength-1]) + "…"
+	}
+	return line
+}
+
+// Supported --key-case values for JSON output
+const (
+	KeyCaseSnake = "snake"
+	KeyCaseCamel = "camel"
+)
+
+// jsonOptions controls how writeJSONWithOptions encodes a batch
+type jsonOptions struct {
+	// KeyCase is KeyCaseSnake or KeyCaseCamel
+	KeyCase string
+
+	// SelfDescribing prepends a "_schema" key with batchSchema
+	SelfDescribing bool
+}
+
+// writeJSON writes a batch as indented JSON
+func writeJSON(w io.Writer, batch MailBatch) error {
+	return writeJSONWithOptions(w, batch, jsonOptions{KeyCase: KeyCaseSnake})
+}
+
+// writeJSONWithOptions writes a batch as indented JSON with either the
+// snake_case keys of the struct tags or their camelCase equivalents,
+// optionally preceded by a description of the keys
+func writeJSONWithOptions(w io.Writer, batch MailBatch, opts jsonOptions) error {
+	convertKey := func(key string) string { return key }
+	switch opts.KeyCase {
+	case KeyCaseSnake:
+	case KeyCaseCamel:
+		convertKey = snakeToCamel
+	default:
+		return fmt.Errorf("unsupported key case %q, expected snake or camel", opts.KeyCase)
+	}
+
+	batch.SchemaVersion = CurrentSchemaVersion
+
+	var value any = batch
+	if opts.KeyCase == KeyCaseCamel || opts.SelfDescribing {
+		object := jsonValue(reflect.ValueOf(batch), convertKey).(jsonObject)
+		if opts.SelfDescribing {
+			schema := make(map[string]string, len(batchSchema))
+			for key, description := range batchSchema {
+				schema[convertKey(key)] = description
+			}
+			object = append(jsonObject{{Key: "_schema", Value: schema}}, object...)
+		}
+		value = object
+	}
+
+	jsonData, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	_, err = w.Write(jsonData)
+	return err
+}
+
+// jsonObject is a JSON object that keeps its keys in order
+type jsonObject []jsonField
+
+// jsonField is a single key of a jsonObject
+type jsonField struct {
+	Key   string
+	Value any
+}
+
+// MarshalJSON implements json.Marshaler
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonValue mirrors how encoding/json encodes v, except that struct field
+// keys are passed through convertKey. Map keys are data, such as sender or
+// planet names, and are kept as they are.
+func jsonValue(v reflect.Value, convertKey func(string) string) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return jsonValue(v.Elem(), convertKey)
+	}
+
+	// Types with their own encoding, such as time.Time, are kept as they are
+	if marshaler, ok := v.Interface().(json.Marshaler); ok {
+		return marshaler
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		var object jsonObject
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			value := v.Field(i)
+			if strings.Contains(options, "omitempty") && isEmptyJSONValue(value) {
+				continue
+			}
+
+			object = append(object, jsonField{Key: convertKey(name), Value: jsonValue(value, convertKey)})
+		}
+		return object
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		object := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			object[iter.Key().String()] = jsonValue(iter.Value(), convertKey)
+		}
+		return object
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = jsonValue(v.Index(i), convertKey)
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
+
+// isEmptyJSONValue reports whether encoding/json treats v as empty for omitempty
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// writeCSV writes one row per mail, preceded by a header row
+func writeCSV(w io.Writer, mails []MailData) error {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(mailColumns))
+	for i, column := range mailColumns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	row := make([]string, len(mailColumns))
+	for i := range mails {
+		for j, column := range mailColumns {
+			row[j] = column.Get(&mails[i])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// saleColumn is a column of the sales CSV, see writeSalesCSV
+type saleColumn struct {
+	Name string
+	Type cellType
+	Get  func(m *MailData) string
+}
+
+// cell returns the value of the column for a spreadsheet, see
+// spreadsheetCell
+func (c saleColumn) cell(m *MailData) any {
+	return spreadsheetCell(c.Get(m), c.Type)
+}
+
+// saleColumns lists the sales CSV columns in output order: the sale data of
+// a mail, identified by its mail ID and time, and where the sale took place
+var saleColumns = []saleColumn{
+	{"mail_id", cellText, func(m *MailData) string { return m.MailID }},
+	{"timestamp", cellTime, func(m *MailData) string { return m.Timestamp.Format(time.RFC3339) }},
+	{"item_name", cellText, func(m *MailData) string { return m.Sale.ItemName }},
+	{"buyer", cellText, func(m *MailData) string { return m.Sale.Buyer }},
+	{"price", cellNumber, func(m *MailData) string { return strconv.FormatInt(m.Sale.Price, 10) }},
+	{"sale_channel", cellText, func(m *MailData) string { return m.Sale.SaleChannel }},
+	{"vendor_name", cellText, func(m *MailData) string { return m.Sale.VendorName }},
+	{"category", cellText, func(m *MailData) string { return m.Sale.Category }},
+	{"serial_number", cellText, func(m *MailData) string { return m.Sale.SerialNumber }},
+	{"unit_count", cellNumber, func(m *MailData) string { return strconv.FormatInt(m.Sale.UnitCount, 10) }},
+	{"price_per_unit", cellNumber, func(m *MailData) string { return strconv.FormatFloat(m.Sale.PricePerUnit, 'f', -1, 64) }},
+	{"city", cellText, func(m *MailData) string { return m.City }},
+	{"planet", cellText, func(m *MailData) string { return m.Planet }},
+}
+
+// writeSalesCSV writes one row per sale notification, preceded by a header
+// row. Other mails are left out.
+func writeSalesCSV(w io.Writer, mails []MailData) error {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(saleColumns))
+	for i, column := range saleColumns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	row := make([]string, len(saleColumns))
+	for i := range mails {
+		if mails[i].Sale == nil {
+			continue
+		}
+		for j, column := range saleColumns {
+			row[j] = column.Get(&mails[i])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeNDJSON writes one JSON encoded mail per line
+func writeNDJSON(w io.Writer, mails []MailData) error {
+	encoder := json.NewEncoder(w)
+	for _, mail := range mails {
+		if err := encoder.Encode(mail); err != nil {
+			return fmt.Errorf("failed to encode mail %s: %w", mail.MailID, err)
+		}
+	}
+	return nil
+}
+
+// writeInflux writes one InfluxDB line protocol line per mail
+func writeInflux(w io.Writer, mails []MailData) error {
+	for _, mail := range mails {
+		if _, err := io.WriteString(w, ToInfluxLine(mail)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushInflux sends line protocol data to the write API of an InfluxDB 2.x
+// server at baseURL
+func pushInflux(ctx context.Context, baseURL, org, bucket, token string, lines []byte) error {
+	endpoint, err := url.JoinPath(baseURL, "/api/v2/write")
+	if err != nil {
+		return fmt.Errorf("invalid InfluxDB URL: %w", err)
+	}
+	query := url.Values{"org": {org}, "bucket": {bucket}, "precision": {"ns"}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"?"+query.Encode(), bytes.NewReader(lines))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to InfluxDB: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("InfluxDB write failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// writeXML writes the mails and summary statistics of a batch as XML
+func writeXML(w io.Writer, batch MailBatch) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	root := xml.StartElement{Name: xml.Name{Local: "mail_batch"}}
+	mailsElement := xml.StartElement{Name: xml.Name{Local: "mails"}}
+	mailElement := xml.StartElement{Name: xml.Name{Local: "mail"}}
+
+	if err := encoder.EncodeToken(root); err != nil {
+		return err
+	}
+	if err := encoder.EncodeToken(mailsElement); err != nil {
+		return err
+	}
+	for i := range batch.Mails {
+		if err := encoder.EncodeToken(mailElement); err != nil {
+			return err
+		}
+		for _, column := range mailColumns {
+			value := column.Get(&batch.Mails[i])
+			if err := encoder.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: column.Name}}); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EncodeToken(mailElement.End()); err != nil {
+			return err
+		}
+	}
+	if err := encoder.EncodeToken(mailsElement.End()); err != nil {
+		return err
+	}
+
+	stats := struct {
+		TotalMails        int       `xml:"total_mails"`
+		SaleNotifications int       `xml:"sale_notifications"`
+		TotalRevenue      int64     `xml:"total_revenue"`
+		StartDate         time.Time `xml:"start_date"`
+		EndDate           time.Time `xml:"end_date"`
+	}{
+		TotalMails:        batch.Stats.TotalMails,
+		SaleNotifications: batch.Stats.SaleNotifications,
+		TotalRevenue:      batch.Stats.TotalRevenue,
+		StartDate:         batch.Stats.DateRange.StartDate,
+		EndDate:           batch.Stats.DateRange.EndDate,
+	}
+	if err := encoder.EncodeElement(stats, xml.StartElement{Name: xml.Name{Local: "stats"}}); err != nil {
+		return err
+	}
+
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
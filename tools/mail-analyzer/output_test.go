@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomicFunc(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   func(w io.Writer) error
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "replaces the file",
+			write: func(w io.Writer) error {
+				_, err := io.WriteString(w, "new batch")
+				return err
+			},
+			want: "new batch",
+		},
+		{
+			name: "failed write keeps the old file",
+			write: func(w io.Writer) error {
+				io.WriteString(w, "half a ba")
+				return errors.New("parse failed")
+			},
+			want:    "old batch",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "mail_data.json")
+			if err := os.WriteFile(path, []byte("old batch"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := writeFileAtomicFunc(path, 0644, tt.write)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeFileAtomicFunc() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file contains %q, want %q", data, tt.want)
+			}
+			if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) > 0 {
+				t.Errorf("temporary files left behind: %v", tmp)
+			}
+		})
+	}
+}
+
+func TestPushInflux(t *testing.T) {
+	lines := []byte("swg_mail mail_count=1i 1705312800000000000\n")
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{"accepted", http.StatusNoContent, ""},
+		{"rejected", http.StatusUnauthorized, "401 Unauthorized: invalid token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/api/v2/write" {
+					t.Errorf("request %s %s, want POST /api/v2/write", r.Method, r.URL.Path)
+				}
+				query := r.URL.Query()
+				if query.Get("org") != "guild" || query.Get("bucket") != "mails" || query.Get("precision") != "ns" {
+					t.Errorf("query = %v", query)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Token secret" {
+					t.Errorf("Authorization = %q, want %q", auth, "Token secret")
+				}
+				if body, _ := io.ReadAll(r.Body); string(body) != string(lines) {
+					t.Errorf("body = %q, want %q", body, lines)
+				}
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusNoContent {
+					io.WriteString(w, "invalid token\n")
+				}
+			}))
+			defer server.Close()
+
+			err := pushInflux(context.Background(), server.URL, "guild", "mails", "secret", lines)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("pushInflux() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteJSONWithOptionsKeyCase(t *testing.T) {
+	mail := MailData{
+		MailID:       "1",
+		Timestamp:    time.Unix(1705312800, 0).UTC(),
+		MailCategory: CategorySale,
+		Sale:         &SaleData{ItemName: "Rifle", Buyer: "Han", Price: 1000, SaleChannel: "vendor"},
+	}
+	batch := MailBatch{Mails: []MailData{mail}, Stats: generateMailStats([]MailData{mail})}
+
+	tests := []struct {
+		keyCase   string
+		batchKeys []string
+		mailKeys  []string
+		saleKeys  []string
+	}{
+		{KeyCaseSnake, []string{"schema_version", "mails"}, []string{"mail_id", "mail_category"}, []string{"item_name", "sale_channel"}},
+		{KeyCaseCamel, []string{"schemaVersion", "mails"}, []string{"mailId", "mailCategory"}, []string{"itemName", "saleChannel"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyCase, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeJSONWithOptions(&buf, batch, jsonOptions{KeyCase: tt.keyCase}); err != nil {
+				t.Fatal(err)
+			}
+			var got struct {
+				Batch map[string]json.RawMessage
+				Mail  map[string]json.RawMessage
+				Sale  map[string]json.RawMessage
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got.Batch); err != nil {
+				t.Fatal(err)
+			}
+			var mails []map[string]json.RawMessage
+			if err := json.Unmarshal(got.Batch[tt.batchKeys[1]], &mails); err != nil || len(mails) != 1 {
+				t.Fatalf("mails = %s, error %v", got.Batch[tt.batchKeys[1]], err)
+			}
+			got.Mail = mails[0]
+			if err := json.Unmarshal(got.Mail["sale"], &got.Sale); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, keys := range []struct {
+				object map[string]json.RawMessage
+				want   []string
+			}{{got.Batch, tt.batchKeys}, {got.Mail, tt.mailKeys}, {got.Sale, tt.saleKeys}} {
+				for _, key := range keys.want {
+					if _, ok := keys.object[key]; !ok {
+						t.Errorf("key %q missing, got %v", key, slices.Sorted(maps.Keys(keys.object)))
+					}
+				}
+				for key := range keys.object {
+					if tt.keyCase == KeyCaseCamel && strings.Contains(key, "_") {
+						t.Errorf("key %q is not camel case", key)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteJSONWithOptionsSelfDescribing(t *testing.T) {
+	batch := parseTestBatch(t)
+
+	for _, selfDescribing := range []bool{false, true} {
+		t.Run("self-describing "+strconv.FormatBool(selfDescribing), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeJSONWithOptions(&buf, batch, jsonOptions{KeyCase: KeyCaseSnake, SelfDescribing: selfDescribing}); err != nil {
+				t.Fatal(err)
+			}
+			var got struct {
+				Schema map[string]string `json:"_schema"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !selfDescribing {
+				if got.Schema != nil {
+					t.Errorf("_schema = %v, want none", got.Schema)
+				}
+				return
+			}
+			if !maps.Equal(got.Schema, batchSchema) {
+				t.Errorf("_schema has %d keys, want the %d of batchSchema", len(got.Schema), len(batchSchema))
+			}
+		})
+	}
+}
+
+func TestBatchSchemaCoversFields(t *testing.T) {
+	for prefix, typ := range map[string]reflect.Type{
+		"mails": reflect.TypeFor[MailData](),
+		"stats": reflect.TypeFor[MailStats](),
+	} {
+		for i := range typ.NumField() {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if _, ok := batchSchema[prefix+"."+name]; !ok {
+				t.Errorf("batchSchema has no description of %s.%s", prefix, name)
+			}
+		}
+	}
+}
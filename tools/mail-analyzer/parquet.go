@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize is the number of rows per row group of a Parquet
+// file; the values of one row group are held in memory
+const parquetRowGroupSize = 100000
+
+// parquetSale is a row of the Parquet output: the sales CSV columns with
+// their natural types, so analytics tools need no conversion
+type parquetSale struct {
+	MailID       string  `parquet:"mail_id"`
+	Timestamp    int64   `parquet:"timestamp,timestamp(millisecond)"`
+	ItemName     string  `parquet:"item_name"`
+	Buyer        string  `parquet:"buyer"`
+	Price        int64   `parquet:"price"`
+	SaleChannel  string  `parquet:"sale_channel"`
+	VendorName   string  `parquet:"vendor_name"`
+	Category     string  `parquet:"category"`
+	SerialNumber string  `parquet:"serial_number"`
+	UnitCount    int64   `parquet:"unit_count"`
+	PricePerUnit float64 `parquet:"price_per_unit"`
+	City         string  `parquet:"city"`
+	Planet       string  `parquet:"planet"`
+	Character    string  `parquet:"character"`
+	Galaxy       string  `parquet:"galaxy"`
+}
+
+// newParquetSale returns the row of the sale notification m
+func newParquetSale(m *MailData) parquetSale {
+	return parquetSale{
+		MailID:       m.MailID,
+		Timestamp:    m.Timestamp.UnixMilli(),
+		ItemName:     m.Sale.ItemName,
+		Buyer:        m.Sale.Buyer,
+		Price:        m.Sale.Price,
+		SaleChannel:  m.Sale.SaleChannel,
+		VendorName:   m.Sale.VendorName,
+		Category:     m.Sale.Category,
+		SerialNumber: m.Sale.SerialNumber,
+		UnitCount:    m.Sale.UnitCount,
+		PricePerUnit: m.Sale.PricePerUnit,
+		City:         m.City,
+		Planet:       m.Planet,
+		Character:    m.Character,
+		Galaxy:       m.Galaxy,
+	}
+}
+
+// writeParquet writes the sale notifications of mails as a Parquet file
+// with one row per sale, see parquetSale. All columns are required and
+// written without compression, which every Parquet reader supports.
+func writeParquet(w io.Writer, mails []MailData) error {
+	writer := parquet.NewGenericWriter[parquetSale](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))
+
+	rows := make([]parquetSale, 0, 1)
+	for i := range mails {
+		if mails[i].Sale == nil {
+			continue
+		}
+		rows = append(rows[:0], newParquetSale(&mails[i]))
+		if _, err := writer.Write(rows); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
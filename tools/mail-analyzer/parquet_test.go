@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestWriteParquet(t *testing.T) {
+	batch := parseTestBatch(t)
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, batch.Mails); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := parquet.NewGenericReader[parquetSale](bytes.NewReader(buf.Bytes()))
+	defer reader.Close()
+
+	columns := []struct {
+		name string
+		kind parquet.Kind
+	}{
+		{"timestamp", parquet.Int64},
+		{"price", parquet.Int64},
+		{"item_name", parquet.ByteArray},
+		{"buyer", parquet.ByteArray},
+		{"planet", parquet.ByteArray},
+	}
+	for _, tt := range columns {
+		column, ok := reader.Schema().Lookup(tt.name)
+		if !ok {
+			t.Errorf("column %s is missing", tt.name)
+			continue
+		}
+		if kind := column.Node.Type().Kind(); kind != tt.kind {
+			t.Errorf("column %s has kind %v, want %v", tt.name, kind, tt.kind)
+		}
+	}
+	if column, ok := reader.Schema().Lookup("timestamp"); ok {
+		if logical := column.Node.Type().LogicalType(); logical == nil || logical.Timestamp == nil {
+			t.Errorf("timestamp column has logical type %v, want a timestamp", logical)
+		}
+	}
+
+	rows := make([]parquetSale, reader.NumRows())
+	if n, err := reader.Read(rows); err != nil && err != io.EOF {
+		t.Fatal(err)
+	} else if n != len(rows) {
+		t.Fatalf("read %d rows, want %d", n, len(rows))
+	}
+
+	want := []struct {
+		timestamp time.Time
+		item      string
+		buyer     string
+		price     int64
+		planet    string
+	}{
+		{time.Unix(1705312800, 0), "Rifle", "Han", 1000, "Tatooine"},
+		{time.Unix(1705917600, 0), "Pistol", "Leia", 500, ""},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		row := rows[i]
+		if !time.UnixMilli(row.Timestamp).Equal(w.timestamp) || row.ItemName != w.item || row.Buyer != w.buyer ||
+			row.Price != w.price || row.Planet != w.planet {
+			t.Errorf("row %d = %v %s %s %d %q, want %v %s %s %d %q", i, time.UnixMilli(row.Timestamp).UTC(),
+				row.ItemName, row.Buyer, row.Price, row.Planet, w.timestamp.UTC(), w.item, w.buyer, w.price, w.planet)
+		}
+	}
+}
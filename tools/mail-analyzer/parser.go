@@ -2,88 +2,878 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
+const (
+	// defaultScannerBufferSize matches the default bufio.Scanner token limit
+	defaultScannerBufferSize = bufio.MaxScanTokenSize
+	// maxScannerBufferSize caps the line length accepted via --scanner-buffer-size
+	maxScannerBufferSize = 16 * 1024 * 1024
+	// defaultMaxFileSize is the largest mail file parsed unless
+	// --max-file-size says otherwise. It matches maxScannerBufferSize, so
+	// that a single line of the longest accepted length still fits.
+	defaultMaxFileSize = maxScannerBufferSize
+)
+
+// errFileTooLarge is the error of mail files exceeding --max-file-size
+var errFileTooLarge = errors.New("file too large")
+
+// Price types assigned by parsePriceType
+const (
+	PriceTypeBid     = "bid"
+	PriceTypeBuyNow  = "buy_now"
+	PriceTypeUnknown = "unknown"
+)
+
+// coordinateNumber matches a single coordinate component, including
+// negative values and scientific notation
+const coordinateNumber = `[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`
+
+// Static body patterns, compiled once at startup
+var (
+	// Expected format: "The sale took place at LocationName, on PlanetName."
+	locationPattern = regexp.MustCompile(`The sale took place at (.*?), on (.*?)\.`)
+
+	// Expected formats:
+	// "Your auction of [SEA] ItemName has been sold to BuyerName for 30000 credits"
+	// "Vendor: VendorName has sold [SEA] ItemName to BuyerName for 30000 credits."
+	itemNamePattern = regexp.MustCompile(`(?:Your auction of|Vendor: .*? has sold) (?:\[.*?\] )?(.*?)(?: has been sold)? to .*? for \d+ credits`)
+	buyerPattern    = regexp.MustCompile(`(?:has been sold|has sold .*?) to (.*?) for \d+ credits`)
+	pricePattern    = regexp.MustCompile(`(?:has been sold|has sold .*?) to .*? for (\d+) credits`)
+
+	// Expected formats:
+	// "You have won the auction of [SEA] ItemName from SellerName for 30000 credits."
+	// "You have purchased [SEA] ItemName from SellerName for 30000 credits."
+	purchasePattern = regexp.MustCompile(`You have (?:won the auction of|purchased) (?:\[.*?\] )?(.*?) from (.*?) for (\d+) credits`)
+
+	// Expected formats:
+	// "You won the auction of [SEA] ItemName with a bid of 30000 credits."
+	// "You have been outbid on [SEA] ItemName. The new high bid is 30000 credits."
+	auctionWonPattern    = regexp.MustCompile(`You (?:have )?won the auction of (?:\[.*?\] )?(.*?) with a bid of (\d+) credits`)
+	auctionOutbidPattern = regexp.MustCompile(`You have been outbid on (?:\[.*?\] )?(.*?)\. The new high bid is (\d+) credits`)
+
+	// Expected format:
+	// "Your auction of [SEA] ItemName has expired. The item can be retrieved at LocationName, on PlanetName."
+	expiredPattern         = regexp.MustCompile(`Your auction of (?:\[.*?\] )?(.*?) has expired`)
+	expiredLocationPattern = regexp.MustCompile(`can be retrieved at (.*?), on (.*?)\.`)
+
+	// Expected formats:
+	// "Mission Complete" / "You have received 2,500 credits for completing your mission."
+	// "GCW Reward" / "You have been awarded 5000 credits for your service to the Empire."
+	incomeAmountPattern = regexp.MustCompile(`(?i)(?:received|awarded|been paid|reward of|payout of) ([\d,]+) credits`)
+	gcwPattern          = regexp.MustCompile(`(?i)\b(?:gcw|galactic civil war|empire|imperial|rebel(?:s|lion)?|faction(?:al)?)\b`)
+
+	// Expected formats:
+	// "Your factory FactoryName has completed its manufacturing run of 500 units of ItemName."
+	// "Factory FactoryName has finished producing 1,000 ItemName."
+	factoryRunPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)factory (.+?) has (?:completed|finished) (?:its|a|the) (?:manufacturing|production) run of ([\d,]+) (?:units of )?(?:\[.*?\] )?(.+?)\.`),
+		regexp.MustCompile(`(?i)factory (.+?) has finished (?:producing|manufacturing) ([\d,]+) (?:units of )?(?:\[.*?\] )?(.+?)\.`),
+	}
+
+	// Expected format: "(serial: xyz123)" in the item name or body
+	serialNumberPattern = regexp.MustCompile(`(?i)\(serial:\s*([^)\s]+)\s*\)`)
+
+	// Stack counts in item names, e.g. "(100000) Polysteel Copper",
+	// "Copper Ore (x20)", "Copper Ore x20" or "20x Copper Ore"
+	unitCountPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^\((\d+)\)\s+`),
+		regexp.MustCompile(`(?i)\s*\(x(\d+)\)\s*$`),
+		regexp.MustCompile(`(?i)\s+x(\d+)\s*$`),
+		regexp.MustCompile(`(?i)^(\d+)\s*x\s+`),
+	}
+
+	// Auctions mention "won the bid", instant listings "purchased at listed price"
+	bidPricePattern    = regexp.MustCompile(`(?i)won the bid`)
+	buyNowPricePattern = regexp.MustCompile(`(?i)purchased at (?:the )?listed price`)
+
+	// Server script prefixes such as "**IMPORTANT**" or "[AUTO]" and trailing punctuation
+	subjectPrefixPattern = regexp.MustCompile(`^(?:\s*(?:\*\*[^*]*\*\*|\[[^\]]*\]))+\s*`)
+	subjectSuffixPattern = regexp.MustCompile(`[\s.!?,:;~*-]+$`)
+
+	// Expected format: "at coordinates 1234.56 -78.9 5678.0 on PlanetName."
+	coordinatesPattern      = regexp.MustCompile(`coordinates\s+(` + coordinateNumber + `)[,\s]+(` + coordinateNumber + `)(?:[,\s]+(` + coordinateNumber + `))?`)
+	coordinatePlanetPattern = regexp.MustCompile(`coordinates[-+\d.eE,\s]+on ([A-Z][\w' ]*?)\s*(?:[.,;!\n]|$)`)
+
+	// Expected format: "tatooine 3500 -4800 12 Krayt Graveyard", a planet
+	// followed by x, z and y and an optional waypoint name
+	waypointPattern = regexp.MustCompile(`(?im)\b(` + planetNames + `)\s+(` +
+		coordinateNumber + `)\s+(` + coordinateNumber + `)\s+(` + coordinateNumber + `)(?:[ \t]+([^\n]*?))?[ \t]*$`)
+)
+
+// regexCache holds compiled user-supplied patterns keyed by pattern string
+var regexCache sync.Map
+
+// getCompiledRegex compiles a user-supplied pattern, reusing earlier compilations
+func getCompiledRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := regexCache.LoadOrStore(pattern, re)
+	return actual.(*regexp.Regexp), nil
+}
+
 // parseMailFile parses a single mail file and extracts raw mail data
-func parseMailFile(filename string) (*MailData, error) {
+func parseMailFile(filename string, opts ParseOptions) (*MailData, error) {
+	maxSize := maxFileSize(opts)
+	readFile := opts.ReadFile
+	if readFile == nil {
+		readFile = readMailFile
+	}
+
+	// Network filesystems occasionally fail reads transiently, so retry
+	// those with exponential backoff
+	var data []byte
+	var err error
+	attempt := 0
+	for {
+		data, err = readFile(filename, maxSize)
+		if err == nil || attempt >= opts.MaxRetries || !isRetryableReadError(err) {
+			break
+		}
+		time.Sleep(opts.RetryBackoff << attempt)
+		attempt++
+	}
+	if err != nil {
+		return nil, err
+	}
+	if attempt > 0 && opts.OnRetriedRead != nil {
+		opts.OnRetriedRead(filename)
+	}
+
+	// The modification time is only needed to recover missing timestamps
+	var modTime time.Time
+	if opts.RecoverHeaders > 0 {
+		if info, err := os.Stat(filename); err == nil {
+			modTime = info.ModTime()
+		}
+	}
+
+	return parseMailData(filename, data, modTime, opts)
+}
+
+// parseMailData parses a mail file that was already read into memory, such
+// as an archive entry; name and modTime stand in for the file name and
+// modification time. With opts.Cache, unchanged contents are served from
+// the cache.
+func parseMailData(name string, data []byte, modTime time.Time, opts ParseOptions) (*MailData, error) {
+	if maxSize := maxFileSize(opts); int64(len(data)) > maxSize {
+		return nil, fileTooLargeError(name, maxSize)
+	}
+
+	var hash string
+	if opts.Cache != nil {
+		hash = fileContentHash(data)
+		if mail, ok := opts.Cache.get(hash); ok {
+			return mail, nil
+		}
+	}
+
+	lines, err := decodeMailLines(name, data, scannerBufferSize(opts))
+	if err != nil {
+		return nil, err
+	}
+	mail, err := parseMailLines(name, lines, modTime, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recovered headers depend on the file name and modification time, not
+	// only the content
+	if opts.Cache != nil && !mail.Recovered {
+		opts.Cache.put(hash, mail)
+	}
+	return mail, nil
+}
+
+// scannerBufferSize returns the maximum line length of opts, or the default
+func scannerBufferSize(opts ParseOptions) int {
+	if opts.ScannerBufferSize <= 0 {
+		return defaultScannerBufferSize
+	}
+	return opts.ScannerBufferSize
+}
+
+// maxFileSize returns the largest mail file size of opts, or the default
+func maxFileSize(opts ParseOptions) int64 {
+	if opts.MaxFileSize <= 0 {
+		return defaultMaxFileSize
+	}
+	return opts.MaxFileSize
+}
+
+// fileTooLargeError describes a mail file exceeding maxSize bytes
+func fileTooLargeError(filename string, maxSize int64) error {
+	return &ParseError{
+		File: filename,
+		Err:  errFileTooLarge,
+		Hint: fmt.Sprintf("larger than %d bytes, increase --max-file-size", maxSize),
+	}
+}
+
+// parseMailLines extracts the mail data from the lines of a mail file
+func parseMailLines(filename string, lines []string, modTime time.Time, opts ParseOptions) (*MailData, error) {
+	header, err := parseMailHeader(lines, opts.TimestampFormat)
+	if err == nil && opts.RecoverHeaders > 0 && !plausibleSender(header.Sender) {
+		// Shuffled header lines can still put a TIMESTAMP line on line 3
+		err = fmt.Errorf("implausible sender: %s", header.Sender)
+	}
+	recovered := false
+	if err != nil {
+		if opts.RecoverHeaders <= 0 {
+			return nil, err
+		}
+		var ok bool
+		if header, ok = recoverMailHeader(filename, modTime, lines, opts.RecoverHeaders, opts.TimestampFormat); !ok {
+			return nil, err
+		}
+		recovered = true
+	}
+	mailID, sender, subject, body := header.MailID, header.Sender, header.Subject, header.Body
+
+	mail := &MailData{
+		MailID:    mailID,
+		Sender:    sender,
+		Subject:   subject,
+		Timestamp: header.Timestamp,
+		Body:      body,
+		ItemName:  parseItemName(body),
+		Buyer:     parseBuyer(body),
+		Price:     parsePrice(body),
+		Recovered: recovered,
+	}
+
+	mail.ContentHash = contentHash(*mail)
+	mail.NormalizedSubject = normalizeSubject(subject)
+	if opts.NormalizeIDs {
+		mail.MailIDNormalized = normalizeMailID(mailID)
+	}
+	mail.MailCategory = categorize(sender, subject, body)
+	mail.SaleType = saleType(mail.MailCategory, body)
+	if mail.SaleType == SaleTypeVendor {
+		mail.VendorName = parseVendorName(body)
+	}
+
+	saleNotification := isSaleNotification(mail)
+	patternSets := localizedPatterns
+	if opts.Patterns != nil {
+		patternSets = append(slices.Clone(patternSets), opts.Patterns)
+	}
+	for _, patterns := range patternSets {
+		patterns.apply(mail)
+		saleNotification = saleNotification || patterns.isSaleNotification(mail)
+	}
+
+	if mail.MailCategory == CategorySale {
+		mail.PriceType = parsePriceType(body)
+	}
+	mail.Purchase = parsePurchase(body)
+	mail.Auction = parseAuction(body)
+	mail.Expired = parseExpiredItem(body)
+	itemKeyRules := opts.ItemKeyRules
+	if itemKeyRules == nil {
+		itemKeyRules = defaultItemKeyRules
+	}
+	mail.FactoryRun = parseFactoryRun(body, itemKeyRules)
+	mail.SurveyResults = parseSurveyResults(subject, body)
+	// Only system mails pay out; players merely talk about payouts
+	if mail.MailCategory == CategoryUnknown {
+		mail.Income = parseIncome(subject, body)
+	}
+	var server string
+	mail.SenderDomain, server, mail.SenderSubsystem = parseSenderParts(sender)
+	mail.Galaxy = opts.Galaxy
+	if mail.Galaxy == "" {
+		mail.Galaxy = server
+	}
+
+	systemSenders := opts.SystemSenders
+	if systemSenders == nil {
+		systemSenders = SystemSenders
+	}
+	mail.SenderLabel = systemSenders[sender]
+
+	if mail.ItemName != "" {
+		mail.ItemKey = normalizeItemKey(mail.ItemName, itemKeyRules)
+
+		mail.SerialNumber = parseSerialNumber(body)
+		mail.UnitCount = parseUnitCount(mail.ItemName)
+		if mail.UnitCount > 0 && mail.Price > 0 {
+			mail.PricePerUnit = float64(mail.Price) / float64(mail.UnitCount)
+		}
+
+		mail.CanonicalItemName = opts.ItemDB[mail.ItemName]
+		if mail.CanonicalItemName == "" {
+			mail.CanonicalItemName = opts.ItemDB[mail.ItemKey]
+		}
+
+		categoryRules := opts.ItemCategoryRules
+		if categoryRules == nil {
+			categoryRules = defaultItemCategoryRules
+		}
+		mail.ItemCategory = classifyItem(canonicalItemName(*mail), categoryRules)
+	}
+	if saleNotification {
+		mail.Sale = saleData(mail)
+	}
+
+	// Extract location if available (look for location pattern in body)
+	if location, ok := parseLocation(body); ok {
+		mail.City = location.City
+		mail.Planet = location.Planet
+		mail.Location = fmt.Sprintf("%s, %s", location.City, location.Planet)
+	}
+
+	// Extract coordinates if available and synthesize a location from them
+	// when the body does not name a city
+	if x, y, z, ok := parseCoordinates(body); ok {
+		mail.HasCoordinates = true
+		mail.LocationX = x
+		mail.LocationY = y
+		mail.LocationZ = z
+
+		if mail.Location == "" {
+			mail.Planet = parseCoordinatePlanet(body)
+			mail.Location = strings.TrimSpace(fmt.Sprintf("%s (%s, %s, %s)",
+				mail.Planet, formatCoordinate(x), formatCoordinate(y), formatCoordinate(z)))
+		}
+	}
+	mail.Waypoints = parseWaypoints(body)
+
+	classifiers := opts.MailClassifiers
+	if classifiers == nil {
+		classifiers = defaultMailClassifiers
+	}
+	mail.MailType = classifyMailType(mail, classifiers)
+	if mail.MailType == MailTypeCity || mail.MailType == MailTypeGuild {
+		mail.BroadcastName = parseBroadcastName(mail)
+	}
+
+	return mail, nil
+}
+
+// mailHeader holds the fixed lines at the start of a mail file and the
+// body following them
+type mailHeader struct {
+	MailID    string
+	Sender    string
+	Subject   string
+	Timestamp time.Time
+	Body      string
+}
+
+// parseMailHeader splits a mail file in the expected format:
+// Line 0: Mail ID
+// Line 1: Sender
+// Line 2: Subject
+// Line 3: TIMESTAMP: <unix timestamp>
+// Line 4+: Body content
+func parseMailHeader(lines []string, timestampFormat string) (mailHeader, error) {
+	if len(lines) < 4 {
+		return mailHeader{}, fmt.Errorf("mail file too short: %d lines", len(lines))
+	}
+
+	// Parse timestamp
+	timestampLine := strings.TrimSpace(lines[3])
+	if !strings.HasPrefix(timestampLine, "TIMESTAMP: ") {
+		return mailHeader{}, fmt.Errorf("invalid timestamp line: %s", timestampLine)
+	}
+
+	timestampStr := strings.TrimPrefix(timestampLine, "TIMESTAMP: ")
+	timestamp, err := parseTimestamp(timestampStr, timestampFormat)
+	if err != nil {
+		return mailHeader{}, err
+	}
+
+	return mailHeader{
+		MailID:    strings.TrimSpace(lines[0]),
+		Sender:    strings.TrimSpace(lines[1]),
+		Subject:   strings.TrimSpace(lines[2]),
+		Timestamp: timestamp,
+		// Everything after the timestamp line
+		Body: strings.Join(lines[4:], "\n"),
+	}, nil
+}
+
+// readMailFile reads the content of a mail file. Files larger than maxSize
+// bytes are rejected without reading them whole.
+func readMailFile(filename string, maxSize int64) ([]byte, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	if info, err := file.Stat(); err == nil && info.Size() > maxSize {
+		return nil, fileTooLargeError(filename, maxSize)
+	}
+
+	// The file may still grow after Stat, so never read more than that
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fileTooLargeError(filename, maxSize)
+	}
+	return data, nil
+}
+
+// decodeMailLines splits the content of a mail file into lines, decoding
+// binary mail files and transcoding text to UTF-8 if necessary
+func decodeMailLines(filename string, data []byte, bufferSize int) ([]string, error) {
+	if lines, ok := decodeBinaryMail(data); ok {
+		return lines, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(toUTF8(data)))
+	scanner.Buffer(make([]byte, 0, min(bufferSize, defaultScannerBufferSize)), bufferSize)
 	var lines []string
 	for scanner.Scan() {
 		lines = append(lines, scanner.Text())
 	}
 
 	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, &ParseError{
+				File: filename,
+				Err:  err,
+				Hint: fmt.Sprintf("a line exceeds %d bytes, increase --scanner-buffer-size", bufferSize),
+			}
+		}
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	if len(lines) < 4 {
-		return nil, fmt.Errorf("mail file too short: %d lines", len(lines))
+	return lines, nil
+}
+
+// isRetryableReadError reports whether a read error is likely transient
+func isRetryableReadError(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.EAGAIN)
+}
+
+// parseMailFileCtx parses a mail file like parseMailFile but gives up when
+// ctx is done. bufio.Scanner cannot be interrupted, so the parse runs in its
+// own goroutine which is abandoned on timeout and exits once the read ends.
+func parseMailFileCtx(ctx context.Context, filename string, opts ParseOptions) (*MailData, error) {
+	type parseResult struct {
+		mail *MailData
+		err  error
 	}
 
-	// Parse mail format:
-	// Line 0: Mail ID
-	// Line 1: Sender
-	// Line 2: Subject
-	// Line 3: TIMESTAMP: <unix timestamp>
-	// Line 4+: Body content
+	done := make(chan parseResult, 1)
+	go func() {
+		mail, err := parseMailFile(filename, opts)
+		done <- parseResult{mail, err}
+	}()
 
-	mailID := strings.TrimSpace(lines[0])
-	sender := strings.TrimSpace(lines[1])
-	subject := strings.TrimSpace(lines[2])
+	select {
+	case result := <-done:
+		return result.mail, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("parsing %s: %w", filename, ctx.Err())
+	}
+}
 
-	// Parse timestamp
-	timestampLine := strings.TrimSpace(lines[3])
-	if !strings.HasPrefix(timestampLine, "TIMESTAMP: ") {
-		return nil, fmt.Errorf("invalid timestamp line: %s", timestampLine)
+// parseMailFileWithTimeout parses a mail file, bounded by opts.ParseTimeout
+// when it is set
+func parseMailFileWithTimeout(ctx context.Context, filename string, opts ParseOptions) (*MailData, error) {
+	if opts.ParseTimeout <= 0 {
+		return parseMailFile(filename, opts)
 	}
 
-	timestampStr := strings.TrimPrefix(timestampLine, "TIMESTAMP: ")
-	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	ctx, cancel := context.WithTimeout(ctx, opts.ParseTimeout)
+	defer cancel()
+
+	return parseMailFileCtx(ctx, filename, opts)
+}
+
+// parseTimestamp parses the value of a TIMESTAMP line, either as Unix
+// seconds (format "unix" or empty) or with the given time.Parse layout
+func parseTimestamp(value, format string) (time.Time, error) {
+	if format == "" || format == "unix" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+		return time.Unix(seconds, 0), nil
+	}
+
+	timestamp, err := time.Parse(format, value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		return time.Time{}, fmt.Errorf("failed to parse timestamp %q with layout %q: %w", value, format, err)
 	}
+	return timestamp, nil
+}
 
-	// Collect body content (everything after timestamp line)
-	var bodyLines []string
-	if len(lines) > 4 {
-		bodyLines = lines[4:]
+// normalizeMailID formats decimal, "0x" hex and "mail_<number>" IDs as
+// zero-padded 16-digit lowercase hex, so "255", "0xFF" and "mail_255" all
+// become "00000000000000ff". Other IDs are returned unchanged.
+func normalizeMailID(raw string) string {
+	id := strings.TrimPrefix(raw, "mail_")
+
+	var value uint64
+	var err error
+	if hexDigits, ok := strings.CutPrefix(strings.ToLower(id), "0x"); ok {
+		value, err = strconv.ParseUint(hexDigits, 16, 64)
+	} else {
+		value, err = strconv.ParseUint(id, 10, 64)
+	}
+	if err != nil {
+		return raw
 	}
-	body := strings.Join(bodyLines, "\n")
 
-	// Extract location if available (look for location pattern in body)
-	location := parseLocation(body)
+	return fmt.Sprintf("%016x", value)
+}
 
-	return &MailData{
-		MailID:    mailID,
-		Sender:    sender,
-		Subject:   subject,
-		Timestamp: time.Unix(timestamp, 0),
-		Body:      body,
-		Location:  location,
-	}, nil
+// normalizeSubject strips leading "**...**" and "[...]" markers and trailing
+// punctuation, e.g. "[AUTO] Sale Complete!" becomes "Sale Complete"
+func normalizeSubject(subject string) string {
+	normalized := subjectPrefixPattern.ReplaceAllString(subject, "")
+	normalized = subjectSuffixPattern.ReplaceAllString(normalized, "")
+	return strings.TrimSpace(normalized)
 }
 
-// parseLocation extracts location from mail body content
-func parseLocation(body string) string {
-	// Expected format: "The sale took place at LocationName, on PlanetName."
-	re := regexp.MustCompile(`The sale took place at (.*?), on (.*?)\.`)
-	matches := re.FindStringSubmatch(body)
+// parseSenderParts splits a "SYSTEM.SERVER.subsystem" sender into its parts.
+// Missing parts are empty; anything after the second dot belongs to the subsystem.
+func parseSenderParts(sender string) (domain, server, subsystem string) {
+	parts := strings.SplitN(sender, ".", 3)
+	domain = parts[0]
+	if len(parts) > 1 {
+		server = parts[1]
+	}
+	if len(parts) > 2 {
+		subsystem = parts[2]
+	}
+	return domain, server, subsystem
+}
+
+// parseLocation extracts location from mail body content, see
+// normalizeLocation
+func parseLocation(body string) (ParsedLocation, bool) {
+	matches := locationPattern.FindStringSubmatch(body)
 
 	if len(matches) == 3 {
-		return fmt.Sprintf("%s, %s", matches[1], matches[2])
+		city, planet := normalizeLocation(matches[1], matches[2])
+		return ParsedLocation{City: city, Planet: planet}, true
+	}
+
+	return ParsedLocation{}, false
+}
+
+// parseItemName extracts the sold item name from mail body content
+func parseItemName(body string) string {
+	matches := itemNamePattern.FindStringSubmatch(body)
+
+	if len(matches) == 2 {
+		return strings.TrimSpace(matches[1])
 	}
 
 	return ""
 }
+
+// parseBuyer extracts the buyer name from mail body content
+func parseBuyer(body string) string {
+	matches := buyerPattern.FindStringSubmatch(body)
+
+	if len(matches) == 2 {
+		return strings.TrimSpace(matches[1])
+	}
+
+	return ""
+}
+
+// parsePrice extracts the sale price in credits from mail body content
+func parsePrice(body string) int64 {
+	matches := pricePattern.FindStringSubmatch(body)
+
+	if len(matches) == 2 {
+		price, err := strconv.ParseInt(matches[1], 10, 64)
+		if err == nil {
+			return price
+		}
+	}
+
+	return 0
+}
+
+// parsePurchase extracts the details of a bought item from mail body
+// content, or returns nil if the body is not a purchase notification
+func parsePurchase(body string) *PurchaseData {
+	matches := purchasePattern.FindStringSubmatch(body)
+	if len(matches) != 4 {
+		return nil
+	}
+
+	price, err := strconv.ParseInt(matches[3], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	purchase := &PurchaseData{
+		ItemName: strings.TrimSpace(matches[1]),
+		Seller:   strings.TrimSpace(matches[2]),
+		Price:    price,
+	}
+	if location, ok := parseLocation(body); ok {
+		purchase.Location = fmt.Sprintf("%s, %s", location.City, location.Planet)
+	}
+	return purchase
+}
+
+// Auction events assigned by parseAuction
+const (
+	AuctionEventWon    = "won"
+	AuctionEventOutbid = "outbid"
+)
+
+// parseAuction extracts the item and bid of an auction won or outbid
+// notification from mail body content, or returns nil for other mails
+func parseAuction(body string) *AuctionData {
+	event := AuctionEventWon
+	matches := auctionWonPattern.FindStringSubmatch(body)
+	if matches == nil {
+		event = AuctionEventOutbid
+		matches = auctionOutbidPattern.FindStringSubmatch(body)
+	}
+	if len(matches) != 3 {
+		return nil
+	}
+
+	bid, err := strconv.ParseInt(matches[2], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &AuctionData{Event: event, ItemName: strings.TrimSpace(matches[1]), Bid: bid}
+}
+
+// Sources of IncomeData
+const (
+	IncomeSourceMission = "mission"
+	IncomeSourceGCW     = "gcw"
+)
+
+// parseIncome extracts the payout of mission completion and GCW reward
+// mails, or returns nil for other mails
+func parseIncome(subject, body string) *IncomeData {
+	matches := incomeAmountPattern.FindStringSubmatch(body)
+	if len(matches) != 2 {
+		return nil
+	}
+	credits, err := strconv.ParseInt(strings.ReplaceAll(matches[1], ",", ""), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	text := subject + "\n" + body
+	switch {
+	case gcwPattern.MatchString(text):
+		return &IncomeData{Source: IncomeSourceGCW, Credits: credits}
+	case missionPattern.MatchString(text):
+		return &IncomeData{Source: IncomeSourceMission, Credits: credits}
+	default:
+		return nil
+	}
+}
+
+// isFactoryRun reports whether body is a factory run completion notification
+func isFactoryRun(body string) bool {
+	for _, pattern := range factoryRunPatterns {
+		if pattern.MatchString(body) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFactoryRun extracts the factory, item and quantity of a factory run
+// completion notification, or returns nil for other mails. The item key is
+// derived with rules, so that production can be matched to sales.
+func parseFactoryRun(body string, rules []itemKeyRule) *FactoryRun {
+	for _, pattern := range factoryRunPatterns {
+		matches := pattern.FindStringSubmatch(body)
+		if len(matches) != 4 {
+			continue
+		}
+
+		quantity, err := strconv.ParseInt(strings.ReplaceAll(matches[2], ",", ""), 10, 64)
+		if err != nil {
+			continue
+		}
+		itemName := strings.TrimSpace(matches[3])
+		return &FactoryRun{
+			Factory:  strings.TrimSpace(matches[1]),
+			ItemName: itemName,
+			ItemKey:  normalizeItemKey(itemName, rules),
+			Quantity: quantity,
+		}
+	}
+	return nil
+}
+
+// parseExpiredItem extracts the item and its pickup location from an
+// expiry notification, or returns nil for other mails
+func parseExpiredItem(body string) *ExpiredItem {
+	matches := expiredPattern.FindStringSubmatch(body)
+	if len(matches) != 2 {
+		return nil
+	}
+
+	expired := &ExpiredItem{ItemName: strings.TrimSpace(matches[1])}
+	if location := expiredLocationPattern.FindStringSubmatch(body); len(location) == 3 {
+		city, planet := normalizeLocation(location[1], location[2])
+		expired.Location = fmt.Sprintf("%s, %s", city, planet)
+	}
+	return expired
+}
+
+// parseSerialNumber extracts the serial number of a crafted item from mail
+// body content, which includes the item name
+func parseSerialNumber(body string) string {
+	matches := serialNumberPattern.FindStringSubmatch(body)
+	if len(matches) == 2 {
+		return matches[1]
+	}
+	return ""
+}
+
+// parseUnitCount extracts the stack size from an item name, or returns 0
+// if the name has none
+func parseUnitCount(itemName string) int64 {
+	for _, pattern := range unitCountPatterns {
+		if matches := pattern.FindStringSubmatch(itemName); len(matches) == 2 {
+			if count, err := strconv.ParseInt(matches[1], 10, 64); err == nil && count > 0 {
+				return count
+			}
+		}
+	}
+	return 0
+}
+
+// parsePriceType tells auction bids from buy-now purchases in mail body content
+func parsePriceType(body string) string {
+	switch {
+	case bidPricePattern.MatchString(body):
+		return PriceTypeBid
+	case buyNowPricePattern.MatchString(body):
+		return PriceTypeBuyNow
+	default:
+		return PriceTypeUnknown
+	}
+}
+
+// parseCoordinates extracts coordinates from mail body content
+func parseCoordinates(body string) (x, y, z float64, ok bool) {
+	// Two-component coordinates are interpreted as x and z.
+	matches := coordinatesPattern.FindStringSubmatch(body)
+	if matches == nil {
+		return 0, 0, 0, false
+	}
+
+	values := make([]float64, 0, 3)
+	for _, match := range matches[1:] {
+		if match == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(match, 64)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		values = append(values, value)
+	}
+
+	if len(values) == 2 {
+		return values[0], 0, values[1], true
+	}
+
+	return values[0], values[1], values[2], true
+}
+
+// parseWaypoints extracts all waypoints embedded in a mail body
+func parseWaypoints(body string) []Waypoint {
+	var waypoints []Waypoint
+	for _, matches := range waypointPattern.FindAllStringSubmatch(body, -1) {
+		x, errX := strconv.ParseFloat(matches[2], 64)
+		z, errZ := strconv.ParseFloat(matches[3], 64)
+		y, errY := strconv.ParseFloat(matches[4], 64)
+		if errX != nil || errZ != nil || errY != nil {
+			continue
+		}
+		waypoints = append(waypoints, Waypoint{
+			Planet: planetScene(matches[1]),
+			X:      x,
+			Y:      y,
+			Z:      z,
+			Name:   matches[5],
+		})
+	}
+	return waypoints
+}
+
+// parseCoordinatePlanet extracts the planet name following a coordinate triplet
+func parseCoordinatePlanet(body string) string {
+	matches := coordinatePlanetPattern.FindStringSubmatch(body)
+
+	if len(matches) == 2 {
+		_, planet := normalizeLocation("", matches[1])
+		return planet
+	}
+
+	return ""
+}
+
+// formatCoordinate formats a coordinate component without trailing zeros
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// validateMail checks that the header fields of a mail are set and that
+// sale details were extracted from auctioneer mails. It returns one error
+// per missing field.
+func validateMail(filename string, mail *MailData) []*ParseError {
+	type check struct {
+		field   string
+		missing bool
+		reason  string
+	}
+
+	checks := []check{
+		{"MailID", mail.MailID == "", "mail ID is empty"},
+		{"Sender", mail.Sender == "", "sender is empty"},
+		{"Subject", mail.Subject == "", "subject is empty"},
+	}
+	if (mail.Sender == auctioneerSender || mail.Sale != nil) && mail.Purchase == nil && mail.Auction == nil && mail.Expired == nil {
+		checks = append(checks,
+			check{"Location", mail.Location == "", "sale location not found in body"},
+			check{"ItemName", mail.ItemName == "", "item name not found in body"},
+			check{"Price", mail.Price == 0, "price not found in body"},
+		)
+	}
+
+	var errs []*ParseError
+	for _, c := range checks {
+		if c.missing {
+			errs = append(errs, &ParseError{File: filename, Field: c.field, Err: errors.New(c.reason)})
+		}
+	}
+	return errs
+}
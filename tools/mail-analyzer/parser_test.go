@@ -0,0 +1,628 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCoordinates(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		x, y, z float64
+		ok      bool
+	}{
+		{"triplet", "Meet me at coordinates 1234.56 -78.9 5678.0 on Tatooine.", 1234.56, -78.9, 5678, true},
+		{"negative", "Drop off at coordinates -3500 -12 -4800 on Naboo.", -3500, -12, -4800, true},
+		{"scientific notation", "Found at coordinates 1.5e3 2E1 -3.25e+2 on Lok.", 1500, 20, -325, true},
+		{"two components are x and z", "Waiting at coordinates -100, 250 on Corellia.", -100, 0, 250, true},
+		{"no coordinates", "The sale took place at Mos Eisley, on Tatooine.", 0, 0, 0, false},
+		{"word without numbers", "Send me your coordinates please.", 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y, z, ok := parseCoordinates(tt.body)
+			if ok != tt.ok || x != tt.x || y != tt.y || z != tt.z {
+				t.Errorf("parseCoordinates(%q) = %v, %v, %v, %v, want %v, %v, %v, %v",
+					tt.body, x, y, z, ok, tt.x, tt.y, tt.z, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseMailLinesCoordinateLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		location string
+		planet   string
+	}{
+		{
+			name:     "synthesized from coordinates",
+			body:     "Meet me at coordinates -1234.5 7 42 on Tatooine.",
+			location: "Tatooine (-1234.5, 7, 42)",
+			planet:   "Tatooine",
+		},
+		{
+			name:     "city pattern wins",
+			body:     "The sale took place at Theed, on Naboo. Pick up at coordinates 10 20 30 on Naboo.",
+			location: "Theed, Naboo",
+			planet:   "Naboo",
+		},
+		{
+			name:     "without planet",
+			body:     "Meet me at coordinates 5 6",
+			location: "(5, 0, 6)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []string{"1", "Player", "Meeting", "TIMESTAMP: 1700000000", tt.body}
+			mail, err := parseMailLines("1.mail", lines, time.Time{}, ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !mail.HasCoordinates {
+				t.Error("HasCoordinates = false, want true")
+			}
+			if mail.Location != tt.location {
+				t.Errorf("Location = %q, want %q", mail.Location, tt.location)
+			}
+			if mail.Planet != tt.planet {
+				t.Errorf("Planet = %q, want %q", mail.Planet, tt.planet)
+			}
+		})
+	}
+}
+
+func TestParseWaypointsPlanet(t *testing.T) {
+	tests := []struct {
+		body   string
+		planet string
+	}{
+		{"/way tatooine 3500 -4800 12 Krayt Graveyard", "tatooine"},
+		{"/way Yavin IV 1 2 3 Temple", "yavin4"},
+		{"/way dantooine 1 2 3 Outpost", "dantooine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			waypoints := parseWaypoints(tt.body)
+			if len(waypoints) != 1 {
+				t.Fatalf("parseWaypoints(%q) returned %d waypoints, want 1", tt.body, len(waypoints))
+			}
+			if waypoints[0].Planet != tt.planet {
+				t.Errorf("Planet = %q, want %q", waypoints[0].Planet, tt.planet)
+			}
+		})
+	}
+}
+
+func TestLocationBoundingBox(t *testing.T) {
+	tests := []struct {
+		name  string
+		mails []MailData
+		want  BoundingBox
+	}{
+		{"no coordinates", []MailData{{MailID: "1"}}, BoundingBox{}},
+		{
+			name: "negative coordinates",
+			mails: []MailData{
+				{MailID: "1", HasCoordinates: true, LocationX: -100, LocationZ: 50},
+				{MailID: "2", HasCoordinates: true, LocationX: 25, LocationZ: -75},
+				{MailID: "3"},
+			},
+			want: BoundingBox{MinX: -100, MaxX: 25, MinZ: -75, MaxZ: 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := generateMailStats(tt.mails)
+			if stats.LocationBoundingBox != tt.want {
+				t.Errorf("LocationBoundingBox = %+v, want %+v", stats.LocationBoundingBox, tt.want)
+			}
+		})
+	}
+}
+
+// writeTestMail writes a mail file with the given header and body to
+// dir/name and returns its path
+func writeTestMail(t *testing.T, dir, name, id, sender, subject string, timestamp int64, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf("%s\n%s\n%s\nTIMESTAMP: %d\n%s\n", id, sender, subject, timestamp, body)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseMailFileScannerBufferSize(t *testing.T) {
+	longLine := strings.Repeat("A", 100*1024)
+	path := writeTestMail(t, t.TempDir(), "1.mail", "1", "Player", "Attachment", 1700000000, longLine)
+
+	tests := []struct {
+		name       string
+		bufferSize int
+		wantErr    bool
+	}{
+		{"default buffer", 0, true},
+		{"too small", 64 * 1024, true},
+		{"large enough", 128 * 1024, false},
+		{"maximum", maxScannerBufferSize, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mail, err := parseMailFile(path, ParseOptions{ScannerBufferSize: tt.bufferSize})
+			if tt.wantErr {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) || !errors.Is(err, bufio.ErrTooLong) {
+					t.Fatalf("parseMailFile() error = %v, want a ParseError for bufio.ErrTooLong", err)
+				}
+				if !strings.Contains(parseErr.Hint, "--scanner-buffer-size") {
+					t.Errorf("Hint = %q, want a hint to increase --scanner-buffer-size", parseErr.Hint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMailFile() error = %v", err)
+			}
+			if mail.Body != longLine {
+				t.Errorf("Body has %d bytes, want %d", len(mail.Body), len(longLine))
+			}
+		})
+	}
+}
+
+func TestGetCompiledRegex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   string
+		wantErr bool
+	}{
+		{`^Rifle`, "Rifle Mk2", false},
+		{`(?i)buyer:\s*(\w+)`, "BUYER: Han", false},
+		{`[unclosed`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re, err := getCompiledRegex(tt.pattern)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("getCompiledRegex() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getCompiledRegex() error = %v", err)
+			}
+			if !re.MatchString(tt.match) {
+				t.Errorf("%s does not match %q", tt.pattern, tt.match)
+			}
+			again, _ := getCompiledRegex(tt.pattern)
+			if again != re {
+				t.Error("second call compiled the pattern again, want the cached regexp")
+			}
+		})
+	}
+}
+
+const benchmarkPattern = `(?i)sold (.+?) to (\w+) for (\d+) credits`
+
+func BenchmarkGetCompiledRegexHit(b *testing.B) {
+	getCompiledRegex(benchmarkPattern)
+	for b.Loop() {
+		if _, err := getCompiledRegex(benchmarkPattern); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetCompiledRegexMiss(b *testing.B) {
+	for b.Loop() {
+		regexCache.Delete(benchmarkPattern)
+		if _, err := getCompiledRegex(benchmarkPattern); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseSenderParts(t *testing.T) {
+	tests := []struct {
+		sender                    string
+		domain, server, subsystem string
+	}{
+		{"Han Solo", "Han Solo", "", ""},
+		{"SWG.Restoration", "SWG", "Restoration", ""},
+		{"SWG.Restoration.auctioner", "SWG", "Restoration", "auctioner"},
+		{"SWG.Restoration.city.hall", "SWG", "Restoration", "city.hall"},
+		{"", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sender, func(t *testing.T) {
+			domain, server, subsystem := parseSenderParts(tt.sender)
+			if domain != tt.domain || server != tt.server || subsystem != tt.subsystem {
+				t.Errorf("parseSenderParts(%q) = %q, %q, %q, want %q, %q, %q",
+					tt.sender, domain, server, subsystem, tt.domain, tt.server, tt.subsystem)
+			}
+		})
+	}
+}
+
+func TestParseMailLinesSenderParts(t *testing.T) {
+	tests := []struct {
+		sender    string
+		domain    string
+		subsystem string
+		galaxy    string
+	}{
+		{"SWG.Restoration.auctioner", "SWG", "auctioner", "Restoration"},
+		{"SWG.Basilisk", "SWG", "", "Basilisk"},
+		{"Han Solo", "Han Solo", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sender, func(t *testing.T) {
+			lines := []string{"1", tt.sender, "Hello", "TIMESTAMP: 1700000000", "Body"}
+			mail, err := parseMailLines("1.mail", lines, time.Time{}, ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mail.SenderDomain != tt.domain || mail.SenderSubsystem != tt.subsystem || mail.Galaxy != tt.galaxy {
+				t.Errorf("SenderDomain, SenderSubsystem, Galaxy = %q, %q, %q, want %q, %q, %q",
+					mail.SenderDomain, mail.SenderSubsystem, mail.Galaxy, tt.domain, tt.subsystem, tt.galaxy)
+			}
+		})
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		city   string
+		planet string
+		ok     bool
+	}{
+		{
+			name:   "city and planet",
+			body:   "Vendor: Crafter has sold Rifle to Han for 1000 credits.\nThe sale took place at Mos Eisley, on Tatooine.",
+			city:   "Mos Eisley",
+			planet: "Tatooine",
+			ok:     true,
+		},
+		{
+			name:   "player city",
+			body:   "The sale took place at Crafter Town, on Naboo.",
+			city:   "Crafter Town",
+			planet: "Naboo",
+			ok:     true,
+		},
+		{
+			name:   "abbreviated planet and NPC city spelling",
+			body:   "The sale took place at  coronet , on cor.",
+			city:   "Coronet",
+			planet: "Corellia",
+			ok:     true,
+		},
+		{
+			name:   "planet of a known NPC city",
+			body:   "The sale took place at Theed, on .",
+			city:   "Theed",
+			planet: "Naboo",
+			ok:     true,
+		},
+		{
+			name: "no location",
+			body: "Vendor: Crafter has sold Rifle to Han for 1000 credits.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, ok := parseLocation(tt.body)
+			if ok != tt.ok || location.City != tt.city || location.Planet != tt.planet {
+				t.Errorf("parseLocation() = %+v, %v, want {City:%s Planet:%s}, %v", location, ok, tt.city, tt.planet, tt.ok)
+			}
+
+			lines := []string{"1", "SWG.Restoration.auctioner", "Vendor Sale Complete", "TIMESTAMP: 1700000000", tt.body}
+			mail, err := parseMailLines("1.mail", lines, time.Time{}, ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mail.City != tt.city || mail.Planet != tt.planet {
+				t.Errorf("City, Planet = %q, %q, want %q, %q", mail.City, mail.Planet, tt.city, tt.planet)
+			}
+		})
+	}
+}
+
+func TestNormalizeMailID(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"255", "00000000000000ff"},
+		{"0", "0000000000000000"},
+		{"0xFF", "00000000000000ff"},
+		{"0x00ff", "00000000000000ff"},
+		{"mail_255", "00000000000000ff"},
+		{"mail_0x1a", "000000000000001a"},
+		{"18446744073709551615", "ffffffffffffffff"},
+		// Opaque IDs are kept as they are
+		{"abc-123", "abc-123"},
+		{"mail_abc", "mail_abc"},
+		{"0xZZ", "0xZZ"},
+		{"-1", "-1"},
+		{"18446744073709551616", "18446744073709551616"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := normalizeMailID(tt.raw); got != tt.want {
+				t.Errorf("normalizeMailID(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNormalizeIDsDedup(t *testing.T) {
+	dir := t.TempDir()
+	writeTestMail(t, dir, "1.mail", "255", "Han Solo", "Hello", 1700000000, "First")
+	writeTestMail(t, dir, "2.mail", "0xff", "Han Solo", "Hello", 1700000001, "Second")
+	writeTestMail(t, dir, "3.mail", "mail_256", "Han Solo", "Hello", 1700000002, "Third")
+
+	tests := []struct {
+		normalizeIDs bool
+		wantMails    int
+	}{
+		{false, 3},
+		// 255 and 0xff are the same mail ID
+		{true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("normalize %v", tt.normalizeIDs), func(t *testing.T) {
+			result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{NormalizeIDs: tt.normalizeIDs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Mails) != tt.wantMails {
+				t.Errorf("parsed %d mails, want %d", len(result.Mails), tt.wantMails)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		format  string
+		want    time.Time
+		wantErr bool
+	}{
+		{"unix", "1705312800", "unix", time.Unix(1705312800, 0), false},
+		{"default unix", "1705312800", "", time.Unix(1705312800, 0), false},
+		{"RFC 3339", "2024-01-15T10:00:00Z", time.RFC3339, time.Date(2024, time.January, 15, 10, 0, 0, 0, time.UTC), false},
+		{"RFC 3339 with offset", "2024-01-15T12:00:00+02:00", time.RFC3339, time.Date(2024, time.January, 15, 10, 0, 0, 0, time.UTC), false},
+		{"custom layout", "15.01.2024 10:00", "02.01.2006 15:04", time.Date(2024, time.January, 15, 10, 0, 0, 0, time.UTC), false},
+		{"unparsable unix", "yesterday", "unix", time.Time{}, true},
+		{"value not in layout", "2024-01-15", "02.01.2006 15:04", time.Time{}, true},
+		{"unix value with layout", "1705312800", time.RFC3339, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimestamp(tt.value, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimestamp(%q, %q) error = %v, wantErr %v", tt.value, tt.format, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseTimestamp(%q, %q) = %v, want %v", tt.value, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMailLinesTimestampFormat(t *testing.T) {
+	lines := []string{"1", "Han Solo", "Hello", "TIMESTAMP: 2024-01-15T10:00:00Z", "Body"}
+	mail, err := parseMailLines("1.mail", lines, time.Time{}, ParseOptions{TimestampFormat: time.RFC3339})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, time.January, 15, 10, 0, 0, 0, time.UTC); !mail.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", mail.Timestamp, want)
+	}
+
+	// The default unix format does not accept other timestamps
+	if _, err := parseMailLines("1.mail", lines, time.Time{}, ParseOptions{}); err == nil {
+		t.Error("parsing an RFC 3339 timestamp as unix succeeded, want an error")
+	}
+}
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Vendor Sale Complete", "Vendor Sale Complete"},
+		{"[AUTO] Sale Complete!", "Sale Complete"},
+		{"**URGENT** Item Expired", "Item Expired"},
+		{"  [AUTO][Bazaar] **Notice** Sale Complete...", "Sale Complete"},
+		{"Sale Complete ~*~", "Sale Complete"},
+		// Brackets inside the subject are kept
+		{"Re: Rifle [x2]", "Re: Rifle [x2]"},
+		{"[AUTO]", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			if got := normalizeSubject(tt.subject); got != tt.want {
+				t.Errorf("normalizeSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseContentDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	const body = "Vendor: Crafter has sold Rifle to Han for 1000 credits."
+	writeTestMail(t, dir, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800, body)
+	// The same mail restored from a backup under another mail ID
+	writeTestMail(t, dir, "2.mail", "1001", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800, body)
+	// Another sale of the same item at another time
+	writeTestMail(t, dir, "3.mail", "2", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705399200, body)
+
+	tests := []struct {
+		name              string
+		noDedupContent    bool
+		wantIDs           []string
+		duplicateMails    int
+		contentDuplicates int
+	}{
+		{"dedup content", false, []string{"1", "2"}, 1, 1},
+		{"no dedup content", true, []string{"1", "1001", "2"}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{NoDedupContent: tt.noDedupContent})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mailIDs(result.Mails); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("parsed mails %v, want %v", got, tt.wantIDs)
+			}
+			if result.DuplicateMails != tt.duplicateMails || result.ContentDuplicates != tt.contentDuplicates {
+				t.Errorf("DuplicateMails, ContentDuplicates = %d, %d, want %d, %d",
+					result.DuplicateMails, result.ContentDuplicates, tt.duplicateMails, tt.contentDuplicates)
+			}
+			if tt.contentDuplicates > 0 && (len(result.SkippedFiles) != 1 || !result.SkippedFiles[0].Duplicate ||
+				!strings.HasPrefix(result.SkippedFiles[0].Reason, "duplicate content")) {
+				t.Errorf("SkippedFiles = %+v, want the content duplicate", result.SkippedFiles)
+			}
+		})
+	}
+}
+
+func TestParseStrictValidationFailures(t *testing.T) {
+	dir := t.TempDir()
+	writeTestMail(t, dir, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.\nThe sale took place at Mos Eisley, on Tatooine.")
+	writeTestMail(t, dir, "2.mail", "2", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312801,
+		"Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+	writeTestMail(t, dir, "3.mail", "3", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312802,
+		"Your sale went through.")
+	writeTestMail(t, dir, "4.mail", "4", "Han Solo", "", 1705312803, "Thanks for the rifle!")
+
+	tests := []struct {
+		name   string
+		strict bool
+		want   []ValidationFailure
+	}{
+		{"lenient", false, nil},
+		{"strict", true, []ValidationFailure{
+			{MailID: "2", FieldName: "Location", Reason: "sale location not found in body"},
+			{MailID: "3", FieldName: "Location", Reason: "sale location not found in body"},
+			{MailID: "3", FieldName: "ItemName", Reason: "item name not found in body"},
+			{MailID: "3", FieldName: "Price", Reason: "price not found in body"},
+			{MailID: "4", FieldName: "Subject", Reason: "subject is empty"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{Strict: tt.strict})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Mails failing validation are kept
+			if len(result.Mails) != 4 {
+				t.Errorf("parsed %d mails, want 4", len(result.Mails))
+			}
+			if !slices.Equal(result.ValidationFailures, tt.want) {
+				t.Errorf("ValidationFailures = %+v, want %+v", result.ValidationFailures, tt.want)
+			}
+		})
+	}
+}
+
+// slowReadFile returns a ReadFile hook that sleeps for delay before
+// reading files whose name ends in slowSuffix
+func slowReadFile(slowSuffix string, delay time.Duration) func(string, int64) ([]byte, error) {
+	return func(filename string, maxSize int64) ([]byte, error) {
+		if strings.HasSuffix(filename, slowSuffix) {
+			time.Sleep(delay)
+		}
+		return readMailFile(filename, maxSize)
+	}
+}
+
+func TestParseMailFileCtxTimeout(t *testing.T) {
+	path := writeTestMail(t, t.TempDir(), "1.mail", "1", "Player", "Hello", 1700000000, "Body")
+
+	tests := []struct {
+		name    string
+		delay   time.Duration
+		timeout time.Duration
+		wantErr error
+	}{
+		{"fast parse", 0, time.Second, nil},
+		{"slow parse times out", 500 * time.Millisecond, 10 * time.Millisecond, context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ParseOptions{ReadFile: slowReadFile(".mail", tt.delay), ParseTimeout: tt.timeout}
+
+			start := time.Now()
+			mail, err := parseMailFileWithTimeout(context.Background(), path, opts)
+			if elapsed := time.Since(start); tt.wantErr != nil && elapsed >= tt.delay {
+				t.Errorf("parse returned after %s, want the timeout of %s to fire first", elapsed, tt.timeout)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseMailFileWithTimeout() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && mail.MailID != "1" {
+				t.Errorf("MailID = %q, want %q", mail.MailID, "1")
+			}
+		})
+	}
+}
+
+func TestParseMailFromDirectoryParseTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writeTestMail(t, dir, "1.mail", "1", "Player", "Hello", 1700000000, "Body")
+	slow := writeTestMail(t, dir, "2.mail", "2", "Player", "Huge", 1700000100, "Body")
+
+	opts := ParseOptions{ReadFile: slowReadFile("2.mail", 500*time.Millisecond), ParseTimeout: 10 * time.Millisecond}
+	result, err := parseMailFromDirectory(context.Background(), dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mailIDs(result.Mails); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("Mails = %v, want [1]", got)
+	}
+	if len(result.SkippedFiles) != 1 || result.SkippedFiles[0].File != slow || !strings.Contains(result.SkippedFiles[0].Reason, "timed out") {
+		t.Errorf("SkippedFiles = %+v, want %s timed out", result.SkippedFiles, slow)
+	}
+}
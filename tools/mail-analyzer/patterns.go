@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// serverPatterns describes the sale notifications of a server other than
+// SWG Restoration, loaded with --patterns-file, or of a localized client
+// (see localizedPatterns). The built-in patterns keep applying;
+// serverPatterns only add to them.
+type serverPatterns struct {
+	// AuctioneerSenders send the sale notifications
+	AuctioneerSenders []string
+
+	// SaleSubject matches the subject of sale notifications
+	SaleSubject *regexp.Regexp
+
+	// SaleBodies match sale bodies with the named groups item, buyer and
+	// price, and optionally vendor for vendor sales. The first match wins.
+	SaleBodies []*regexp.Regexp
+
+	// Location matches the sale location with the named groups city and planet
+	Location *regexp.Regexp
+}
+
+// serverPatternsFile is the JSON format of --patterns-file
+type serverPatternsFile struct {
+	AuctioneerSenders []string `json:"auctioneer_senders"`
+	SaleSubject       string   `json:"sale_subject"`
+	SaleBodies        []string `json:"sale_bodies"`
+	Location          string   `json:"location"`
+}
+
+// loadServerPatterns reads and compiles a patterns file
+func loadServerPatterns(path string) (*serverPatterns, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patterns file: %w", err)
+	}
+
+	var file serverPatternsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse patterns file: %w", err)
+	}
+
+	patterns := &serverPatterns{AuctioneerSenders: file.AuctioneerSenders}
+	if file.SaleSubject != "" {
+		if patterns.SaleSubject, err = regexp.Compile(file.SaleSubject); err != nil {
+			return nil, fmt.Errorf("invalid sale_subject pattern: %w", err)
+		}
+	}
+	for i, body := range file.SaleBodies {
+		pattern, err := compileNamedPattern(body, "item", "buyer", "price")
+		if err != nil {
+			return nil, fmt.Errorf("invalid sale_bodies pattern %d: %w", i+1, err)
+		}
+		patterns.SaleBodies = append(patterns.SaleBodies, pattern)
+	}
+	if file.Location != "" {
+		if patterns.Location, err = compileNamedPattern(file.Location, "city", "planet"); err != nil {
+			return nil, fmt.Errorf("invalid location pattern: %w", err)
+		}
+	}
+
+	return patterns, nil
+}
+
+// compileNamedPattern compiles a regular expression that must define the
+// given named capture groups
+func compileNamedPattern(expr string, groups ...string) (*regexp.Regexp, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if pattern.SubexpIndex(group) < 0 {
+			return nil, fmt.Errorf("missing named group (?P<%s>...)", group)
+		}
+	}
+	return pattern, nil
+}
+
+// namedGroup returns the trimmed text of a named group, or "" if the group
+// does not exist or did not participate in the match
+func namedGroup(pattern *regexp.Regexp, matches []string, name string) string {
+	index := pattern.SubexpIndex(name)
+	if index < 0 || index >= len(matches) {
+		return ""
+	}
+	return strings.TrimSpace(matches[index])
+}
+
+// thousandsSeparators removes the digit grouping of English, German and
+// French prices
+var thousandsSeparators = strings.NewReplacer(",", "", ".", "", " ", "", "\u00a0", "", "\u202f", "")
+
+// isSaleNotification reports whether a mail is a sale notification from
+// one of the configured auctioneers
+func (p *serverPatterns) isSaleNotification(mail *MailData) bool {
+	return slices.Contains(p.AuctioneerSenders, mail.Sender) &&
+		p.SaleSubject != nil && p.SaleSubject.MatchString(mail.Subject)
+}
+
+// apply extracts the sale details and location of a mail with the
+// configured patterns, overriding what the built-in patterns found
+func (p *serverPatterns) apply(mail *MailData) {
+	if p.isSaleNotification(mail) {
+		mail.MailCategory = CategorySale
+	}
+
+	for _, pattern := range p.SaleBodies {
+		matches := pattern.FindStringSubmatch(mail.Body)
+		if matches == nil {
+			continue
+		}
+
+		// Allow thousands separators in the price, e.g. "1,500", "1.500" or "1 500"
+		price, err := strconv.ParseInt(thousandsSeparators.Replace(namedGroup(pattern, matches, "price")), 10, 64)
+		if err != nil {
+			continue
+		}
+		mail.MailCategory = CategorySale
+		mail.ItemName = namedGroup(pattern, matches, "item")
+		mail.Buyer = namedGroup(pattern, matches, "buyer")
+		mail.Price = price
+		mail.VendorName = namedGroup(pattern, matches, "vendor")
+		mail.SaleType = SaleTypeBazaar
+		if mail.VendorName != "" {
+			mail.SaleType = SaleTypeVendor
+		}
+		break
+	}
+	if mail.MailCategory == CategorySale && mail.SaleType == "" {
+		mail.SaleType = SaleTypeUnknown
+	}
+
+	if p.Location != nil {
+		if matches := p.Location.FindStringSubmatch(mail.Body); matches != nil {
+			mail.City, mail.Planet = normalizeLocation(namedGroup(p.Location, matches, "city"), namedGroup(p.Location, matches, "planet"))
+			mail.Location = fmt.Sprintf("%s, %s", mail.City, mail.Planet)
+		}
+	}
+}
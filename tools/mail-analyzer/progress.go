@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// progressTracker counts the progress of a parse run. It is safe for
+// concurrent use, so it can be read from a signal handler while parsing.
+type progressTracker struct {
+	start time.Time
+
+	// files counts the mail files processed so far, of which parsed were
+	// parsed and skipped left out, see ParseResult.SkippedFiles; mails
+	// counts the mails kept
+	files   atomic.Int64
+	parsed  atomic.Int64
+	skipped atomic.Int64
+	mails   atomic.Int64
+
+	// total is the number of mail files to process, 0 while unknown
+	total atomic.Int64
+}
+
+// newProgressTracker returns a tracker whose throughput is measured from now
+func newProgressTracker() *progressTracker {
+	return &progressTracker{start: time.Now()}
+}
+
+// report writes the current progress with an estimate of the remaining
+// time, based on the throughput so far
+func (p *progressTracker) report(w io.Writer) {
+	files := p.files.Load()
+	parsed := p.parsed.Load()
+	skipped := p.skipped.Load()
+	mails := p.mails.Load()
+	total := p.total.Load()
+	elapsed := time.Since(p.start)
+
+	eta := "unknown"
+	if total > 0 && files > 0 {
+		remaining := time.Duration(float64(elapsed) / float64(files) * float64(max(total-files, 0)))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	totalText := "?"
+	if total > 0 {
+		totalText = fmt.Sprint(total)
+	}
+
+	fmt.Fprintf(w, "Progress: %d/%s files scanned, %d parsed, %d skipped, %d mails, %s elapsed, %s remaining\n",
+		files, totalText, parsed, skipped, mails, elapsed.Round(time.Second), eta)
+}
+
+// countMailFiles sets the tracker total to the number of .mail files below
+// dirs, or in them if they are archives or /mailsave dumps. Unreadable directories are
+// skipped, as in parseMailFromDirectories.
+func (p *progressTracker) countMailFiles(dirs ...string) {
+	var count int64
+	for _, dir := range dirs {
+		walkMailFiles(dir, runtime.NumCPU(), func(path string, entry fs.DirEntry, err error) error {
+			if err == nil {
+				count++
+			}
+			return nil
+		})
+
+		countEntry := func(string, []byte, time.Time) error {
+			count++
+			return nil
+		}
+		if isMailArchive(dir) {
+			walkMailArchive(dir, defaultMaxFileSize, countEntry)
+		} else if isMailsaveDump(dir) {
+			walkMailsaveDump(dir, countEntry)
+		}
+	}
+	p.total.Store(count)
+}
+
+// progressInterval is the time between two progress lines of a parse
+// without --quiet. Parses done in less time print none.
+const progressInterval = 5 * time.Second
+
+// reportProgress prints the progress to w, usually stderr, whenever one of
+// the progressSignals is received and, unless interval is 0, every
+// interval. Nothing is printed once the returned stop function returns.
+func reportProgress(p *progressTracker, interval time.Duration, w io.Writer) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	if signals := progressSignals(); len(signals) > 0 {
+		signal.Notify(ch, signals...)
+	}
+
+	// A nil channel never fires, so without interval only signals report
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ch:
+				p.report(w)
+			case <-tick:
+				p.report(w)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		if ticker != nil {
+			ticker.Stop()
+		}
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,14 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// progressSignals returns the signals that print the parse progress.
+// SIGINFO is sent by Ctrl+T on BSD terminals.
+func progressSignals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR1, syscall.SIGINFO}
+}
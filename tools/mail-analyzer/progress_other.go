@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// progressSignals returns no signals, as there is no SIGUSR1 on this platform
+func progressSignals() []os.Signal {
+	return nil
+}
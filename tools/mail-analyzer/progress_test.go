@@ -0,0 +1,82 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestReportProgressOnSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   int64
+		total   int64
+		mails   int64
+		want    string
+		wantEnd string
+	}{
+		{"total known", 3, 10, 2, "Progress: 3/10 files scanned, 3 parsed, 0 skipped, 2 mails", "remaining"},
+		{"total unknown", 5, 0, 4, "Progress: 5/? files scanned, 5 parsed, 0 skipped, 4 mails", "unknown remaining"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newProgressTracker()
+			tracker.files.Store(tt.files)
+			tracker.parsed.Store(tt.files)
+			tracker.mails.Store(tt.mails)
+			tracker.total.Store(tt.total)
+
+			var stderr syncBuffer
+			stop := reportProgress(tracker, 0, &stderr)
+			defer stop()
+
+			// Sent from another goroutine, as by kill -USR1 during a parse
+			go func() {
+				if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+					t.Errorf("failed to send SIGUSR1: %v", err)
+				}
+			}()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for !strings.Contains(stderr.String(), "\n") {
+				if time.Now().After(deadline) {
+					t.Fatal("no progress printed after SIGUSR1")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			got := stderr.String()
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("progress = %q, want prefix %q", got, tt.want)
+			}
+			if !strings.HasSuffix(got, tt.wantEnd+"\n") {
+				t.Errorf("progress = %q, want suffix %q", got, tt.wantEnd)
+			}
+		})
+	}
+}
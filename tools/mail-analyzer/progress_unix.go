@@ -0,0 +1,13 @@
+//go:build unix && !(darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// progressSignals returns the signals that print the parse progress
+func progressSignals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR1}
+}
@@ -0,0 +1,259 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=module=mail-analyzer mailbatch.proto
+
+import (
+	"bufio"
+	"io"
+	"time"
+
+	"mail-analyzer/mailbatchpb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protoMarshal marshals the messages of writeProto; map entries are sorted
+// by key so that the output is reproducible
+var protoMarshal = proto.MarshalOptions{Deterministic: true}
+
+// writeProto writes a batch as a MailBatch message of mailbatch.proto.
+// Serialized messages concatenate into their merge, so the batch is written
+// as one MailBatch per mail followed by one with the statistics, and large
+// batches are not held twice.
+func writeProto(w io.Writer, batch MailBatch) error {
+	buffered := bufio.NewWriter(w)
+	write := func(m *mailbatchpb.MailBatch) error {
+		data, err := protoMarshal.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = buffered.Write(data)
+		return err
+	}
+
+	if err := write(&mailbatchpb.MailBatch{SchemaVersion: CurrentSchemaVersion}); err != nil {
+		return err
+	}
+	for i := range batch.Mails {
+		if err := write(&mailbatchpb.MailBatch{Mails: []*mailbatchpb.MailData{protoMail(&batch.Mails[i])}}); err != nil {
+			return err
+		}
+	}
+
+	last := &mailbatchpb.MailBatch{Stats: protoStats(&batch.Stats)}
+	if merge := batch.Merge; merge != nil {
+		last.Merge = &mailbatchpb.MergeInfo{
+			Inputs:                   merge.Inputs,
+			DuplicateIdsRemoved:      int64(merge.DuplicateIDsRemoved),
+			ContentDuplicatesRemoved: int64(merge.ContentDuplicatesRemoved),
+		}
+	}
+	if err := write(last); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// protoMail returns a mail as a MailData message
+func protoMail(mail *MailData) *mailbatchpb.MailData {
+	m := &mailbatchpb.MailData{
+		MailId:            mail.MailID,
+		Sender:            mail.Sender,
+		Subject:           mail.Subject,
+		Timestamp:         protoTimestamp(mail.Timestamp),
+		Body:              mail.Body,
+		Location:          mail.Location,
+		City:              mail.City,
+		Planet:            mail.Planet,
+		Tags:              mail.Tags,
+		MailIdNormalized:  mail.MailIDNormalized,
+		ContentHash:       mail.ContentHash,
+		Galaxy:            mail.Galaxy,
+		Source:            mail.Source,
+		Recovered:         mail.Recovered,
+		Character:         mail.Character,
+		MailCategory:      mail.MailCategory,
+		MailType:          string(mail.MailType),
+		BroadcastName:     mail.BroadcastName,
+		SaleType:          mail.SaleType,
+		NormalizedSubject: mail.NormalizedSubject,
+		SenderDomain:      mail.SenderDomain,
+		SenderSubsystem:   mail.SenderSubsystem,
+		SenderLabel:       mail.SenderLabel,
+		ItemName:          mail.ItemName,
+		CanonicalItemName: mail.CanonicalItemName,
+		Buyer:             mail.Buyer,
+		Price:             mail.Price,
+		ItemKey:           mail.ItemKey,
+		ItemCategory:      mail.ItemCategory,
+		SerialNumber:      mail.SerialNumber,
+		UnitCount:         mail.UnitCount,
+		PricePerUnit:      mail.PricePerUnit,
+		VendorName:        mail.VendorName,
+		PriceType:         mail.PriceType,
+		HasCoordinates:    mail.HasCoordinates,
+		LocationX:         mail.LocationX,
+		LocationY:         mail.LocationY,
+		LocationZ:         mail.LocationZ,
+	}
+
+	if sale := mail.Sale; sale != nil {
+		m.Sale = &mailbatchpb.Sale{
+			ItemName:     sale.ItemName,
+			Buyer:        sale.Buyer,
+			Price:        sale.Price,
+			SaleChannel:  sale.SaleChannel,
+			VendorName:   sale.VendorName,
+			Category:     sale.Category,
+			SerialNumber: sale.SerialNumber,
+			UnitCount:    sale.UnitCount,
+			PricePerUnit: sale.PricePerUnit,
+		}
+	}
+	if purchase := mail.Purchase; purchase != nil {
+		m.Purchase = &mailbatchpb.Purchase{
+			ItemName: purchase.ItemName,
+			Seller:   purchase.Seller,
+			Price:    purchase.Price,
+			Location: purchase.Location,
+		}
+	}
+	if auction := mail.Auction; auction != nil {
+		m.Auction = &mailbatchpb.Auction{
+			Event:    auction.Event,
+			ItemName: auction.ItemName,
+			Bid:      auction.Bid,
+		}
+	}
+	if expired := mail.Expired; expired != nil {
+		m.Expired = &mailbatchpb.ExpiredItem{
+			ItemName: expired.ItemName,
+			Location: expired.Location,
+		}
+	}
+	if run := mail.FactoryRun; run != nil {
+		m.FactoryRun = &mailbatchpb.FactoryRun{
+			Factory:  run.Factory,
+			ItemName: run.ItemName,
+			ItemKey:  run.ItemKey,
+			Quantity: run.Quantity,
+		}
+	}
+	if income := mail.Income; income != nil {
+		m.Income = &mailbatchpb.Income{
+			Source:  income.Source,
+			Credits: income.Credits,
+		}
+	}
+	for _, survey := range mail.SurveyResults {
+		m.SurveyResults = append(m.SurveyResults, &mailbatchpb.SurveyResult{
+			Resource:      survey.Resource,
+			Planet:        survey.Planet,
+			Concentration: survey.Concentration,
+		})
+	}
+	for _, waypoint := range mail.Waypoints {
+		m.Waypoints = append(m.Waypoints, &mailbatchpb.Waypoint{
+			Planet: waypoint.Planet,
+			X:      waypoint.X,
+			Y:      waypoint.Y,
+			Z:      waypoint.Z,
+			Name:   waypoint.Name,
+		})
+	}
+	return m
+}
+
+// protoStats returns the totals and per-key counts of stats as a MailStats
+// message
+func protoStats(stats *MailStats) *mailbatchpb.MailStats {
+	m := &mailbatchpb.MailStats{
+		TotalMails:        int64(stats.TotalMails),
+		SaleNotifications: int64(stats.SaleNotifications),
+		DateRange: &mailbatchpb.DateRange{
+			StartDate: protoTimestamp(stats.DateRange.StartDate),
+			EndDate:   protoTimestamp(stats.DateRange.EndDate),
+		},
+		Senders:                      protoCounts(stats.Senders),
+		MailsBySubsystem:             protoCounts(stats.MailsBySubsystem),
+		SubjectClusters:              protoCounts(stats.SubjectClusters),
+		MailsByCategory:              protoCounts(stats.MailsByCategory),
+		MailsByType:                  protoCounts(stats.MailsByType),
+		TotalRevenue:                 stats.TotalRevenue,
+		KnownSystemMails:             int64(stats.KnownSystemMails),
+		UnknownSenderMails:           int64(stats.UnknownSenderMails),
+		VendorRevenue:                stats.VendorRevenue,
+		BazaarRevenue:                stats.BazaarRevenue,
+		VendorSaleCount:              int64(stats.VendorSaleCount),
+		BazaarSaleCount:              int64(stats.BazaarSaleCount),
+		VendorToBazaarRatio:          stats.VendorToBazaarRatio,
+		BidSaleCount:                 int64(stats.BidSaleCount),
+		BuyNowSaleCount:              int64(stats.BuyNowSaleCount),
+		AvgBidPrice:                  stats.AvgBidPrice,
+		AvgBuyNowPrice:               stats.AvgBuyNowPrice,
+		PurchaseCount:                int64(stats.PurchaseCount),
+		PurchaseSpending:             stats.PurchaseSpending,
+		OtherIncome:                  stats.OtherIncome,
+		OtherIncomeBySource:          protoCounts(stats.OtherIncomeBySource),
+		AuctionsWon:                  int64(stats.AuctionsWon),
+		AuctionsOutbid:               int64(stats.AuctionsOutbid),
+		AuctionWonSpending:           stats.AuctionWonSpending,
+		ExpiredCount:                 int64(stats.ExpiredCount),
+		FactoryRuns:                  int64(stats.FactoryRuns),
+		UnitsProducedByItem:          protoCounts(stats.UnitsProducedByItem),
+		SalesByItemCategory:          protoCounts(stats.SalesByItemCategory),
+		RevenueByItemCategory:        protoCounts(stats.RevenueByItemCategory),
+		GoalProgress:                 stats.GoalProgress,
+		AvgInterSaleIntervalHours:    stats.AvgInterSaleIntervalHours,
+		MedianInterSaleIntervalHours: stats.MedianInterSaleIntervalHours,
+		ItemDemandIndex:              stats.ItemDemandIndex,
+		MailCountByPlanet:            protoCounts(stats.MailCountByPlanet),
+		RevenueByPlanet:              protoCounts(stats.RevenueByPlanet),
+		MailCountByCity:              protoCounts(stats.MailCountByCity),
+		RevenueByCity:                protoCounts(stats.RevenueByCity),
+		MailsByGalaxy:                protoCounts(stats.MailsByGalaxy),
+		RevenueByGalaxy:              protoCounts(stats.RevenueByGalaxy),
+		MailsBySource:                protoCounts(stats.MailsBySource),
+		MailCountByCharacter:         protoCounts(stats.MailCountByCharacter),
+		RevenueByCharacter:           protoCounts(stats.RevenueByCharacter),
+		AnnouncementsCollapsed:       int64(stats.AnnouncementsCollapsed),
+		RetriedFiles:                 int64(stats.RetriedFiles),
+		RecoveredMails:               int64(stats.RecoveredMails),
+		DuplicateMails:               int64(stats.DuplicateMails),
+		SequentialGapCount:           int64(stats.SequentialGapCount),
+		MissingIdCount:               stats.MissingIDCount,
+		ContentDuplicateMails:        int64(stats.ContentDuplicateMails),
+	}
+	if len(stats.Vendors) > 0 {
+		m.Vendors = make(map[string]*mailbatchpb.VendorStats, len(stats.Vendors))
+		for name, vendor := range stats.Vendors {
+			m.Vendors[name] = &mailbatchpb.VendorStats{
+				MailCount:    int64(vendor.MailCount),
+				TotalCredits: vendor.TotalCredits,
+			}
+		}
+	}
+	return m
+}
+
+// protoCounts returns a map of counts or credits as a map<string, int64>
+func protoCounts[V int | int64](values map[string]V) map[string]int64 {
+	if len(values) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(values))
+	for key, value := range values {
+		counts[key] = int64(value)
+	}
+	return counts
+}
+
+// protoTimestamp returns t as a google.protobuf.Timestamp, or nil for the
+// zero time
+func protoTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
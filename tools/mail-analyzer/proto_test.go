@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"mail-analyzer/mailbatchpb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestWriteProtoRoundTrip(t *testing.T) {
+	batch := parseTestBatch(t)
+	batch.Merge = &MergeInfo{Inputs: []string{"first.json", "second.json"}, DuplicateIDsRemoved: 1}
+
+	var buf bytes.Buffer
+	if err := writeProto(&buf, batch); err != nil {
+		t.Fatal(err)
+	}
+	// The concatenated messages merge into a single MailBatch
+	var got mailbatchpb.MailBatch
+	if err := proto.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.GetSchemaVersion() != CurrentSchemaVersion {
+		t.Errorf("schema version = %d, want %d", got.GetSchemaVersion(), CurrentSchemaVersion)
+	}
+	if len(got.GetMails()) != len(batch.Mails) {
+		t.Fatalf("got %d mails, want %d", len(got.GetMails()), len(batch.Mails))
+	}
+	for i, m := range got.GetMails() {
+		want := &batch.Mails[i]
+		if m.GetMailId() != want.MailID || m.GetSender() != want.Sender || m.GetSubject() != want.Subject ||
+			!m.GetTimestamp().AsTime().Equal(want.Timestamp) || m.GetBody() != want.Body {
+			t.Errorf("mail %d = %s from %q at %v, want %s from %q at %v",
+				i, m.GetMailId(), m.GetSender(), m.GetTimestamp().AsTime(), want.MailID, want.Sender, want.Timestamp)
+		}
+		if (m.GetSale() != nil) != (want.Sale != nil) {
+			t.Errorf("mail %s has sale %v, want %v", want.MailID, m.GetSale(), want.Sale)
+		} else if want.Sale != nil && (m.GetSale().GetItemName() != want.Sale.ItemName ||
+			m.GetSale().GetBuyer() != want.Sale.Buyer || m.GetSale().GetPrice() != want.Sale.Price) {
+			t.Errorf("mail %s has sale %v, want %+v", want.MailID, m.GetSale(), *want.Sale)
+		}
+	}
+
+	stats := got.GetStats()
+	if stats.GetTotalMails() != int64(batch.Stats.TotalMails) || stats.GetTotalRevenue() != batch.Stats.TotalRevenue ||
+		stats.GetSaleNotifications() != int64(batch.Stats.SaleNotifications) {
+		t.Errorf("stats = %d mails, %d sales, revenue %d, want %d, %d, %d",
+			stats.GetTotalMails(), stats.GetSaleNotifications(), stats.GetTotalRevenue(),
+			batch.Stats.TotalMails, batch.Stats.SaleNotifications, batch.Stats.TotalRevenue)
+	}
+	if merge := got.GetMerge(); !slices.Equal(merge.GetInputs(), batch.Merge.Inputs) || merge.GetDuplicateIdsRemoved() != 1 {
+		t.Errorf("merge = %v, want %+v", merge, *batch.Merge)
+	}
+}
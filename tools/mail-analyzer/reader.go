@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readBatch decodes a batch in the given format: json, csv or ndjson.
+// Formats that only carry mails get freshly generated statistics.
+func readBatch(r io.Reader, format string) (*MailBatch, error) {
+	switch format {
+	case "json":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON batch: %w", err)
+		}
+		return MigrateBatch(data)
+	case "csv":
+		mails, err := readCSV(r)
+		if err != nil {
+			return nil, err
+		}
+		return &MailBatch{Mails: mails, Stats: generateMailStats(mails)}, nil
+	case "ndjson":
+		mails, err := readNDJSON(r)
+		if err != nil {
+			return nil, err
+		}
+		return &MailBatch{Mails: mails, Stats: generateMailStats(mails)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported input format %q, expected json, csv or ndjson", format)
+	}
+}
+
+// readCSV decodes mails written by writeCSV. Columns are matched by their
+// header name; unknown columns are ignored and missing ones stay empty.
+func readCSV(r io.Reader) ([]MailData, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make([]*mailColumn, len(header))
+	for i, name := range header {
+		for j := range mailColumns {
+			if mailColumns[j].Name == strings.TrimSpace(name) {
+				columns[i] = &mailColumns[j]
+				break
+			}
+		}
+	}
+
+	var mails []MailData
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		var mail MailData
+		for i, value := range record {
+			if i >= len(columns) || columns[i] == nil || value == "" {
+				continue
+			}
+			if err := columns[i].Set(&mail, value); err != nil {
+				return nil, fmt.Errorf("invalid %s on CSV line %d: %w", columns[i].Name, line, err)
+			}
+		}
+		// The sale details are not a column of their own
+		if isSaleNotification(&mail) {
+			mail.Sale = saleData(&mail)
+		}
+		mail.Purchase = parsePurchase(mail.Body)
+		mail.Auction = parseAuction(mail.Body)
+		mail.Expired = parseExpiredItem(mail.Body)
+		mail.FactoryRun = parseFactoryRun(mail.Body, defaultItemKeyRules)
+		mail.SurveyResults = parseSurveyResults(mail.Subject, mail.Body)
+		if mail.MailCategory == CategoryUnknown {
+			mail.Income = parseIncome(mail.Subject, mail.Body)
+		}
+		mail.Waypoints = parseWaypoints(mail.Body)
+		mails = append(mails, mail)
+	}
+
+	return mails, nil
+}
+
+// readNDJSON decodes one JSON encoded mail per line, skipping blank lines
+func readNDJSON(r io.Reader) ([]MailData, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, defaultScannerBufferSize), maxScannerBufferSize)
+
+	var mails []MailData
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var mail MailData
+		if err := json.Unmarshal([]byte(text), &mail); err != nil {
+			return nil, fmt.Errorf("failed to decode NDJSON line %d: %w", line, err)
+		}
+		mails = append(mails, mail)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+
+	return mails, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"testing"
+)
+
+// parseTestBatch parses a directory of varied fixture mails into a batch
+func parseTestBatch(t *testing.T) MailBatch {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestMail(t, dir, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.\nThe sale took place at Mos Eisley, on Tatooine.")
+	writeTestMail(t, dir, "2.mail", "2", "Han Solo", "Re: Rifle, \"thanks\"", 1705399200,
+		"Thanks for the rifle!\nMeet me at coordinates -1234.5 7 42 on Tatooine.")
+	writeTestMail(t, dir, "3.mail", "3", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705917600,
+		"Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+	writeTestMail(t, dir, "4.mail", "4", "Leia Organa", "Guild meeting", 1706004000, "See you at the hall,\nbring credits.")
+
+	result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return MailBatch{SchemaVersion: CurrentSchemaVersion, Mails: result.Mails, Stats: generateMailStats(result.Mails)}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	batch := parseTestBatch(t)
+	if batch.Stats.TotalRevenue != 1500 {
+		t.Fatalf("fixture TotalRevenue = %d, want 1500", batch.Stats.TotalRevenue)
+	}
+	want, err := json.Marshal(batch.Mails)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		formats []string
+	}{
+		{"json", []string{"json"}},
+		{"json to csv to json", []string{"json", "csv", "json"}},
+		{"json to ndjson to json", []string{"json", "ndjson", "json"}},
+		{"csv to ndjson to csv", []string{"csv", "ndjson", "csv"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := batch
+			for _, format := range tt.formats {
+				var buf bytes.Buffer
+				if err := writeBatch(&buf, current, format); err != nil {
+					t.Fatalf("writeBatch(%s) error = %v", format, err)
+				}
+				read, err := readBatch(&buf, format)
+				if err != nil {
+					t.Fatalf("readBatch(%s) error = %v", format, err)
+				}
+				current = *read
+			}
+
+			got, err := json.Marshal(current.Mails)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("mails after round trip differ:\ngot  %s\nwant %s", got, want)
+			}
+			if current.Stats.TotalRevenue != batch.Stats.TotalRevenue {
+				t.Errorf("TotalRevenue = %d, want %d", current.Stats.TotalRevenue, batch.Stats.TotalRevenue)
+			}
+		})
+	}
+}
+
+func TestReadBatchErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{"unknown format", "yaml", "mails: []"},
+		{"invalid JSON", "json", "{"},
+		{"invalid CSV timestamp", "csv", "mail_id,timestamp\n1,yesterday\n"},
+		{"invalid NDJSON", "ndjson", "{\"mail_id\": \"1\"}\nnot json\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readBatch(bytes.NewBufferString(tt.input), tt.format); err == nil {
+				t.Error("readBatch() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestPipeParseIntoFilter(t *testing.T) {
+	batch := parseTestBatch(t)
+
+	tests := []struct {
+		name        string
+		opts        FilterOpts
+		describe    bool
+		wantIDs     []string
+		wantRevenue int64
+	}{
+		{"sender", FilterOpts{SenderFilter: "auctioner"}, false, []string{"1", "3"}, 1500},
+		{"subject", FilterOpts{SubjectFilter: "Rifle"}, false, []string{"2"}, 0},
+		{"self-describing input", FilterOpts{SenderFilter: "auctioner"}, true, []string{"1", "3"}, 1500},
+		{"nothing matches", FilterOpts{SenderFilter: "Jabba"}, false, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// parse --output=- | filter --input=- --output=-
+			parseOut, filterIn := io.Pipe()
+			go func() {
+				filterIn.CloseWithError(writeBatchToWriter(filterIn, &batch, jsonOptions{KeyCase: KeyCaseSnake, SelfDescribing: tt.describe}))
+			}()
+
+			filterOut, resultIn := io.Pipe()
+			go func() {
+				input, err := readBatchFromReader(parseOut)
+				if err != nil {
+					resultIn.CloseWithError(err)
+					return
+				}
+				mails := ApplyFilters(input.Mails, tt.opts)
+				filtered := &MailBatch{Mails: mails, Stats: generateMailStats(mails)}
+				resultIn.CloseWithError(writeBatchToWriter(resultIn, filtered, jsonOptions{KeyCase: KeyCaseSnake}))
+			}()
+
+			result, err := readBatchFromReader(filterOut)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mailIDs(result.Mails); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("filtered mails = %v, want %v", got, tt.wantIDs)
+			}
+			if result.Stats.TotalRevenue != tt.wantRevenue {
+				t.Errorf("TotalRevenue = %d, want %d", result.Stats.TotalRevenue, tt.wantRevenue)
+			}
+		})
+	}
+}
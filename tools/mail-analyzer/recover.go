@@ -0,0 +1,101 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Mail IDs as written by the client or understood by normalizeMailID
+var recoverMailIDPattern = regexp.MustCompile(`^(?:\d+|0[xX][0-9a-fA-F]+|mail_\d+)$`)
+
+// Dotted system senders like "SWG.Restoration.auctioner"
+var recoverSystemSenderPattern = regexp.MustCompile(`^[\w-]+(?:\.[\w-]+)+$`)
+
+// plausibleSender reports whether a sender line could be a sender rather
+// than a mail ID or the TIMESTAMP line
+func plausibleSender(sender string) bool {
+	return sender != "" && !recoverMailIDPattern.MatchString(sender) && !strings.HasPrefix(sender, "TIMESTAMP:")
+}
+
+// recoverMailHeader locates the mail ID, sender, subject and TIMESTAMP line
+// anywhere in the first maxLines lines of a mail file whose header is
+// malformed, e.g. because the lines are shuffled or the TIMESTAMP line is
+// missing. Mails without a mail ID line fall back to the file name and
+// mails without a timestamp to modTime, the modification time of the
+// file, if known. The body
+// is everything after the last header line found. It reports false if no
+// sender and subject can be told apart.
+func recoverMailHeader(filename string, modTime time.Time, lines []string, maxLines int, timestampFormat string) (mailHeader, bool) {
+	window := lines[:min(maxLines, len(lines))]
+
+	var header mailHeader
+	used := make(map[int]bool)
+	last := -1
+	take := func(i int) string {
+		used[i] = true
+		last = max(last, i)
+		return strings.TrimSpace(window[i])
+	}
+
+	for i, line := range window {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "TIMESTAMP:")
+		if !ok {
+			continue
+		}
+		if timestamp, err := parseTimestamp(strings.TrimSpace(value), timestampFormat); err == nil {
+			take(i)
+			header.Timestamp = timestamp
+			break
+		}
+	}
+
+	for i, line := range window {
+		if !used[i] && recoverMailIDPattern.MatchString(strings.TrimSpace(line)) {
+			header.MailID = take(i)
+			break
+		}
+	}
+
+	// Prefer dotted system senders; otherwise the sender is assumed to
+	// come before the subject as in a well-formed header
+	senderIndex := -1
+	for i, line := range window {
+		line = strings.TrimSpace(line)
+		if used[i] || !plausibleSender(line) {
+			continue
+		}
+		if recoverSystemSenderPattern.MatchString(line) {
+			senderIndex = i
+			break
+		}
+		if senderIndex < 0 {
+			senderIndex = i
+		}
+	}
+	if senderIndex < 0 {
+		return mailHeader{}, false
+	}
+	header.Sender = take(senderIndex)
+
+	for i, line := range window {
+		if !used[i] && strings.TrimSpace(line) != "" {
+			header.Subject = take(i)
+			break
+		}
+	}
+	if header.Subject == "" {
+		return mailHeader{}, false
+	}
+
+	if header.MailID == "" {
+		header.MailID = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if header.Timestamp.IsZero() {
+		header.Timestamp = modTime.Truncate(time.Second)
+	}
+
+	header.Body = strings.Join(lines[last+1:], "\n")
+	return header, true
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultMarkdownTemplate renders a MailBatch as a Markdown summary
+const defaultMarkdownTemplate = `# SWG Mail Report
+
+Generated by mail-analyzer {{ .Version }} on {{ .GeneratedAt.Format "2006-01-02 15:04" }}
+
+## Statistics
+
+| Statistic | Value |
+| --- | --- |
+| Total mails | {{ .Stats.TotalMails }} |
+| Sale notifications | {{ .Stats.SaleNotifications }} |
+| Total revenue | {{ credits .Stats.TotalRevenue }} |
+| First mail | {{ .Stats.DateRange.StartDate.Format "2006-01-02" }} |
+| Last mail | {{ .Stats.DateRange.EndDate.Format "2006-01-02" }} |
+
+## Top Items
+
+| Item | Sales | Revenue |
+| --- | --- | --- |
+{{ range .Stats.TopItems }}| {{ .ItemName }} | {{ .SaleCount }} | {{ credits .Revenue }} |
+{{ end }}
+## Top Buyers
+
+| Buyer | Purchases | Revenue |
+| --- | --- | --- |
+{{ range .Stats.TopBuyers }}| {{ .Buyer }} | {{ .PurchaseCount }} | {{ credits .Revenue }} |
+{{ end }}
+## Revenue by Planet
+
+| Planet | Mails | Revenue |
+| --- | --- | --- |
+{{ range .Planets }}| {{ .Planet }} | {{ .MailCount }} | {{ credits .Revenue }} |
+{{ end }}`
+
+// reportFuncs are the functions available to Markdown report templates
+var reportFuncs = template.FuncMap{
+	"credits": FormatCredits,
+}
+
+// reportData is the data passed to the Markdown report template
+type reportData struct {
+	Version     string
+	GeneratedAt time.Time
+	Stats       MailStats
+	Planets     []planetRevenue
+}
+
+// planetRevenue represents the mails and revenue of a single planet
+type planetRevenue struct {
+	Planet    string
+	MailCount int
+	Revenue   int64
+}
+
+// outputFileBase returns outputFile without its extension and that of its
+// compression, e.g. "sales" for "sales.json.gz", for the files written next
+// to it
+func outputFileBase(outputFile string) string {
+	path := trimCompressionExt(outputFile)
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// markdownReportPath derives the report path from the JSON output path,
+// e.g. "mail_data.json" becomes "mail_data_report.md"
+func markdownReportPath(outputFile string) string {
+	return outputFileBase(outputFile) + "_report.md"
+}
+
+// errorsReportPath returns the path of the errors report written next to
+// outputFile, e.g. "sales_errors.json" for "sales.json"
+func errorsReportPath(outputFile string) string {
+	return outputFileBase(outputFile) + "_errors.json"
+}
+
+// statsReportPath returns the path of the statistics written next to a
+// streamed or CSV outputFile, e.g. "sales_stats.json" for "sales.ndjson"
+func statsReportPath(outputFile string) string {
+	return outputFileBase(outputFile) + "_stats.json"
+}
+
+// salesCSVPath returns the path of the sales written next to the CSV
+// outputFile, e.g. "mail_data_sales.csv" for "mail_data.csv"
+func salesCSVPath(outputFile string) string {
+	return outputFileBase(outputFile) + "_sales.csv"
+}
+
+// writeStatsReport writes the statistics of a streamed or CSV parse to path
+func writeStatsReport(path string, stats MailStats) error {
+	jsonData, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal statistics: %w", err)
+	}
+	if err := writeFileAtomic(path, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write statistics: %w", err)
+	}
+	return nil
+}
+
+// reportSkippedFiles lists the files skipped by a lenient parse in the
+// errors report next to outputFile, so the data can be fixed
+func reportSkippedFiles(status io.Writer, outputFile string, skipped []SkippedFile) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+	if outputFile == "-" {
+		fmt.Fprintf(status, "Skipped %d mail files, write to a file for the errors report\n", len(skipped))
+		return nil
+	}
+
+	errorsFile := errorsReportPath(outputFile)
+	if err := writeErrorsReport(errorsFile, skipped); err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "Skipped %d mail files, see %s\n", len(skipped), errorsFile)
+	return nil
+}
+
+// writeErrorsReport writes the files skipped by a lenient parse as a JSON
+// array to path
+func writeErrorsReport(path string, skipped []SkippedFile) error {
+	jsonData, err := json.MarshalIndent(skipped, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal errors report: %w", err)
+	}
+	if err := writeFileAtomic(path, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write errors report: %w", err)
+	}
+	return nil
+}
+
+// loadMarkdownTemplate returns the template at path, or the built-in
+// template if path is empty
+func loadMarkdownTemplate(path string) (*template.Template, error) {
+	text := defaultMarkdownTemplate
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read markdown template: %w", err)
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New("report").Funcs(reportFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse markdown template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderMarkdownReport renders a Markdown summary of the batch
+func renderMarkdownReport(w io.Writer, tmpl *template.Template, batch MailBatch, version string) error {
+	data := reportData{
+		Version:     version,
+		GeneratedAt: time.Now(),
+		Stats:       batch.Stats,
+	}
+
+	for planet, revenue := range batch.Stats.RevenueByPlanet {
+		data.Planets = append(data.Planets, planetRevenue{
+			Planet:    planet,
+			MailCount: batch.Stats.MailCountByPlanet[planet],
+			Revenue:   revenue,
+		})
+	}
+	sort.Slice(data.Planets, func(i, j int) bool {
+		if data.Planets[i].Revenue != data.Planets[j].Revenue {
+			return data.Planets[i].Revenue > data.Planets[j].Revenue
+		}
+		return data.Planets[i].Planet < data.Planets[j].Planet
+	})
+
+	return tmpl.Execute(w, data)
+}
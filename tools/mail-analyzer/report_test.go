@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdownReport(t *testing.T) {
+	mails := []MailData{
+		testSale("1", date(2024, time.January, 15), "Rifle", "Han", 1000),
+		testSale("2", date(2024, time.January, 16), "Rifle", "Leia", 2000),
+		testSale("3", date(2024, time.January, 17), "Pistol", "Han", 500),
+	}
+	mails[0].Planet, mails[1].Planet, mails[2].Planet = "Tatooine", "Naboo", "Tatooine"
+	batch := MailBatch{Mails: mails, Stats: generateMailStats(mails)}
+
+	customTemplate := filepath.Join(t.TempDir(), "custom.tmpl")
+	if err := os.WriteFile(customTemplate, []byte("{{ .Version }}: {{ .Stats.TotalMails }} mails, {{ credits .Stats.TotalRevenue }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{
+			name: "built-in template",
+			want: []string{
+				"# SWG Mail Report",
+				"Generated by mail-analyzer 1.2.3 on ",
+				"| Statistic | Value |",
+				"| Total mails | 3 |",
+				"| First mail | 2024-01-15 |",
+				"| Item | Sales | Revenue |",
+				"| Rifle | 2 | 3,000 cr |",
+				"| Buyer | Purchases | Revenue |",
+				"| Han | 2 | 1,500 cr |",
+				"| Planet | Mails | Revenue |",
+				"| Naboo | 1 | 2,000 cr |\n| Tatooine | 2 | 1,500 cr |",
+			},
+		},
+		{
+			name:     "custom template",
+			template: customTemplate,
+			want:     []string{"1.2.3: 3 mails, 3,500 cr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := loadMarkdownTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := renderMarkdownReport(&buf, tmpl, batch, "1.2.3"); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("report does not contain %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMarkdownTemplateErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.tmpl")
+	if err := os.WriteFile(invalid, []byte("{{ .Stats"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing file", filepath.Join(dir, "missing.tmpl")},
+		{"invalid template", invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadMarkdownTemplate(tt.path); err == nil {
+				t.Error("loadMarkdownTemplate() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// batchServer serves a mail batch over HTTP. The batch is loaded in the
+// background, so the server can accept requests right away and answers
+// 503 Service Unavailable until loading is complete.
+type batchServer struct {
+	mu      sync.RWMutex
+	batch   *MailBatch
+	loadErr error
+}
+
+// load reads the batch file and swaps it in once it is fully parsed
+func (s *batchServer) load(path string) {
+	batch, err := readBatchFile(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.loadErr = err
+		slog.Error("failed to load batch", "input", path, "error", err)
+		return
+	}
+	s.batch = batch
+	slog.Info("batch loaded", "input", path, "mails", len(batch.Mails))
+}
+
+// set swaps in a batch produced in memory, e.g. by a scan of the daemon
+func (s *batchServer) set(batch *MailBatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch = batch
+	s.loadErr = nil
+}
+
+// current returns the loaded batch, or nil and the reason it is unavailable
+func (s *batchServer) current() (*MailBatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.batch == nil {
+		if s.loadErr != nil {
+			return nil, fmt.Errorf("failed to load batch: %w", s.loadErr)
+		}
+		return nil, fmt.Errorf("batch is still loading")
+	}
+	return s.batch, nil
+}
+
+// listen serves the routes of the server on addr in the background, for
+// commands serving the batch they build. Serving errors are sent on the
+// returned channel.
+func (s *batchServer) listen(addr string) (*http.Server, <-chan error) {
+	httpServer := &http.Server{Addr: addr, Handler: s.handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+	slog.Info("listening", "addr", addr)
+	return httpServer, serveErr
+}
+
+// handler returns the HTTP routes of the server
+func (s *batchServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := s.current(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /mails", func(w http.ResponseWriter, r *http.Request) {
+		s.serveBatch(w, func(b *MailBatch) any { return b.Mails })
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		s.serveBatch(w, func(b *MailBatch) any { return b.Stats })
+	})
+	return mux
+}
+
+// serveBatch writes part of the loaded batch as JSON, or 503 while it is
+// unavailable
+func (s *batchServer) serveBatch(w http.ResponseWriter, part func(*MailBatch) any) {
+	batch, err := s.current()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(part(batch)); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchServerConcurrentReads(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		check func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name: "mails",
+			path: "/mails",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var mails []MailData
+				if err := json.Unmarshal(rec.Body.Bytes(), &mails); err != nil {
+					t.Errorf("GET /mails returned invalid JSON: %v", err)
+				}
+			},
+		},
+		{
+			name: "stats",
+			path: "/stats",
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var stats MailStats
+				if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+					t.Errorf("GET /stats returned invalid JSON: %v", err)
+				}
+			},
+		},
+		{"ready", "/ready", func(t *testing.T, rec *httptest.ResponseRecorder) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &batchServer{}
+			server.set(&MailBatch{Stats: generateMailStats(nil)})
+			handler := server.handler()
+
+			// Swap in growing batches, as watch does for every poll with new
+			// mails, while the readers below request them
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var mails []MailData
+				for i := range 200 {
+					mails = append(mails, MailData{MailID: strconv.Itoa(i + 1)})
+					server.set(&MailBatch{Mails: mails, Stats: generateMailStats(mails)})
+				}
+			}()
+
+			for range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 50 {
+						batch, err := server.current()
+						if err != nil {
+							t.Errorf("current() error = %v", err)
+							return
+						}
+						if batch.Stats.TotalMails != len(batch.Mails) {
+							t.Errorf("batch has %d mails, its stats count %d", len(batch.Mails), batch.Stats.TotalMails)
+						}
+
+						rec := httptest.NewRecorder()
+						handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+						if rec.Code != http.StatusOK {
+							t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, http.StatusOK)
+							return
+						}
+						tt.check(t, rec)
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestBatchServerLazyLoad(t *testing.T) {
+	batch := parseTestBatch(t)
+	dir := t.TempDir()
+	batchPath := filepath.Join(dir, "mail_data.json")
+	file, err := os.Create(batchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBatchToWriter(file, &batch, jsonOptions{KeyCase: KeyCaseSnake}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	tests := []struct {
+		name      string
+		input     string
+		wantReady int
+		wantMails int
+	}{
+		{"batch loads", batchPath, http.StatusOK, len(batch.Mails)},
+		{"missing batch", filepath.Join(dir, "missing.json"), http.StatusServiceUnavailable, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &batchServer{}
+			ts := httptest.NewServer(server.handler())
+			defer ts.Close()
+
+			get := func(path string) *http.Response {
+				t.Helper()
+				resp, err := http.Get(ts.URL + path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { resp.Body.Close() })
+				return resp
+			}
+
+			// The server answers before loading has started
+			for _, path := range []string{"/ready", "/mails", "/stats"} {
+				if resp := get(path); resp.StatusCode != http.StatusServiceUnavailable {
+					t.Errorf("GET %s before loading = %d, want %d", path, resp.StatusCode, http.StatusServiceUnavailable)
+				}
+			}
+
+			done := make(chan struct{})
+			go func() {
+				server.load(tt.input)
+				close(done)
+			}()
+
+			// Poll /ready like a readiness probe
+			deadline := time.Now().Add(5 * time.Second)
+			for get("/ready").StatusCode != tt.wantReady {
+				if time.Now().After(deadline) {
+					t.Fatalf("GET /ready did not return %d in time", tt.wantReady)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			<-done
+
+			resp := get("/mails")
+			if tt.wantReady != http.StatusOK {
+				if resp.StatusCode != http.StatusServiceUnavailable {
+					t.Errorf("GET /mails = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+				}
+				return
+			}
+			var mails []MailData
+			if err := json.NewDecoder(resp.Body).Decode(&mails); err != nil {
+				t.Fatal(err)
+			}
+			if len(mails) != tt.wantMails {
+				t.Errorf("GET /mails returned %d mails, want %d", len(mails), tt.wantMails)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteColumn is a column of the mails or sales table of the SQLite
+// output with its declared type and its value for a mail: nil for NULL,
+// int64, float64 or string
+type sqliteColumn struct {
+	Name  string
+	Type  string
+	Value func(m *MailData) any
+}
+
+// sqliteMailColumns lists the columns of the mails table after its id.
+// Fields of other mail types, such as purchases, are left out.
+var sqliteMailColumns = []sqliteColumn{
+	sqliteTextColumn("mail_id", func(m *MailData) string { return m.MailID }),
+	sqliteTextColumn("mail_id_normalized", func(m *MailData) string { return m.MailIDNormalized }),
+	sqliteTextColumn("content_hash", func(m *MailData) string { return m.ContentHash }),
+	{"timestamp", "TEXT", func(m *MailData) any { return sqliteTime(m.Timestamp) }},
+	sqliteTextColumn("sender", func(m *MailData) string { return m.Sender }),
+	sqliteTextColumn("sender_domain", func(m *MailData) string { return m.SenderDomain }),
+	sqliteTextColumn("sender_subsystem", func(m *MailData) string { return m.SenderSubsystem }),
+	sqliteTextColumn("sender_label", func(m *MailData) string { return m.SenderLabel }),
+	sqliteTextColumn("subject", func(m *MailData) string { return m.Subject }),
+	sqliteTextColumn("normalized_subject", func(m *MailData) string { return m.NormalizedSubject }),
+	sqliteTextColumn("body", func(m *MailData) string { return m.Body }),
+	sqliteTextColumn("mail_category", func(m *MailData) string { return m.MailCategory }),
+	sqliteTextColumn("mail_type", func(m *MailData) string { return string(m.MailType) }),
+	sqliteTextColumn("broadcast_name", func(m *MailData) string { return m.BroadcastName }),
+	sqliteTextColumn("location", func(m *MailData) string { return m.Location }),
+	sqliteTextColumn("city", func(m *MailData) string { return m.City }),
+	sqliteTextColumn("planet", func(m *MailData) string { return m.Planet }),
+	sqliteTextColumn("galaxy", func(m *MailData) string { return m.Galaxy }),
+	sqliteTextColumn("character", func(m *MailData) string { return m.Character }),
+	sqliteTextColumn("source", func(m *MailData) string { return m.Source }),
+	{"recovered", "INTEGER", func(m *MailData) any { return sqliteBool(m.Recovered) }},
+}
+
+// sqliteSaleColumns lists the columns of the sales table after its id,
+// which is that of the mail
+var sqliteSaleColumns = []sqliteColumn{
+	sqliteTextColumn("item_name", func(m *MailData) string { return m.Sale.ItemName }),
+	sqliteTextColumn("canonical_item_name", func(m *MailData) string { return m.CanonicalItemName }),
+	sqliteTextColumn("item_key", func(m *MailData) string { return m.ItemKey }),
+	sqliteTextColumn("buyer", func(m *MailData) string { return m.Sale.Buyer }),
+	{"price", "INTEGER", func(m *MailData) any { return m.Sale.Price }},
+	sqliteTextColumn("price_type", func(m *MailData) string { return m.PriceType }),
+	sqliteTextColumn("sale_channel", func(m *MailData) string { return m.Sale.SaleChannel }),
+	sqliteTextColumn("vendor_name", func(m *MailData) string { return m.Sale.VendorName }),
+	sqliteTextColumn("category", func(m *MailData) string { return m.Sale.Category }),
+	sqliteTextColumn("serial_number", func(m *MailData) string { return m.Sale.SerialNumber }),
+	{"unit_count", "INTEGER", func(m *MailData) any {
+		if m.Sale.UnitCount == 0 {
+			return nil
+		}
+		return m.Sale.UnitCount
+	}},
+	{"price_per_unit", "REAL", func(m *MailData) any {
+		if m.Sale.PricePerUnit == 0 {
+			return nil
+		}
+		return m.Sale.PricePerUnit
+	}},
+}
+
+// sqliteTextColumn returns a TEXT column; empty strings are stored as NULL
+func sqliteTextColumn(name string, field func(m *MailData) string) sqliteColumn {
+	return sqliteColumn{
+		Name: name,
+		Type: "TEXT",
+		Value: func(m *MailData) any {
+			if value := field(m); value != "" {
+				return value
+			}
+			return nil
+		},
+	}
+}
+
+// sqliteTime returns t in UTC in the format of the SQLite date and time
+// functions, which sorts chronologically, or nil for the zero time
+func sqliteTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.DateTime)
+}
+
+func sqliteBool(v bool) int64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// sqliteTable is a table of the SQLite output with its column definitions,
+// the columns it is indexed on, and its rows
+type sqliteTable struct {
+	Name    string
+	Columns []string
+	Indexed []string
+	Rows    func(insert func(values ...any) error) error
+}
+
+// writeSQLite writes a batch as a SQLite database with the tables mails,
+// sales (one row per sale notification, with the id of its mail),
+// mail_tags, stats (the totals) and stats_by_key (the counts and credits
+// by key), with indexes on the mail timestamp and the item name and
+// buyer of sales.
+//
+// SQLite can only write databases to files, so the database is built in
+// a temporary file and then copied to w.
+func writeSQLite(w io.Writer, batch MailBatch) error {
+	tmp, err := os.CreateTemp("", "mail-analyzer-*.db")
+	if err != nil {
+		return err
+	}
+	path := tmp.Name()
+	tmp.Close()
+	defer os.Remove(path)
+	defer os.Remove(path + "-journal")
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	if err := writeSQLiteTables(db, sqliteTables(&batch)); err != nil {
+		db.Close()
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// sqliteTables returns the tables of the SQLite output of batch
+func sqliteTables(batch *MailBatch) []sqliteTable {
+	mails := batch.Mails
+	totals, byKey := sqliteStats(&batch.Stats)
+	return []sqliteTable{
+		{
+			Name:    "mails",
+			Columns: sqliteColumnDefinitions("id INTEGER PRIMARY KEY", sqliteMailColumns),
+			Indexed: []string{"timestamp"},
+			Rows: func(insert func(values ...any) error) error {
+				values := make([]any, len(sqliteMailColumns)+1)
+				for i := range mails {
+					values[0] = int64(i + 1)
+					for j, column := range sqliteMailColumns {
+						values[j+1] = column.Value(&mails[i])
+					}
+					if err := insert(values...); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "sales",
+			Columns: sqliteColumnDefinitions("id INTEGER PRIMARY KEY REFERENCES mails (id)", sqliteSaleColumns),
+			Indexed: []string{"item_name", "buyer"},
+			Rows: func(insert func(values ...any) error) error {
+				values := make([]any, len(sqliteSaleColumns)+1)
+				for i := range mails {
+					if mails[i].Sale == nil {
+						continue
+					}
+					values[0] = int64(i + 1)
+					for j, column := range sqliteSaleColumns {
+						values[j+1] = column.Value(&mails[i])
+					}
+					if err := insert(values...); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "mail_tags",
+			Columns: []string{"mail INTEGER NOT NULL REFERENCES mails (id)", "tag TEXT NOT NULL"},
+			Rows: func(insert func(values ...any) error) error {
+				for i := range mails {
+					for _, tag := range mails[i].Tags {
+						if err := insert(int64(i+1), tag); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "stats",
+			Columns: []string{"name TEXT NOT NULL", "value"},
+			Rows:    sqliteRows(totals),
+		},
+		{
+			Name:    "stats_by_key",
+			Columns: []string{"stat TEXT NOT NULL", "name TEXT NOT NULL", "value"},
+			Rows:    sqliteRows(byKey),
+		},
+	}
+}
+
+// sqliteRows returns the rows of a table with the given values
+func sqliteRows(rows [][]any) func(insert func(values ...any) error) error {
+	return func(insert func(values ...any) error) error {
+		for _, row := range rows {
+			if err := insert(row...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// writeSQLiteTables creates tables in db and inserts their rows in a single
+// transaction. The indexes are created after the rows are inserted, which
+// is faster than updating them row by row.
+func writeSQLiteTables(db *sql.DB, tables []sqliteTable) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if _, err := tx.Exec("CREATE TABLE " + table.Name + " (\n  " + strings.Join(table.Columns, ",\n  ") + "\n)"); err != nil {
+			return fmt.Errorf("failed to create SQLite table %s: %w", table.Name, err)
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(table.Columns)), ", ")
+		stmt, err := tx.Prepare("INSERT INTO " + table.Name + " VALUES (" + placeholders + ")")
+		if err != nil {
+			return err
+		}
+		err = table.Rows(func(values ...any) error {
+			_, err := stmt.Exec(values...)
+			return err
+		})
+		stmt.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write SQLite table %s: %w", table.Name, err)
+		}
+
+		for _, column := range table.Indexed {
+			indexName := table.Name + "_" + column
+			if _, err := tx.Exec("CREATE INDEX " + indexName + " ON " + table.Name + " (" + column + ")"); err != nil {
+				return fmt.Errorf("failed to create SQLite index %s: %w", indexName, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// sqliteColumnDefinitions returns the column definitions of a table with
+// the id column id followed by columns
+func sqliteColumnDefinitions(id string, columns []sqliteColumn) []string {
+	definitions := []string{id}
+	for _, column := range columns {
+		definitions = append(definitions, column.Name+" "+column.Type)
+	}
+	return definitions
+}
+
+// sqliteStats returns the rows of the stats table, the totals of stats,
+// and of the stats_by_key table, its maps of counts, credits and ratios
+// by key. The date range is stored as the totals start_date and end_date;
+// the lists and nested statistics are left out.
+func sqliteStats(stats *MailStats) (totals [][]any, byKey [][]any) {
+	totals = append(totals,
+		[]any{"start_date", sqliteTime(stats.DateRange.StartDate)},
+		[]any{"end_date", sqliteTime(stats.DateRange.EndDate)})
+
+	v := reflect.ValueOf(stats).Elem()
+	for i := range v.NumField() {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Int, reflect.Int64:
+			totals = append(totals, []any{name, field.Int()})
+		case reflect.Float64:
+			totals = append(totals, []any{name, field.Float()})
+		case reflect.Map:
+			var value func(v reflect.Value) any
+			switch field.Type().Elem().Kind() {
+			case reflect.Int, reflect.Int64:
+				value = func(v reflect.Value) any { return v.Int() }
+			case reflect.Float64:
+				value = func(v reflect.Value) any { return v.Float() }
+			default:
+				continue
+			}
+			values := make(map[string]any, field.Len())
+			for iter := field.MapRange(); iter.Next(); {
+				values[iter.Key().String()] = value(iter.Value())
+			}
+			for _, key := range slices.Sorted(maps.Keys(values)) {
+				byKey = append(byKey, []any{name, key, values[key]})
+			}
+		}
+	}
+	return totals, byKey
+}
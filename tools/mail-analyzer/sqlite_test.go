@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	batch := parseTestBatch(t)
+	path := filepath.Join(t.TempDir(), "mail_data.db")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSQLite(file, batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	counts := []struct {
+		table string
+		want  int
+	}{
+		{"mails", 4},
+		{"sales", 2},
+	}
+	for _, tt := range counts {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + tt.table).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != tt.want {
+			t.Errorf("%s has %d rows, want %d", tt.table, count, tt.want)
+		}
+	}
+
+	var revenue int64
+	if err := db.QueryRow("SELECT SUM(price) FROM sales").Scan(&revenue); err != nil {
+		t.Fatal(err)
+	}
+	if revenue != 1500 {
+		t.Errorf("sum of sales prices = %d, want 1500", revenue)
+	}
+
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'index' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var indexes []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		indexes = append(indexes, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"mails_timestamp", "sales_buyer", "sales_item_name"} {
+		if !slices.Contains(indexes, want) {
+			t.Errorf("indexes = %v, missing %s", indexes, want)
+		}
+	}
+}
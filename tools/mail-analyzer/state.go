@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// stateFileVersion is the format version of the files written by
+// parse --state-file
+const stateFileVersion = 1
+
+// parseState records the input files and mails processed by earlier runs of
+// parse --state-file, so that later runs skip unchanged files and only emit
+// new mails
+type parseState struct {
+	files         map[string]stateFile
+	mailIDs       map[string]bool
+	contentHashes map[string]bool
+}
+
+// stateFile identifies the version of an input file that was processed
+type stateFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// equal reports whether two versions of a file have the same size and
+// modification time
+func (f stateFile) equal(other stateFile) bool {
+	return f.Size == other.Size && f.ModTime.Equal(other.ModTime)
+}
+
+// stateFileContent is the JSON layout of a state file
+type stateFileContent struct {
+	Version       int                  `json:"version"`
+	Files         map[string]stateFile `json:"files"`
+	MailIDs       []string             `json:"mail_ids"`
+	ContentHashes []string             `json:"content_hashes"`
+}
+
+// newParseState returns a state without any processed files or mails
+func newParseState() *parseState {
+	return &parseState{
+		files:         make(map[string]stateFile),
+		mailIDs:       make(map[string]bool),
+		contentHashes: make(map[string]bool),
+	}
+}
+
+// loadParseState reads a state file, returning an empty state if the file
+// does not exist
+func loadParseState(path string) (*parseState, error) {
+	state := newParseState()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var content stateFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if content.Version > stateFileVersion {
+		return nil, fmt.Errorf("state file %s has version %d, this version of mail-analyzer supports up to %d", path, content.Version, stateFileVersion)
+	}
+
+	for name, file := range content.Files {
+		state.files[name] = file
+	}
+	for _, id := range content.MailIDs {
+		state.mailIDs[id] = true
+	}
+	for _, hash := range content.ContentHashes {
+		state.contentHashes[hash] = true
+	}
+	return state, nil
+}
+
+// save writes the state to path, replacing the previous state file
+func (s *parseState) save(path string) error {
+	content := stateFileContent{
+		Version:       stateFileVersion,
+		Files:         s.files,
+		MailIDs:       sortedKeys(s.mailIDs),
+		ContentHashes: sortedKeys(s.contentHashes),
+	}
+
+	jsonData, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state file: %w", err)
+	}
+	if err := writeFileAtomic(path, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// unchanged reports whether the input file at path was processed by an
+// earlier run and has the same size and modification time as back then
+func (s *parseState) unchanged(path string, info os.FileInfo) bool {
+	file, ok := s.files[stateKey(path)]
+	return ok && file.equal(stateFile{Size: info.Size(), ModTime: info.ModTime()})
+}
+
+// recordFile marks the input file at path as processed
+func (s *parseState) recordFile(path string, info os.FileInfo) {
+	s.files[stateKey(path)] = stateFile{Size: info.Size(), ModTime: info.ModTime()}
+}
+
+// seen reports whether a mail with the same ID, or with byContent the same
+// content, was processed before
+func (s *parseState) seen(mail MailData, byContent bool) bool {
+	return s.mailIDs[dedupID(mail)] || (byContent && mail.ContentHash != "" && s.contentHashes[mail.ContentHash])
+}
+
+// recordMail marks a mail as processed
+func (s *parseState) recordMail(mail MailData) {
+	s.mailIDs[dedupID(mail)] = true
+	if mail.ContentHash != "" {
+		s.contentHashes[mail.ContentHash] = true
+	}
+}
+
+// stateKey returns the key of an input file in the state, its absolute path
+// so that runs from different working directories agree
+func stateKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIncrementalState(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	writeTestMail(t, dir, "1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	second := writeTestMail(t, dir, "2.mail", "2", "Han Solo", "Re: Rifle", 1705399200, "Thanks for the rifle!")
+	// Files that fail to parse are not recorded and retried next run
+	broken := filepath.Join(dir, "5.mail")
+	if err := os.WriteFile(broken, []byte("not a mail"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func() *ParseResult {
+		t.Helper()
+		state, err := loadParseState(statePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := parseMailFromDirectory(context.Background(), dir, ParseOptions{State: state})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := state.save(statePath); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := parse()
+	if got, want := mailIDs(result.Mails), []string{"1", "2"}; !slices.Equal(got, want) {
+		t.Fatalf("first run parsed %v, want %v", got, want)
+	}
+	if result.UnchangedFiles != 0 || result.KnownMails != 0 {
+		t.Errorf("first run skipped %d unchanged files and %d known mails, want none", result.UnchangedFiles, result.KnownMails)
+	}
+
+	// A new mail, a touched file of a known mail, a new file reusing a known
+	// mail ID and the broken file fixed
+	writeTestMail(t, dir, "3.mail", "3", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705917600,
+		"Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(second, later, later); err != nil {
+		t.Fatal(err)
+	}
+	writeTestMail(t, dir, "copy/1.mail", "1", "SWG.Restoration.auctioner", "Vendor Sale Complete", 1705312800,
+		"Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	writeTestMail(t, dir, "5.mail", "5", "Leia Organa", "Guild meeting", 1706004000, "See you at the hall.")
+
+	result = parse()
+	if got, want := mailIDs(result.Mails), []string{"3", "5"}; !slices.Equal(got, want) {
+		t.Errorf("second run parsed %v, want %v", got, want)
+	}
+	if result.UnchangedFiles != 1 || result.KnownMails != 2 {
+		t.Errorf("second run skipped %d unchanged files and %d known mails, want 1 and 2", result.UnchangedFiles, result.KnownMails)
+	}
+
+	result = parse()
+	if len(result.Mails) != 0 || result.UnchangedFiles != 5 {
+		t.Errorf("third run parsed %v and skipped %d unchanged files, want none and 5", mailIDs(result.Mails), result.UnchangedFiles)
+	}
+}
+
+func TestLoadParseState(t *testing.T) {
+	dir := t.TempDir()
+
+	state, err := loadParseState(filepath.Join(dir, "missing.json"))
+	if err != nil || len(state.files) != 0 || len(state.mailIDs) != 0 {
+		t.Errorf("loadParseState() of a missing file = %+v, %v, want an empty state", state, err)
+	}
+
+	newer := filepath.Join(dir, "newer.json")
+	if err := os.WriteFile(newer, []byte(`{"version": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadParseState(newer); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("loadParseState() of a newer state file error = %v, want a version error", err)
+	}
+}
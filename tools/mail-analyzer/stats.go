@@ -0,0 +1,1176 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// weekLabel returns the ISO week label of a timestamp, e.g. "2024-W03"
+func weekLabel(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// periodLabel returns the label of the time bucket a timestamp falls into.
+// Supported buckets are "day", "week", "month" and "quarter".
+func periodLabel(t time.Time, bucket string) (string, error) {
+	switch bucket {
+	case "day":
+		return t.Format("2006-01-02"), nil
+	case "week":
+		return weekLabel(t), nil
+	case "month":
+		return t.Format("2006-01"), nil
+	case "quarter":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1), nil
+	default:
+		return "", fmt.Errorf("unsupported bucket %q", bucket)
+	}
+}
+
+// itemMatcher returns a function matching item names either exactly or,
+// if the item is a valid regular expression, against that expression
+func itemMatcher(item string) func(string) bool {
+	re, err := getCompiledRegex(item)
+	return func(name string) bool {
+		if name == item {
+			return true
+		}
+		return err == nil && re.MatchString(name)
+	}
+}
+
+// PriceHistoryBuckets aggregates the sale prices of an item per time bucket,
+// ordered by period. Mails without a price are ignored.
+func PriceHistoryBuckets(mails []MailData, item, bucket string) []PriceBucket {
+	matches := itemMatcher(item)
+
+	byPeriod := make(map[string]*PriceBucket)
+	totals := make(map[string]int64)
+	for _, mail := range mails {
+		if mail.Price == 0 || !matches(canonicalItemName(mail)) {
+			continue
+		}
+
+		period, err := periodLabel(mail.Timestamp, bucket)
+		if err != nil {
+			return nil
+		}
+
+		b, ok := byPeriod[period]
+		if !ok {
+			b = &PriceBucket{Period: period, MinPrice: mail.Price, MaxPrice: mail.Price}
+			byPeriod[period] = b
+		}
+		b.Count++
+		b.MinPrice = min(b.MinPrice, mail.Price)
+		b.MaxPrice = max(b.MaxPrice, mail.Price)
+		totals[period] += mail.Price
+	}
+
+	buckets := make([]PriceBucket, 0, len(byPeriod))
+	for period, b := range byPeriod {
+		b.AvgPrice = totals[period] / int64(b.Count)
+		buckets = append(buckets, *b)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Period < buckets[j].Period
+	})
+
+	return buckets
+}
+
+// Price trends within this many percent of the previous period are flat
+const priceTrendFlatThreshold = 1.0
+
+// Price trend directions
+const (
+	TrendUp   = "up"
+	TrendDown = "down"
+	TrendFlat = "flat"
+)
+
+// ComputePriceTrend returns the average price of an item per time bucket,
+// ordered by period, with the change against the previous period
+func ComputePriceTrend(mails []MailData, item, bucket string) []PriceTrendPoint {
+	buckets := PriceHistoryBuckets(mails, item, bucket)
+
+	points := make([]PriceTrendPoint, 0, len(buckets))
+	for i, b := range buckets {
+		point := PriceTrendPoint{Period: b.Period, AvgPrice: b.AvgPrice, Count: b.Count, Trend: TrendFlat}
+		if i > 0 && buckets[i-1].AvgPrice != 0 {
+			previous := buckets[i-1].AvgPrice
+			point.WeekOverWeekChangePct = sanitizeFloat(float64(b.AvgPrice-previous) / float64(previous) * 100)
+		}
+
+		switch {
+		case point.WeekOverWeekChangePct > priceTrendFlatThreshold:
+			point.Trend = TrendUp
+		case point.WeekOverWeekChangePct < -priceTrendFlatThreshold:
+			point.Trend = TrendDown
+		}
+
+		points = append(points, point)
+	}
+
+	return points
+}
+
+// findSequentialGaps parses the mail IDs as integers and returns the ranges
+// missing from the sequence between the lowest and highest ID. IDs that are
+// not integers are returned in skipped.
+func findSequentialGaps(mails []MailData) (gaps []IDGap, skipped []string) {
+	ids := make([]int64, 0, len(mails))
+	for _, mail := range mails {
+		id, err := strconv.ParseInt(strings.TrimSpace(mail.MailID), 10, 64)
+		if err != nil {
+			skipped = append(skipped, mail.MailID)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	for i := 1; i < len(ids); i++ {
+		if ids[i]-ids[i-1] > 1 {
+			gaps = append(gaps, IDGap{
+				GapStart:     ids[i-1] + 1,
+				GapEnd:       ids[i] - 1,
+				MissingCount: ids[i] - ids[i-1] - 1,
+			})
+		}
+	}
+
+	return gaps, skipped
+}
+
+// Balance history events
+const (
+	BalanceEventSale       = "sale"
+	BalanceEventNonRevenue = "non_revenue"
+)
+
+// ComputeBalanceSeries reconstructs the running credit balance from the
+// mails in chronological order, starting at initialBalance. Mails without
+// a price are included as non-revenue events.
+func ComputeBalanceSeries(mails []MailData, initialBalance int64) []BalancePoint {
+	sorted := slices.Clone(mails)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	points := make([]BalancePoint, 0, len(sorted))
+	balance := initialBalance
+	for _, mail := range sorted {
+		event := BalanceEventSale
+		if mail.Price == 0 {
+			event = BalanceEventNonRevenue
+		}
+		balance += mail.Price
+
+		points = append(points, BalancePoint{
+			Timestamp:    mail.Timestamp,
+			MailID:       mail.MailID,
+			Event:        event,
+			Amount:       mail.Price,
+			RunningTotal: balance,
+		})
+	}
+
+	return points
+}
+
+// senderTreeCountKey holds the mail count of a sender that is also a prefix
+// of other senders, e.g. "SWG" next to "SWG.Restoration"
+const senderTreeCountKey = "_count"
+
+// buildSenderTree nests senders by their dot-separated segments. Leaves are
+// mail counts; "SWG.Restoration.auctioner" becomes
+// {"SWG": {"Restoration": {"auctioner": 3}}}.
+func buildSenderTree(senders map[string]int) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for sender, count := range senders {
+		insertSenderTree(tree, strings.Split(sender, "."), count)
+	}
+	return tree
+}
+
+// insertSenderTree adds the count of a sender, split into its segments, to the tree
+func insertSenderTree(tree map[string]interface{}, segments []string, count int) {
+	head := segments[0]
+	if len(segments) == 1 {
+		if subtree, ok := tree[head].(map[string]interface{}); ok {
+			subtree[senderTreeCountKey] = count
+		} else {
+			tree[head] = count
+		}
+		return
+	}
+
+	subtree, ok := tree[head].(map[string]interface{})
+	if !ok {
+		subtree = make(map[string]interface{})
+		// Keep the count of a sender that turned out to be a prefix
+		if leaf, isLeaf := tree[head].(int); isLeaf {
+			subtree[senderTreeCountKey] = leaf
+		}
+		tree[head] = subtree
+	}
+	insertSenderTree(subtree, segments[1:], count)
+}
+
+// concurrentStatsThreshold is the batch size from which generateMailStats
+// computes its aggregations concurrently
+const concurrentStatsThreshold = 100000
+
+// statsAggregation starts computing a group of independent statistics. It
+// returns a function that adds a mail, and one that stores the statistics
+// of the mails added so far in a MailStats. Only the aggregates are held,
+// not the mails, so mails can be added as they are parsed.
+type statsAggregation func() (add func(mail *MailData), apply func(stats *MailStats))
+
+// statsAggregations lists all aggregations making up MailStats. Each one
+// writes a disjoint set of fields, so they can run in any order.
+var statsAggregations = []statsAggregation{
+	aggregateDateRange,
+	aggregateSenders,
+	aggregateRevenue,
+	aggregatePurchases,
+	aggregateIncome,
+	aggregateAuctions,
+	aggregateFactoryRuns,
+	aggregateSurveys,
+	aggregateVendors,
+	aggregateItemCategories,
+	aggregateLocations,
+	aggregateOrigins,
+	aggregateInterSaleIntervals,
+	aggregateBodyLengths,
+	aggregateRecovered,
+	aggregateTopLists,
+}
+
+// statsAccumulator computes the statistics of mails added one at a time,
+// holding only the aggregates. It is used where mails are written as they
+// are produced rather than collected into a batch.
+type statsAccumulator struct {
+	count   int
+	adds    []func(mail *MailData)
+	applies []func(stats *MailStats)
+}
+
+// newStatsAccumulator returns an accumulator running all statsAggregations
+func newStatsAccumulator() *statsAccumulator {
+	acc := &statsAccumulator{}
+	for _, aggregate := range statsAggregations {
+		add, apply := aggregate()
+		acc.adds = append(acc.adds, add)
+		acc.applies = append(acc.applies, apply)
+	}
+	return acc
+}
+
+// add adds a mail to the statistics
+func (a *statsAccumulator) add(mail *MailData) {
+	a.count++
+	for _, add := range a.adds {
+		add(mail)
+	}
+}
+
+// stats returns the statistics of all mails added so far
+func (a *statsAccumulator) stats() MailStats {
+	stats := newMailStats(a.count)
+	if a.count == 0 {
+		return stats
+	}
+
+	for _, apply := range a.applies {
+		apply(&stats)
+	}
+
+	sanitizeStats(&stats)
+	return stats
+}
+
+// generateMailStats computes the statistics of a batch of mails. Large
+// batches are aggregated concurrently.
+func generateMailStats(mails []MailData) MailStats {
+	if len(mails) >= concurrentStatsThreshold {
+		return generateMailStatsConcurrent(mails)
+	}
+	return generateMailStatsSequential(mails)
+}
+
+// generateMailStatsSequential runs all aggregations in a single pass
+func generateMailStatsSequential(mails []MailData) MailStats {
+	acc := newStatsAccumulator()
+	for i := range mails {
+		acc.add(&mails[i])
+	}
+	return acc.stats()
+}
+
+// generateMailStatsConcurrent runs each aggregation in its own goroutine and
+// merges the results once they are done
+func generateMailStatsConcurrent(mails []MailData) MailStats {
+	stats := newMailStats(len(mails))
+	if len(mails) == 0 {
+		return stats
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, aggregate := range statsAggregations {
+		wg.Add(1)
+		go func(aggregate statsAggregation) {
+			defer wg.Done()
+			add, apply := aggregate()
+			for i := range mails {
+				add(&mails[i])
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			apply(&stats)
+		}(aggregate)
+	}
+	wg.Wait()
+
+	sanitizeStats(&stats)
+	return stats
+}
+
+// newMailStats returns stats with the mail count set and all maps allocated
+func newMailStats(count int) MailStats {
+	return MailStats{
+		TotalMails:        count,
+		Senders:           make(map[string]int),
+		MailsBySubsystem:  make(map[string]int),
+		SubjectClusters:   make(map[string]int),
+		MailsByCategory:   make(map[string]int),
+		MailsByType:       make(map[string]int),
+		MailCountByPlanet: make(map[string]int),
+		RevenueByPlanet:   make(map[string]int64),
+		MailCountByCity:   make(map[string]int),
+		RevenueByCity:     make(map[string]int64),
+		ItemDemandIndex:   make(map[string]float64),
+		Vendors:           make(map[string]VendorStats),
+
+		SalesByItemCategory:   make(map[string]int),
+		RevenueByItemCategory: make(map[string]int64),
+	}
+}
+
+// aggregateDateRange computes the time span of the mails
+func aggregateDateRange() (func(mail *MailData), func(stats *MailStats)) {
+	var dateRange DateRange
+	first := true
+	add := func(mail *MailData) {
+		if first {
+			dateRange = DateRange{StartDate: mail.Timestamp, EndDate: mail.Timestamp}
+			first = false
+		}
+		if mail.Timestamp.Before(dateRange.StartDate) {
+			dateRange.StartDate = mail.Timestamp
+		}
+		if mail.Timestamp.After(dateRange.EndDate) {
+			dateRange.EndDate = mail.Timestamp
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		stats.DateRange = dateRange
+	}
+}
+
+// aggregateSenders counts mails per sender, subsystem, subject, category and
+// type and from known and unknown senders
+func aggregateSenders() (func(mail *MailData), func(stats *MailStats)) {
+	senders := make(map[string]int)
+	subsystems := make(map[string]int)
+	subjects := make(map[string]int)
+	categories := make(map[string]int)
+	types := make(map[string]int)
+	var known, unknown int
+	add := func(mail *MailData) {
+		senders[mail.Sender]++
+		if mail.SenderLabel != "" {
+			known++
+		} else {
+			unknown++
+		}
+		subjects[mail.NormalizedSubject]++
+		categories[mail.MailCategory]++
+		if mail.MailType != "" {
+			types[string(mail.MailType)]++
+		}
+		if mail.SenderSubsystem != "" {
+			subsystems[mail.SenderSubsystem]++
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		stats.Senders = senders
+		stats.MailsBySubsystem = subsystems
+		stats.SubjectClusters = subjects
+		stats.MailsByCategory = categories
+		stats.MailsByType = types
+		stats.SenderTree = buildSenderTree(senders)
+		stats.KnownSystemMails = known
+		stats.UnknownSenderMails = unknown
+	}
+}
+
+// aggregatePurchases sums up the items bought and the credits spent on them
+func aggregatePurchases() (func(mail *MailData), func(stats *MailStats)) {
+	var purchases int
+	var spending int64
+	add := func(mail *MailData) {
+		if mail.Purchase == nil {
+			return
+		}
+		purchases++
+		spending += mail.Purchase.Price
+	}
+
+	return add, func(stats *MailStats) {
+		stats.PurchaseCount = purchases
+		stats.PurchaseSpending = spending
+	}
+}
+
+// aggregateIncome sums up mission and GCW payouts
+func aggregateIncome() (func(mail *MailData), func(stats *MailStats)) {
+	var total int64
+	var bySource map[string]int64
+	add := func(mail *MailData) {
+		if mail.Income == nil {
+			return
+		}
+		if bySource == nil {
+			bySource = make(map[string]int64)
+		}
+		total += mail.Income.Credits
+		bySource[mail.Income.Source] += mail.Income.Credits
+	}
+
+	return add, func(stats *MailStats) {
+		stats.OtherIncome = total
+		stats.OtherIncomeBySource = bySource
+	}
+}
+
+// aggregateAuctions counts won and outbid auctions and expired items
+func aggregateAuctions() (func(mail *MailData), func(stats *MailStats)) {
+	var won, outbid, expired int
+	var wonSpending int64
+	add := func(mail *MailData) {
+		if mail.Expired != nil {
+			expired++
+		}
+		if mail.Auction == nil {
+			return
+		}
+		switch mail.Auction.Event {
+		case AuctionEventWon:
+			won++
+			wonSpending += mail.Auction.Bid
+		case AuctionEventOutbid:
+			outbid++
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		stats.AuctionsWon = won
+		stats.AuctionsOutbid = outbid
+		stats.AuctionWonSpending = wonSpending
+		stats.ExpiredCount = expired
+	}
+}
+
+// aggregateFactoryRuns counts factory runs and sums up their output by
+// item key
+func aggregateFactoryRuns() (func(mail *MailData), func(stats *MailStats)) {
+	runs := 0
+	var produced map[string]int64
+	add := func(mail *MailData) {
+		if mail.FactoryRun == nil {
+			return
+		}
+		if produced == nil {
+			produced = make(map[string]int64)
+		}
+		runs++
+		produced[mail.FactoryRun.ItemKey] += mail.FactoryRun.Quantity
+	}
+
+	return add, func(stats *MailStats) {
+		stats.FactoryRuns = runs
+		stats.UnitsProducedByItem = produced
+	}
+}
+
+// aggregateSurveys collects the latest surveyed concentration of each
+// resource per planet. Of surveys with the same timestamp, the one added
+// last wins.
+func aggregateSurveys() (func(mail *MailData), func(stats *MailStats)) {
+	var concentrations map[string]map[string]float64
+	surveyedAt := make(map[[2]string]time.Time)
+	add := func(mail *MailData) {
+		for _, result := range mail.SurveyResults {
+			key := [2]string{result.Resource, result.Planet}
+			if last, ok := surveyedAt[key]; ok && mail.Timestamp.Before(last) {
+				continue
+			}
+			surveyedAt[key] = mail.Timestamp
+
+			if concentrations == nil {
+				concentrations = make(map[string]map[string]float64)
+			}
+			if concentrations[result.Resource] == nil {
+				concentrations[result.Resource] = make(map[string]float64)
+			}
+			concentrations[result.Resource][result.Planet] = result.Concentration
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		stats.ResourceConcentrations = concentrations
+	}
+}
+
+// aggregateVendors sums up the sales of every player vendor
+func aggregateVendors() (func(mail *MailData), func(stats *MailStats)) {
+	vendors := make(map[string]VendorStats)
+	add := func(mail *MailData) {
+		if mail.VendorName == "" {
+			return
+		}
+		vendor := vendors[mail.VendorName]
+		vendor.MailCount++
+		vendor.TotalCredits += mail.Price
+		vendors[mail.VendorName] = vendor
+	}
+
+	return add, func(stats *MailStats) {
+		stats.Vendors = vendors
+	}
+}
+
+// aggregateItemCategories counts sales and revenue per item category
+func aggregateItemCategories() (func(mail *MailData), func(stats *MailStats)) {
+	sales := make(map[string]int)
+	revenue := make(map[string]int64)
+	add := func(mail *MailData) {
+		if mail.ItemCategory == "" || mail.Price == 0 {
+			return
+		}
+		sales[mail.ItemCategory]++
+		revenue[mail.ItemCategory] += mail.Price
+	}
+
+	return add, func(stats *MailStats) {
+		stats.SalesByItemCategory = sales
+		stats.RevenueByItemCategory = revenue
+	}
+}
+
+// aggregateRevenue sums up revenue and sale notifications, split by sale type
+func aggregateRevenue() (func(mail *MailData), func(stats *MailStats)) {
+	var totalRevenue, vendorRevenue, bazaarRevenue, bidRevenue, buyNowRevenue int64
+	var saleNotifications, vendorSales, bazaarSales, bidSales, buyNowSales int
+	add := func(mail *MailData) {
+		totalRevenue += mail.Price
+		if mail.Sale != nil || isSaleNotification(mail) {
+			saleNotifications++
+		}
+
+		switch mail.SaleType {
+		case SaleTypeVendor:
+			vendorRevenue += mail.Price
+			vendorSales++
+		case SaleTypeBazaar:
+			bazaarRevenue += mail.Price
+			bazaarSales++
+		}
+
+		switch mail.PriceType {
+		case PriceTypeBid:
+			bidRevenue += mail.Price
+			bidSales++
+		case PriceTypeBuyNow:
+			buyNowRevenue += mail.Price
+			buyNowSales++
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		var ratio float64
+		if bazaarRevenue != 0 {
+			ratio = float64(vendorRevenue) / float64(bazaarRevenue)
+		}
+
+		stats.TotalRevenue = totalRevenue
+		stats.SaleNotifications = saleNotifications
+		stats.VendorRevenue = vendorRevenue
+		stats.BazaarRevenue = bazaarRevenue
+		stats.VendorSaleCount = vendorSales
+		stats.BazaarSaleCount = bazaarSales
+		stats.VendorToBazaarRatio = ratio
+		stats.BidSaleCount = bidSales
+		stats.BuyNowSaleCount = buyNowSales
+		if bidSales > 0 {
+			stats.AvgBidPrice = bidRevenue / int64(bidSales)
+		}
+		if buyNowSales > 0 {
+			stats.AvgBuyNowPrice = buyNowRevenue / int64(buyNowSales)
+		}
+	}
+}
+
+// aggregateOrigins counts mails per source directory and mails and revenue
+// per galaxy and character
+func aggregateOrigins() (func(mail *MailData), func(stats *MailStats)) {
+	var mailsBySource map[string]int
+	var mailsByGalaxy map[string]int
+	var revenueByGalaxy map[string]int64
+	var mailsByCharacter map[string]int
+	var revenueByCharacter map[string]int64
+	add := func(mail *MailData) {
+		if mail.Galaxy != "" {
+			if mailsByGalaxy == nil {
+				mailsByGalaxy = make(map[string]int)
+				revenueByGalaxy = make(map[string]int64)
+			}
+			mailsByGalaxy[mail.Galaxy]++
+			revenueByGalaxy[mail.Galaxy] += mail.Price
+		}
+
+		if mail.Source != "" {
+			if mailsBySource == nil {
+				mailsBySource = make(map[string]int)
+			}
+			mailsBySource[mail.Source]++
+		}
+
+		if mail.Character == "" {
+			return
+		}
+		if mailsByCharacter == nil {
+			mailsByCharacter = make(map[string]int)
+			revenueByCharacter = make(map[string]int64)
+		}
+		mailsByCharacter[mail.Character]++
+		revenueByCharacter[mail.Character] += mail.Price
+	}
+
+	return add, func(stats *MailStats) {
+		stats.MailsBySource = mailsBySource
+		stats.MailsByGalaxy = mailsByGalaxy
+		stats.RevenueByGalaxy = revenueByGalaxy
+		stats.MailCountByCharacter = mailsByCharacter
+		stats.RevenueByCharacter = revenueByCharacter
+	}
+}
+
+// aggregateLocations counts mails and revenue per planet and city and
+// computes the coordinate bounding box
+func aggregateLocations() (func(mail *MailData), func(stats *MailStats)) {
+	mailsByPlanet := make(map[string]int)
+	revenueByPlanet := make(map[string]int64)
+	mailsByCity := make(map[string]int)
+	revenueByCity := make(map[string]int64)
+
+	var box BoundingBox
+	hasCoordinates := false
+
+	add := func(mail *MailData) {
+		if mail.Planet != "" {
+			mailsByPlanet[mail.Planet]++
+			revenueByPlanet[mail.Planet] += mail.Price
+		}
+		if mail.City != "" {
+			mailsByCity[mail.City]++
+			revenueByCity[mail.City] += mail.Price
+		}
+
+		if mail.HasCoordinates {
+			if !hasCoordinates {
+				box = BoundingBox{MinX: mail.LocationX, MaxX: mail.LocationX, MinZ: mail.LocationZ, MaxZ: mail.LocationZ}
+				hasCoordinates = true
+			}
+			box.MinX = math.Min(box.MinX, mail.LocationX)
+			box.MaxX = math.Max(box.MaxX, mail.LocationX)
+			box.MinZ = math.Min(box.MinZ, mail.LocationZ)
+			box.MaxZ = math.Max(box.MaxZ, mail.LocationZ)
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		stats.MailCountByPlanet = mailsByPlanet
+		stats.RevenueByPlanet = revenueByPlanet
+		stats.MailCountByCity = mailsByCity
+		stats.RevenueByCity = revenueByCity
+		stats.LocationBoundingBox = box
+	}
+}
+
+// aggregateInterSaleIntervals computes the mean and median time between
+// consecutive sales. The median needs every interval, so the sale times
+// are the only per-mail data kept; mails without a price are ignored.
+func aggregateInterSaleIntervals() (func(mail *MailData), func(stats *MailStats)) {
+	var saleTimes []time.Time
+	add := func(mail *MailData) {
+		if mail.Price != 0 {
+			saleTimes = append(saleTimes, mail.Timestamp)
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		// Mails are not necessarily added in timestamp order
+		sorted := slices.Clone(saleTimes)
+		slices.SortStableFunc(sorted, func(a, b time.Time) int { return a.Compare(b) })
+
+		intervals := make([]float64, 0, len(sorted))
+		for i := 1; i < len(sorted); i++ {
+			intervals = append(intervals, sorted[i].Sub(sorted[i-1]).Hours())
+		}
+		if len(intervals) > 0 {
+			stats.AvgInterSaleIntervalHours = mean(intervals)
+			stats.MedianInterSaleIntervalHours = median(intervals)
+		}
+	}
+}
+
+// aggregateBodyLengths computes the body length distribution from a
+// histogram of the lengths in bytes
+func aggregateBodyLengths() (func(mail *MailData), func(stats *MailStats)) {
+	counts := make(map[int]int)
+	n, total := 0, 0
+	add := func(mail *MailData) {
+		counts[len(mail.Body)]++
+		n++
+		total += len(mail.Body)
+	}
+
+	return add, func(stats *MailStats) {
+		if n == 0 {
+			return
+		}
+		lengths := slices.Sorted(maps.Keys(counts))
+
+		// nth returns the length at index i of all lengths in sorted order
+		nth := func(i int) int {
+			for _, length := range lengths {
+				if i < counts[length] {
+					return length
+				}
+				i -= counts[length]
+			}
+			return lengths[len(lengths)-1]
+		}
+
+		bodyLengths := BodyLengthStats{
+			Min:  lengths[0],
+			Max:  lengths[len(lengths)-1],
+			Mean: total / n,
+			// Nearest-rank percentile
+			P90: nth((n*9+9)/10 - 1),
+		}
+		if n%2 == 0 {
+			bodyLengths.Median = (nth(n/2-1) + nth(n/2)) / 2
+		} else {
+			bodyLengths.Median = nth(n / 2)
+		}
+		stats.BodyLengthStats = bodyLengths
+	}
+}
+
+// aggregateRecovered counts the mails whose header was reconstructed by
+// --recover-headers
+func aggregateRecovered() (func(mail *MailData), func(stats *MailStats)) {
+	recovered := 0
+	add := func(mail *MailData) {
+		if mail.Recovered {
+			recovered++
+		}
+	}
+
+	return add, func(stats *MailStats) {
+		stats.RecoveredMails = recovered
+	}
+}
+
+// aggregateTopLists computes the top items and buyers by revenue and the
+// items in highest demand
+func aggregateTopLists() (func(mail *MailData), func(stats *MailStats)) {
+	items := make(itemRevenues)
+	buyers := make(buyerRevenues)
+	demands := newItemDemands()
+	add := func(mail *MailData) {
+		items.add(mail)
+		buyers.add(mail)
+		demands.add(mail)
+	}
+
+	return add, func(stats *MailStats) {
+		stats.TopItems = items.top(10)
+		stats.TopBuyers = buyers.top(10)
+		stats.ItemDemandIndex = demands.index()
+		stats.TopDemandItems = demands.top(10)
+	}
+}
+
+// sanitizeFloat replaces NaN and infinite values, which cannot be encoded as
+// JSON numbers, with 0
+func sanitizeFloat(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
+
+// sanitizeStats applies sanitizeFloat to every float field of the stats
+func sanitizeStats(stats *MailStats) {
+	stats.AvgInterSaleIntervalHours = sanitizeFloat(stats.AvgInterSaleIntervalHours)
+	stats.MedianInterSaleIntervalHours = sanitizeFloat(stats.MedianInterSaleIntervalHours)
+	stats.GoalProgress = sanitizeFloat(stats.GoalProgress)
+	stats.VendorToBazaarRatio = sanitizeFloat(stats.VendorToBazaarRatio)
+
+	box := &stats.LocationBoundingBox
+	box.MinX = sanitizeFloat(box.MinX)
+	box.MaxX = sanitizeFloat(box.MaxX)
+	box.MinZ = sanitizeFloat(box.MinZ)
+	box.MaxZ = sanitizeFloat(box.MaxZ)
+}
+
+// findShortBodyMails returns the IDs of mails whose body is shorter than minLength
+func findShortBodyMails(mails []MailData, minLength int) []string {
+	var ids []string
+	for _, mail := range mails {
+		if len(mail.Body) < minLength {
+			ids = append(ids, mail.MailID)
+		}
+	}
+	return ids
+}
+
+// mean returns the arithmetic mean of values, or 0 if there are none
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// median returns the median of values, or 0 if there are none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// GroupByWeek groups mails by the ISO week they were received in
+func GroupByWeek(mails []MailData) map[string][]MailData {
+	weeks := make(map[string][]MailData)
+	for _, mail := range mails {
+		label := weekLabel(mail.Timestamp)
+		weeks[label] = append(weeks[label], mail)
+	}
+	return weeks
+}
+
+// computeWeeklySummaries summarizes the mails of each ISO week, ordered by week
+func computeWeeklySummaries(mails []MailData) []WeeklySummary {
+	weeks := GroupByWeek(mails)
+
+	labels := make([]string, 0, len(weeks))
+	for label := range weeks {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	revenueByWeek := make(map[string]int64, len(weeks))
+	summaries := make([]WeeklySummary, 0, len(weeks))
+	for _, label := range labels {
+		weekMails := weeks[label]
+		summary := WeeklySummary{
+			Week:      label,
+			MailCount: len(weekMails),
+		}
+
+		for _, mail := range weekMails {
+			summary.TotalRevenue += mail.Price
+		}
+		if items := computeTopItems(weekMails, 1); len(items) > 0 {
+			summary.TopItem = items[0].ItemName
+		}
+		if buyers := computeTopBuyers(weekMails, 1); len(buyers) > 0 {
+			summary.TopBuyer = buyers[0].Buyer
+		}
+
+		revenueByWeek[label] = summary.TotalRevenue
+		summaries = append(summaries, summary)
+	}
+
+	// Compare against the same week of the previous year
+	for i := range summaries {
+		var year, week int
+		if _, err := fmt.Sscanf(summaries[i].Week, "%d-W%d", &year, &week); err != nil {
+			continue
+		}
+
+		previous, ok := revenueByWeek[fmt.Sprintf("%d-W%02d", year-1, week)]
+		if !ok || previous == 0 {
+			continue
+		}
+
+		growth := float64(summaries[i].TotalRevenue-previous) / float64(previous) * 100
+		summaries[i].YearOverYearGrowth = &growth
+	}
+
+	return summaries
+}
+
+// canonicalItemName returns the name a mail's item is aggregated under: the
+// item database name if known, otherwise the item key
+func canonicalItemName(mail MailData) string {
+	if mail.CanonicalItemName != "" {
+		return mail.CanonicalItemName
+	}
+	if mail.ItemKey != "" {
+		return mail.ItemKey
+	}
+	return mail.ItemName
+}
+
+// findUnrecognizedItems returns the sorted, distinct item names missing from the item database
+func findUnrecognizedItems(mails []MailData, itemDB map[string]string) []string {
+	seen := make(map[string]bool)
+	var items []string
+	for _, mail := range mails {
+		if mail.ItemName == "" || seen[mail.ItemName] {
+			continue
+		}
+		_, known := itemDB[mail.ItemName]
+		if _, ok := itemDB[mail.ItemKey]; ok && mail.ItemKey != "" {
+			known = true
+		}
+		if !known {
+			items = append(items, mail.ItemName)
+		}
+		seen[mail.ItemName] = true
+	}
+	sort.Strings(items)
+	return items
+}
+
+// itemRevenues accumulates the sales and revenue of every item
+type itemRevenues map[string]*ItemRevenueStat
+
+// add adds a mail to the item it sold, if any
+func (r itemRevenues) add(mail *MailData) {
+	name := canonicalItemName(*mail)
+	if name == "" {
+		return
+	}
+
+	stat, ok := r[name]
+	if !ok {
+		stat = &ItemRevenueStat{ItemName: name, FirstSoldAt: mail.Timestamp, LastSoldAt: mail.Timestamp}
+		r[name] = stat
+	}
+	stat.SaleCount++
+	stat.Revenue += mail.Price
+	if mail.Timestamp.Before(stat.FirstSoldAt) {
+		stat.FirstSoldAt = mail.Timestamp
+	}
+	if mail.Timestamp.After(stat.LastSoldAt) {
+		stat.LastSoldAt = mail.Timestamp
+	}
+}
+
+// top returns up to limit items ordered by revenue
+func (r itemRevenues) top(limit int) []ItemRevenueStat {
+	items := make([]ItemRevenueStat, 0, len(r))
+	for _, stat := range r {
+		item := *stat
+		item.DaysActive = item.LastSoldAt.Sub(item.FirstSoldAt).Hours() / 24
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Revenue != items[j].Revenue {
+			return items[i].Revenue > items[j].Revenue
+		}
+		return items[i].ItemName < items[j].ItemName
+	})
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// computeTopItems returns up to limit items ordered by revenue
+func computeTopItems(mails []MailData, limit int) []ItemRevenueStat {
+	items := make(itemRevenues)
+	for i := range mails {
+		items.add(&mails[i])
+	}
+	return items.top(limit)
+}
+
+// itemDemands accumulates the sales and distinct buyers of every item.
+// Sales without a buyer are ignored.
+type itemDemands struct {
+	buyersByItem map[string]map[string]bool
+	salesByItem  map[string]int
+}
+
+// newItemDemands returns an empty itemDemands
+func newItemDemands() *itemDemands {
+	return &itemDemands{
+		buyersByItem: make(map[string]map[string]bool),
+		salesByItem:  make(map[string]int),
+	}
+}
+
+// add adds a sale to the demand of its item
+func (d *itemDemands) add(mail *MailData) {
+	name := canonicalItemName(*mail)
+	if name == "" || mail.Buyer == "" {
+		return
+	}
+
+	if d.buyersByItem[name] == nil {
+		d.buyersByItem[name] = make(map[string]bool)
+	}
+	d.buyersByItem[name][mail.Buyer] = true
+	d.salesByItem[name]++
+}
+
+// items returns the demand of every item
+func (d *itemDemands) items() []ItemDemandStat {
+	demands := make([]ItemDemandStat, 0, len(d.salesByItem))
+	for name, sales := range d.salesByItem {
+		buyers := len(d.buyersByItem[name])
+		demands = append(demands, ItemDemandStat{
+			ItemName:     name,
+			SaleCount:    sales,
+			UniqueBuyers: buyers,
+			DemandIndex:  float64(buyers) / float64(sales),
+		})
+	}
+	return demands
+}
+
+// index returns the unique buyers per sale of every item
+func (d *itemDemands) index() map[string]float64 {
+	index := make(map[string]float64)
+	for _, demand := range d.items() {
+		index[demand.ItemName] = demand.DemandIndex
+	}
+	return index
+}
+
+// computeItemDemandIndex returns the demand index of every sold item: its
+// unique buyers per sale, from near 0 for a single repeat buyer to 1 for
+// one sale per buyer
+func computeItemDemandIndex(mails []MailData) map[string]float64 {
+	demands := newItemDemands()
+	for i := range mails {
+		demands.add(&mails[i])
+	}
+	return demands.index()
+}
+
+// top returns up to limit items ordered by demand index. Ties are broken by
+// sale count, so broadly demanded items with many sales come before items
+// sold only once.
+func (d *itemDemands) top(limit int) []ItemDemandStat {
+	items := d.items()
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].DemandIndex != items[j].DemandIndex {
+			return items[i].DemandIndex > items[j].DemandIndex
+		}
+		if items[i].SaleCount != items[j].SaleCount {
+			return items[i].SaleCount > items[j].SaleCount
+		}
+		return items[i].ItemName < items[j].ItemName
+	})
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// buyerRevenues accumulates the purchases and revenue of every buyer
+type buyerRevenues map[string]*BuyerRevenueStat
+
+// add adds a sale to its buyer, if known
+func (r buyerRevenues) add(mail *MailData) {
+	if mail.Buyer == "" {
+		return
+	}
+
+	stat, ok := r[mail.Buyer]
+	if !ok {
+		stat = &BuyerRevenueStat{Buyer: mail.Buyer}
+		r[mail.Buyer] = stat
+	}
+	stat.PurchaseCount++
+	stat.Revenue += mail.Price
+}
+
+// top returns up to limit buyers ordered by revenue
+func (r buyerRevenues) top(limit int) []BuyerRevenueStat {
+	buyers := make([]BuyerRevenueStat, 0, len(r))
+	for _, stat := range r {
+		buyers = append(buyers, *stat)
+	}
+	sort.Slice(buyers, func(i, j int) bool {
+		if buyers[i].Revenue != buyers[j].Revenue {
+			return buyers[i].Revenue > buyers[j].Revenue
+		}
+		return buyers[i].Buyer < buyers[j].Buyer
+	})
+
+	if limit > 0 && len(buyers) > limit {
+		buyers = buyers[:limit]
+	}
+	return buyers
+}
+
+// computeTopBuyers returns up to limit buyers ordered by revenue
+func computeTopBuyers(mails []MailData, limit int) []BuyerRevenueStat {
+	buyers := make(buyerRevenues)
+	for i := range mails {
+		buyers.add(&mails[i])
+	}
+	return buyers.top(limit)
+}
@@ -0,0 +1,983 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSale returns a sale notification of item to buyer for price at the
+// given UTC time
+func testSale(id string, at time.Time, item, buyer string, price int64) MailData {
+	return MailData{MailID: id, Timestamp: at, ItemName: item, Buyer: buyer, Price: price}
+}
+
+// date returns noon UTC of the given day
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestGroupByWeek(t *testing.T) {
+	mails := []MailData{
+		{MailID: "1", Timestamp: date(2024, time.January, 15)},
+		{MailID: "2", Timestamp: date(2024, time.January, 21)},
+		{MailID: "3", Timestamp: date(2024, time.January, 22)},
+		// ISO week 1 of 2025 starts on Monday, December 30 2024
+		{MailID: "4", Timestamp: date(2024, time.December, 30)},
+	}
+
+	tests := []struct {
+		week  string
+		count int
+	}{
+		{"2024-W03", 2},
+		{"2024-W04", 1},
+		{"2025-W01", 1},
+	}
+
+	weeks := GroupByWeek(mails)
+	if len(weeks) != len(tests) {
+		t.Errorf("GroupByWeek() returned %d weeks, want %d", len(weeks), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.week, func(t *testing.T) {
+			if got := len(weeks[tt.week]); got != tt.count {
+				t.Errorf("week %s has %d mails, want %d", tt.week, got, tt.count)
+			}
+		})
+	}
+}
+
+func TestComputeWeeklySummaries(t *testing.T) {
+	mails := []MailData{
+		// 2023-W03
+		testSale("1", date(2023, time.January, 16), "Rifle", "Han", 600),
+		testSale("2", date(2023, time.January, 17), "Pistol", "Leia", 400),
+		// 2024-W03
+		testSale("3", date(2024, time.January, 15), "Rifle", "Han", 1000),
+		testSale("4", date(2024, time.January, 16), "Rifle", "Luke", 500),
+		// 2024-W04, without a week a year before
+		testSale("5", date(2024, time.January, 22), "Pistol", "Leia", 300),
+	}
+
+	tests := []struct {
+		week     string
+		count    int
+		revenue  int64
+		topItem  string
+		topBuyer string
+		growth   *float64
+	}{
+		{"2023-W03", 2, 1000, "Rifle", "Han", nil},
+		{"2024-W03", 2, 1500, "Rifle", "Han", ptr(50.0)},
+		{"2024-W04", 1, 300, "Pistol", "Leia", nil},
+	}
+
+	summaries := computeWeeklySummaries(mails)
+	if len(summaries) != len(tests) {
+		t.Fatalf("computeWeeklySummaries() returned %d weeks, want %d", len(summaries), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.week, func(t *testing.T) {
+			got := summaries[i]
+			if got.Week != tt.week || got.MailCount != tt.count || got.TotalRevenue != tt.revenue {
+				t.Errorf("summary = %s, %d mails, %d cr, want %s, %d mails, %d cr",
+					got.Week, got.MailCount, got.TotalRevenue, tt.week, tt.count, tt.revenue)
+			}
+			if got.TopItem != tt.topItem || got.TopBuyer != tt.topBuyer {
+				t.Errorf("top item and buyer = %s, %s, want %s, %s", got.TopItem, got.TopBuyer, tt.topItem, tt.topBuyer)
+			}
+			switch {
+			case tt.growth == nil && got.YearOverYearGrowth != nil:
+				t.Errorf("YearOverYearGrowth = %v, want none", *got.YearOverYearGrowth)
+			case tt.growth != nil && (got.YearOverYearGrowth == nil || *got.YearOverYearGrowth != *tt.growth):
+				t.Errorf("YearOverYearGrowth = %v, want %v", got.YearOverYearGrowth, *tt.growth)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestPriceHistoryBuckets(t *testing.T) {
+	mails := []MailData{
+		testSale("1", date(2024, time.January, 15), "Rifle", "Han", 40000),
+		testSale("2", date(2024, time.January, 15), "Rifle", "Han", 50000),
+		testSale("3", date(2024, time.January, 17), "Rifle", "Leia", 45000),
+		testSale("4", date(2024, time.February, 1), "Rifle", "Luke", 30000),
+		testSale("5", date(2024, time.January, 15), "Carbine", "Han", 90000),
+		testSale("6", date(2024, time.January, 15), "Pistol", "Han", 1000),
+		{MailID: "7", Timestamp: date(2024, time.January, 15), ItemName: "Rifle"},
+	}
+
+	tests := []struct {
+		name   string
+		item   string
+		bucket string
+		want   []PriceBucket
+	}{
+		{
+			name:   "day",
+			item:   "Rifle",
+			bucket: "day",
+			want: []PriceBucket{
+				{Period: "2024-01-15", AvgPrice: 45000, MinPrice: 40000, MaxPrice: 50000, Count: 2},
+				{Period: "2024-01-17", AvgPrice: 45000, MinPrice: 45000, MaxPrice: 45000, Count: 1},
+				{Period: "2024-02-01", AvgPrice: 30000, MinPrice: 30000, MaxPrice: 30000, Count: 1},
+			},
+		},
+		{
+			name:   "week",
+			item:   "Rifle",
+			bucket: "week",
+			want: []PriceBucket{
+				{Period: "2024-W03", AvgPrice: 45000, MinPrice: 40000, MaxPrice: 50000, Count: 3},
+				{Period: "2024-W05", AvgPrice: 30000, MinPrice: 30000, MaxPrice: 30000, Count: 1},
+			},
+		},
+		{
+			name:   "month",
+			item:   "Rifle",
+			bucket: "month",
+			want: []PriceBucket{
+				{Period: "2024-01", AvgPrice: 45000, MinPrice: 40000, MaxPrice: 50000, Count: 3},
+				{Period: "2024-02", AvgPrice: 30000, MinPrice: 30000, MaxPrice: 30000, Count: 1},
+			},
+		},
+		{
+			name:   "regular expression",
+			item:   "^(Rifle|Carbine)$",
+			bucket: "month",
+			want: []PriceBucket{
+				{Period: "2024-01", AvgPrice: 56250, MinPrice: 40000, MaxPrice: 90000, Count: 4},
+				{Period: "2024-02", AvgPrice: 30000, MinPrice: 30000, MaxPrice: 30000, Count: 1},
+			},
+		},
+		{"unknown item", "Armor", "day", []PriceBucket{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PriceHistoryBuckets(mails, tt.item, tt.bucket)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PriceHistoryBuckets(%q, %q) = %+v, want %+v", tt.item, tt.bucket, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterSaleIntervals(t *testing.T) {
+	start := date(2024, time.January, 15)
+	at := func(hours float64) time.Time {
+		return start.Add(time.Duration(hours * float64(time.Hour)))
+	}
+
+	tests := []struct {
+		name   string
+		mails  []MailData
+		mean   float64
+		median float64
+	}{
+		{
+			name: "five sales",
+			mails: []MailData{
+				testSale("1", at(0), "Rifle", "Han", 100),
+				testSale("2", at(1), "Rifle", "Han", 100),
+				testSale("3", at(3), "Rifle", "Han", 100),
+				testSale("4", at(7), "Rifle", "Han", 100),
+				testSale("5", at(15), "Rifle", "Han", 100),
+			},
+			mean:   3.75,
+			median: 3,
+		},
+		{
+			name: "unsorted with mails without a price",
+			mails: []MailData{
+				testSale("3", at(4), "Rifle", "Han", 100),
+				{MailID: "4", Timestamp: at(5)},
+				testSale("1", at(0), "Rifle", "Han", 100),
+				testSale("2", at(1.5), "Rifle", "Han", 100),
+			},
+			mean:   2,
+			median: 2,
+		},
+		{
+			name:  "single sale",
+			mails: []MailData{testSale("1", at(0), "Rifle", "Han", 100), {MailID: "2", Timestamp: at(1)}},
+		},
+		{"no mails", nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := generateMailStats(tt.mails)
+			if stats.AvgInterSaleIntervalHours != tt.mean || stats.MedianInterSaleIntervalHours != tt.median {
+				t.Errorf("mean, median = %v, %v, want %v, %v",
+					stats.AvgInterSaleIntervalHours, stats.MedianInterSaleIntervalHours, tt.mean, tt.median)
+			}
+		})
+	}
+}
+
+// mailsWithBodyLengths returns mails with IDs "1", "2", ... whose bodies
+// have the given lengths
+func mailsWithBodyLengths(lengths ...int) []MailData {
+	mails := make([]MailData, len(lengths))
+	for i, length := range lengths {
+		mails[i] = MailData{MailID: strconv.Itoa(i + 1), Body: strings.Repeat("x", length)}
+	}
+	return mails
+}
+
+func TestBodyLengthStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		lengths []int
+		want    BodyLengthStats
+	}{
+		{"ten mails", []int{100, 10, 90, 20, 80, 30, 70, 40, 60, 50}, BodyLengthStats{Min: 10, Max: 100, Mean: 55, Median: 55, P90: 90}},
+		{"three mails", []int{5, 1, 3}, BodyLengthStats{Min: 1, Max: 5, Mean: 3, Median: 3, P90: 5}},
+		{"repeated lengths", []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 1000}, BodyLengthStats{Min: 0, Max: 1000, Mean: 100, Median: 0, P90: 0}},
+		{"no mails", nil, BodyLengthStats{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := generateMailStats(mailsWithBodyLengths(tt.lengths...))
+			if stats.BodyLengthStats != tt.want {
+				t.Errorf("BodyLengthStats = %+v, want %+v", stats.BodyLengthStats, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindShortBodyMails(t *testing.T) {
+	mails := mailsWithBodyLengths(0, 5, 10, 50)
+
+	tests := []struct {
+		minLength int
+		want      []string
+	}{
+		{1, []string{"1"}},
+		{10, []string{"1", "2"}},
+		{11, []string{"1", "2", "3"}},
+		{0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.minLength), func(t *testing.T) {
+			if got := findShortBodyMails(mails, tt.minLength); !slices.Equal(got, tt.want) {
+				t.Errorf("findShortBodyMails(%d) = %v, want %v", tt.minLength, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailsBySubsystem(t *testing.T) {
+	mails := []MailData{
+		{MailID: "1", SenderSubsystem: "auctioner"},
+		{MailID: "2", SenderSubsystem: "auctioner"},
+		{MailID: "3", SenderSubsystem: "bazaar"},
+		{MailID: "4"},
+	}
+
+	tests := []struct {
+		subsystem string
+		want      int
+	}{
+		{"auctioner", 2},
+		{"bazaar", 1},
+		{"", 0},
+	}
+
+	stats := generateMailStats(mails)
+	for _, tt := range tests {
+		t.Run(tt.subsystem, func(t *testing.T) {
+			if got := stats.MailsBySubsystem[tt.subsystem]; got != tt.want {
+				t.Errorf("MailsBySubsystem[%q] = %d, want %d", tt.subsystem, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFloat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   float64
+		want float64
+	}{
+		{"finite", 1.5, 1.5},
+		{"negative", -2, -2},
+		{"NaN", math.NaN(), 0},
+		{"positive infinity", math.Inf(1), 0},
+		{"negative infinity", math.Inf(-1), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeFloat(tt.in); got != tt.want {
+				t.Errorf("sanitizeFloat(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailStatsJSONNonFiniteFloats(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats func() MailStats
+	}{
+		{"empty mail slice", func() MailStats { return generateMailStats(nil) }},
+		{"single sale", func() MailStats {
+			return generateMailStats([]MailData{testSale("1", date(2024, time.January, 15), "Rifle", "Han", 100)})
+		}},
+		{"zero denominators", func() MailStats {
+			stats := generateMailStats(nil)
+			stats.AvgInterSaleIntervalHours = math.NaN()
+			stats.MedianInterSaleIntervalHours = math.Inf(1)
+			stats.GoalProgress = math.Inf(-1)
+			stats.VendorToBazaarRatio = math.NaN()
+			stats.LocationBoundingBox.MinX = math.NaN()
+			sanitizeStats(&stats)
+			return stats
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := tt.stats()
+			if _, err := json.Marshal(stats); err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			for name, value := range map[string]float64{
+				"AvgInterSaleIntervalHours":    stats.AvgInterSaleIntervalHours,
+				"MedianInterSaleIntervalHours": stats.MedianInterSaleIntervalHours,
+				"GoalProgress":                 stats.GoalProgress,
+				"VendorToBazaarRatio":          stats.VendorToBazaarRatio,
+				"LocationBoundingBox.MinX":     stats.LocationBoundingBox.MinX,
+			} {
+				if value != 0 {
+					t.Errorf("%s = %v, want 0", name, value)
+				}
+			}
+		})
+	}
+}
+
+// syntheticMails returns n varied mails: sales of a few items to a few
+// buyers on a few planets, interleaved with player mails
+func syntheticMails(n int) []MailData {
+	items := []string{"Rifle", "Pistol", "Carbine", "Armor Segment x5", "Food"}
+	buyers := []string{"Han", "Leia", "Luke", "Chewbacca"}
+	planets := []string{"Tatooine", "Naboo", "Corellia"}
+	start := date(2024, time.January, 1)
+
+	mails := make([]MailData, n)
+	for i := range mails {
+		mail := MailData{
+			MailID:          strconv.Itoa(i + 1),
+			Timestamp:       start.Add(time.Duration(i) * time.Minute),
+			Sender:          "Han Solo",
+			Subject:         "Hello " + strconv.Itoa(i%7),
+			Body:            strings.Repeat("x", i%200),
+			MailCategory:    CategoryUnknown,
+			SenderSubsystem: "",
+		}
+		if i%3 != 0 {
+			mail.Sender = "SWG.Restoration.auctioner"
+			mail.SenderSubsystem = "auctioner"
+			mail.MailCategory = CategorySale
+			mail.ItemName = items[i%len(items)]
+			mail.ItemKey = mail.ItemName
+			mail.Buyer = buyers[i%len(buyers)]
+			mail.Price = int64(100 + i%1000)
+			mail.Planet = planets[i%len(planets)]
+			mail.Sale = saleData(&mail)
+		}
+		mails[i] = mail
+	}
+	return mails
+}
+
+func TestGenerateMailStatsConcurrent(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{"empty", 0},
+		{"single mail", 1},
+		{"small batch", 100},
+		{"large batch", 20000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mails := syntheticMails(tt.count)
+			sequential := generateMailStatsSequential(mails)
+			concurrent := generateMailStatsConcurrent(mails)
+			if !reflect.DeepEqual(concurrent, sequential) {
+				t.Errorf("concurrent stats differ from sequential stats:\ngot  %+v\nwant %+v", concurrent, sequential)
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateMailStats(b *testing.B) {
+	mails := syntheticMails(500000)
+
+	benchmarks := []struct {
+		name     string
+		generate func([]MailData) MailStats
+	}{
+		{"sequential", generateMailStatsSequential},
+		{"concurrent", generateMailStatsConcurrent},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for b.Loop() {
+				bm.generate(mails)
+			}
+		})
+	}
+}
+
+// mailsWithIDs returns one mail per ID
+func mailsWithIDs(ids ...string) []MailData {
+	mails := make([]MailData, len(ids))
+	for i, id := range ids {
+		mails[i] = MailData{MailID: id}
+	}
+	return mails
+}
+
+func TestFindSequentialGaps(t *testing.T) {
+	tests := []struct {
+		name        string
+		ids         []string
+		wantGaps    []IDGap
+		wantSkipped []string
+	}{
+		{
+			name:     "gaps",
+			ids:      []string{"1", "2", "4", "5", "10"},
+			wantGaps: []IDGap{{GapStart: 3, GapEnd: 3, MissingCount: 1}, {GapStart: 6, GapEnd: 9, MissingCount: 4}},
+		},
+		{
+			name:     "unsorted with duplicates",
+			ids:      []string{"10", "5", "1", "5", "2", "4"},
+			wantGaps: []IDGap{{GapStart: 3, GapEnd: 3, MissingCount: 1}, {GapStart: 6, GapEnd: 9, MissingCount: 4}},
+		},
+		{
+			name: "no gaps",
+			ids:  []string{"1003", "1001", "1002"},
+		},
+		{
+			name:        "non-integer IDs are skipped",
+			ids:         []string{"1005", "abc", "1009", "mail_7"},
+			wantGaps:    []IDGap{{GapStart: 1006, GapEnd: 1008, MissingCount: 3}},
+			wantSkipped: []string{"abc", "mail_7"},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gaps, skipped := findSequentialGaps(mailsWithIDs(tt.ids...))
+			if !slices.Equal(gaps, tt.wantGaps) {
+				t.Errorf("gaps = %v, want %v", gaps, tt.wantGaps)
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+// salesTo returns one sale of item to each of buyers
+func salesTo(item string, buyers ...string) []MailData {
+	mails := make([]MailData, len(buyers))
+	for i, buyer := range buyers {
+		mails[i] = testSale(item+strconv.Itoa(i), date(2024, time.January, 1+i), item, buyer, 100)
+	}
+	return mails
+}
+
+func TestComputeItemDemandIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		mails   []MailData
+		want    map[string]float64
+		wantTop []string
+	}{
+		{
+			name: "broad and repeat demand",
+			mails: slices.Concat(
+				salesTo("Rifle", "Han", "Leia", "Luke", "Chewbacca", "Lando"),
+				salesTo("Pistol", "Han", "Han", "Han", "Han", "Han"),
+			),
+			want:    map[string]float64{"Rifle": 1, "Pistol": 0.2},
+			wantTop: []string{"Rifle", "Pistol"},
+		},
+		{
+			name: "ties go to more sales",
+			mails: slices.Concat(
+				salesTo("Food", "Han"),
+				salesTo("Armor", "Han", "Leia"),
+				salesTo("Carbine", "Han", "Leia", "Han", "Leia"),
+			),
+			want:    map[string]float64{"Food": 1, "Armor": 1, "Carbine": 0.5},
+			wantTop: []string{"Armor", "Food", "Carbine"},
+		},
+		{
+			name:    "sales without buyer are ignored",
+			mails:   append(salesTo("Rifle", "Han"), salesTo("Pistol", "")...),
+			want:    map[string]float64{"Rifle": 1},
+			wantTop: []string{"Rifle"},
+		},
+		{
+			name:    "no sales",
+			mails:   []MailData{{MailID: "1", Sender: "Han Solo"}},
+			want:    map[string]float64{},
+			wantTop: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeItemDemandIndex(tt.mails); !maps.Equal(got, tt.want) {
+				t.Errorf("computeItemDemandIndex() = %v, want %v", got, tt.want)
+			}
+
+			stats := generateMailStats(tt.mails)
+			if !maps.Equal(stats.ItemDemandIndex, tt.want) {
+				t.Errorf("ItemDemandIndex = %v, want %v", stats.ItemDemandIndex, tt.want)
+			}
+			top := make([]string, len(stats.TopDemandItems))
+			for i, item := range stats.TopDemandItems {
+				top[i] = item.ItemName
+			}
+			if !slices.Equal(top, tt.wantTop) {
+				t.Errorf("TopDemandItems = %v, want %v", top, tt.wantTop)
+			}
+		})
+	}
+}
+
+func TestAggregateLocations(t *testing.T) {
+	mails := []MailData{
+		{MailID: "1", City: "Mos Eisley", Planet: "Tatooine", Price: 1000},
+		{MailID: "2", City: "Mos Eisley", Planet: "Tatooine", Price: 500},
+		{MailID: "3", City: "Theed", Planet: "Naboo", Price: 250},
+		// A player mail that names the planet only
+		{MailID: "4", Planet: "Naboo"},
+		{MailID: "5"},
+	}
+
+	add, apply := aggregateLocations()
+	for i := range mails {
+		add(&mails[i])
+	}
+	var stats MailStats
+	apply(&stats)
+
+	wantMailsByCity := map[string]int{"Mos Eisley": 2, "Theed": 1}
+	wantRevenueByCity := map[string]int64{"Mos Eisley": 1500, "Theed": 250}
+	wantMailsByPlanet := map[string]int{"Tatooine": 2, "Naboo": 2}
+	wantRevenueByPlanet := map[string]int64{"Tatooine": 1500, "Naboo": 250}
+	if !maps.Equal(stats.MailCountByCity, wantMailsByCity) {
+		t.Errorf("MailCountByCity = %v, want %v", stats.MailCountByCity, wantMailsByCity)
+	}
+	if !maps.Equal(stats.RevenueByCity, wantRevenueByCity) {
+		t.Errorf("RevenueByCity = %v, want %v", stats.RevenueByCity, wantRevenueByCity)
+	}
+	if !maps.Equal(stats.MailCountByPlanet, wantMailsByPlanet) {
+		t.Errorf("MailCountByPlanet = %v, want %v", stats.MailCountByPlanet, wantMailsByPlanet)
+	}
+	if !maps.Equal(stats.RevenueByPlanet, wantRevenueByPlanet) {
+		t.Errorf("RevenueByPlanet = %v, want %v", stats.RevenueByPlanet, wantRevenueByPlanet)
+	}
+}
+
+func TestCanonicalItemName(t *testing.T) {
+	tests := []struct {
+		name string
+		mail MailData
+		want string
+	}{
+		{"canonical name", MailData{ItemName: "CDEF rifle", ItemKey: "CDEF rifle", CanonicalItemName: "CDEF Rifle"}, "CDEF Rifle"},
+		{"item key", MailData{ItemName: "Heavy Blaster #a1b2c3", ItemKey: "Heavy Blaster"}, "Heavy Blaster"},
+		{"item name", MailData{ItemName: "Heavy Blaster"}, "Heavy Blaster"},
+		{"no item", MailData{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalItemName(tt.mail); got != tt.want {
+				t.Errorf("canonicalItemName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseItemDB(t *testing.T) {
+	itemDB := map[string]string{
+		"CDEF rifle":       "CDEF Rifle",
+		"Polysteel Copper": "Polysteel Copper Ore",
+	}
+
+	tests := []struct {
+		item      string
+		canonical string
+		itemKey   string
+	}{
+		{"CDEF rifle", "CDEF Rifle", "CDEF rifle"},
+		// Looked up by the item key when the item name is not known
+		{"(100000) Polysteel Copper", "Polysteel Copper Ore", "Polysteel Copper"},
+		{"Heavy Blaster #a1b2c3", "", "Heavy Blaster"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.item, func(t *testing.T) {
+			lines := []string{"1", "SWG.Restoration.auctioner", "Vendor Sale Complete", "TIMESTAMP: 1700000000",
+				"Vendor: Crafter has sold " + tt.item + " to Han for 1000 credits."}
+			mail, err := parseMailLines("1.mail", lines, time.Time{}, ParseOptions{ItemDB: itemDB})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mail.CanonicalItemName != tt.canonical || mail.ItemKey != tt.itemKey {
+				t.Errorf("CanonicalItemName, ItemKey = %q, %q, want %q, %q", mail.CanonicalItemName, mail.ItemKey, tt.canonical, tt.itemKey)
+			}
+		})
+	}
+}
+
+func TestFindUnrecognizedItems(t *testing.T) {
+	itemDB := map[string]string{
+		"CDEF rifle":    "CDEF Rifle",
+		"Heavy Blaster": "Heavy Blaster",
+	}
+	mails := []MailData{
+		{MailID: "1", ItemName: "CDEF rifle", ItemKey: "CDEF rifle"},
+		// Known by its item key
+		{MailID: "2", ItemName: "Heavy Blaster #a1b2c3", ItemKey: "Heavy Blaster"},
+		{MailID: "3", ItemName: "Wookiee Armor", ItemKey: "Wookiee Armor"},
+		{MailID: "4", ItemName: "Bantha Doll", ItemKey: "Bantha Doll"},
+		{MailID: "5", ItemName: "Bantha Doll", ItemKey: "Bantha Doll"},
+		{MailID: "6"},
+	}
+
+	want := []string{"Bantha Doll", "Wookiee Armor"}
+	if got := findUnrecognizedItems(mails, itemDB); !slices.Equal(got, want) {
+		t.Errorf("findUnrecognizedItems() = %v, want %v", got, want)
+	}
+}
+
+func TestSubjectClusters(t *testing.T) {
+	var mails []MailData
+	for i, subject := range []string{
+		"Vendor Sale Complete",
+		"[AUTO] Vendor Sale Complete!",
+		"**Notice** Vendor Sale Complete.",
+		"Item Expired",
+		"[AUTO] Item Expired",
+		"Guild meeting",
+	} {
+		lines := []string{strconv.Itoa(i + 1), "Han Solo", subject, "TIMESTAMP: 1700000000", "Body"}
+		mail, err := parseMailLines("mail", lines, time.Time{}, ParseOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mails = append(mails, *mail)
+	}
+
+	want := map[string]int{"Vendor Sale Complete": 3, "Item Expired": 2, "Guild meeting": 1}
+	if got := generateMailStats(mails).SubjectClusters; !maps.Equal(got, want) {
+		t.Errorf("SubjectClusters = %v, want %v", got, want)
+	}
+}
+
+// parseTestSale parses a sale notification of the auctioneer with body
+func parseTestSale(t *testing.T, id string, at time.Time, body string) MailData {
+	t.Helper()
+	lines := []string{id, auctioneerSender, "Sale Complete", "TIMESTAMP: " + strconv.FormatInt(at.Unix(), 10), body}
+	mail, err := parseMailLines(id+".mail", lines, time.Time{}, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *mail
+}
+
+func TestAggregateRevenue(t *testing.T) {
+	at := date(2024, time.January, 15)
+	vendorRifle := parseTestSale(t, "1", at, "Vendor: Crafter has sold Rifle to Han for 1000 credits.")
+	vendorPistol := parseTestSale(t, "2", at, "Vendor: Crafter has sold Pistol to Leia for 500 credits.")
+	bazaarArmor := parseTestSale(t, "3", at, "Your auction of Armor has been sold to Luke for 300 credits.")
+	bazaarFood := parseTestSale(t, "4", at, "Your auction of Food has been sold to Chewbacca for 200 credits.")
+	letter := MailData{MailID: "5", Sender: "Han Solo", Timestamp: at, MailCategory: CategoryPlayer}
+
+	tests := []struct {
+		name              string
+		mails             []MailData
+		saleNotifications int
+		vendorSales       int
+		bazaarSales       int
+		vendorRevenue     int64
+		bazaarRevenue     int64
+		ratio             float64
+	}{
+		{
+			name:              "vendor and bazaar sales",
+			mails:             []MailData{vendorRifle, vendorPistol, bazaarArmor, bazaarFood, letter},
+			saleNotifications: 4,
+			vendorSales:       2,
+			bazaarSales:       2,
+			vendorRevenue:     1500,
+			bazaarRevenue:     500,
+			ratio:             3,
+		},
+		{
+			name:              "no bazaar revenue",
+			mails:             []MailData{vendorRifle, vendorPistol, letter},
+			saleNotifications: 2,
+			vendorSales:       2,
+			vendorRevenue:     1500,
+			ratio:             0,
+		},
+		{
+			name:              "bazaar sales only",
+			mails:             []MailData{bazaarArmor, bazaarFood},
+			saleNotifications: 2,
+			bazaarSales:       2,
+			bazaarRevenue:     500,
+			ratio:             0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, apply := aggregateRevenue()
+			for i := range tt.mails {
+				add(&tt.mails[i])
+			}
+			var stats MailStats
+			apply(&stats)
+
+			if stats.SaleNotifications != tt.saleNotifications || stats.VendorSaleCount != tt.vendorSales || stats.BazaarSaleCount != tt.bazaarSales {
+				t.Errorf("sales, vendor sales, bazaar sales = %d, %d, %d, want %d, %d, %d",
+					stats.SaleNotifications, stats.VendorSaleCount, stats.BazaarSaleCount, tt.saleNotifications, tt.vendorSales, tt.bazaarSales)
+			}
+			if stats.TotalRevenue != tt.vendorRevenue+tt.bazaarRevenue || stats.VendorRevenue != tt.vendorRevenue || stats.BazaarRevenue != tt.bazaarRevenue {
+				t.Errorf("revenue, vendor revenue, bazaar revenue = %d, %d, %d, want %d, %d, %d",
+					stats.TotalRevenue, stats.VendorRevenue, stats.BazaarRevenue, tt.vendorRevenue+tt.bazaarRevenue, tt.vendorRevenue, tt.bazaarRevenue)
+			}
+			if stats.VendorToBazaarRatio != tt.ratio {
+				t.Errorf("VendorToBazaarRatio = %v, want %v", stats.VendorToBazaarRatio, tt.ratio)
+			}
+		})
+	}
+}
+
+func TestPriceType(t *testing.T) {
+	at := date(2024, time.January, 15)
+	mails := []MailData{
+		parseTestSale(t, "1", at, "Your auction of Rifle has been sold to Han for 1000 credits. Han won the bid."),
+		parseTestSale(t, "2", at, "Your auction of Pistol has been sold to Leia for 501 credits after Leia WON THE BID."),
+		parseTestSale(t, "3", at, "Your auction of Armor has been sold to Luke for 300 credits. It was purchased at the listed price."),
+		parseTestSale(t, "4", at, "Vendor: Crafter has sold Food to Chewbacca for 200 credits."),
+		{MailID: "5", Sender: "Han Solo", Timestamp: at, MailCategory: CategoryPlayer, Body: "I won the bid!"},
+	}
+
+	wantTypes := []string{PriceTypeBid, PriceTypeBid, PriceTypeBuyNow, PriceTypeUnknown, ""}
+	for i, mail := range mails {
+		if mail.PriceType != wantTypes[i] {
+			t.Errorf("mail %s has PriceType %q, want %q", mail.MailID, mail.PriceType, wantTypes[i])
+		}
+	}
+
+	stats := generateMailStats(mails)
+	if stats.BidSaleCount != 2 || stats.BuyNowSaleCount != 1 {
+		t.Errorf("BidSaleCount, BuyNowSaleCount = %d, %d, want 2, 1", stats.BidSaleCount, stats.BuyNowSaleCount)
+	}
+	// Averages are rounded down to whole credits
+	if stats.AvgBidPrice != 750 || stats.AvgBuyNowPrice != 300 {
+		t.Errorf("AvgBidPrice, AvgBuyNowPrice = %d, %d, want 750, 300", stats.AvgBidPrice, stats.AvgBuyNowPrice)
+	}
+
+	// Without sales of a price type its average is 0
+	stats = generateMailStats(mails[3:])
+	if stats.AvgBidPrice != 0 || stats.AvgBuyNowPrice != 0 {
+		t.Errorf("AvgBidPrice, AvgBuyNowPrice without bid and buy-now sales = %d, %d, want 0, 0", stats.AvgBidPrice, stats.AvgBuyNowPrice)
+	}
+}
+
+func TestComputeTopItemsDaysActive(t *testing.T) {
+	mails := []MailData{
+		// Out of order, the first and last sale are found by timestamp
+		testSale("1", date(2024, time.January, 11), "Rifle", "Han", 100),
+		testSale("2", date(2024, time.January, 1), "Rifle", "Leia", 100),
+		testSale("3", date(2024, time.January, 4).Add(12*time.Hour), "Rifle", "Luke", 100),
+		testSale("4", date(2024, time.January, 5), "Pistol", "Han", 50),
+	}
+
+	tests := []struct {
+		item       string
+		first      time.Time
+		last       time.Time
+		daysActive float64
+	}{
+		{"Rifle", date(2024, time.January, 1), date(2024, time.January, 11), 10},
+		// A single sale is active for no time
+		{"Pistol", date(2024, time.January, 5), date(2024, time.January, 5), 0},
+	}
+
+	items := computeTopItems(mails, 0)
+	if len(items) != len(tests) {
+		t.Fatalf("got %d items, want %d", len(items), len(tests))
+	}
+	for i, tt := range tests {
+		item := items[i]
+		if item.ItemName != tt.item || !item.FirstSoldAt.Equal(tt.first) || !item.LastSoldAt.Equal(tt.last) || item.DaysActive != tt.daysActive {
+			t.Errorf("item %d = %s sold %v to %v, %v days, want %s sold %v to %v, %v days", i,
+				item.ItemName, item.FirstSoldAt, item.LastSoldAt, item.DaysActive, tt.item, tt.first, tt.last, tt.daysActive)
+		}
+	}
+
+	// Fractions of days are kept
+	items = computeTopItems(mails[1:3], 0)
+	if want := 3.5; items[0].DaysActive != want {
+		t.Errorf("DaysActive = %v, want %v", items[0].DaysActive, want)
+	}
+}
+
+func TestComputePriceTrend(t *testing.T) {
+	// One Rifle sale per week over 8 weeks, starting with ISO week 1 of 2024
+	prices := []int64{1000, 1100, 1000, 1010, 1000, 990, 1000, 980}
+	var mails []MailData
+	for i, price := range prices {
+		mails = append(mails, testSale(strconv.Itoa(i+1), date(2024, time.January, 1+7*i), "Rifle", "Han", price))
+	}
+
+	want := []struct {
+		period string
+		trend  string
+	}{
+		{"2024-W01", TrendFlat},
+		{"2024-W02", TrendUp},   // +10%
+		{"2024-W03", TrendDown}, // -9.1%
+		// Changes of up to priceTrendFlatThreshold percent are flat
+		{"2024-W04", TrendFlat}, // +1%
+		{"2024-W05", TrendFlat}, // -0.99%
+		{"2024-W06", TrendFlat}, // -1%
+		{"2024-W07", TrendUp},   // +1.01%
+		{"2024-W08", TrendDown}, // -2%
+	}
+
+	points := ComputePriceTrend(mails, "Rifle", "week")
+	if len(points) != len(want) {
+		t.Fatalf("got %d points, want %d", len(points), len(want))
+	}
+	for i, w := range want {
+		point := points[i]
+		if point.Period != w.period || point.Trend != w.trend || point.AvgPrice != prices[i] {
+			t.Errorf("point %d = %s %s at %d (%+.2f%%), want %s %s at %d",
+				i, point.Period, point.Trend, point.AvgPrice, point.WeekOverWeekChangePct, w.period, w.trend, prices[i])
+		}
+	}
+	if points[0].WeekOverWeekChangePct != 0 || points[1].WeekOverWeekChangePct != 10 {
+		t.Errorf("WeekOverWeekChangePct = %v, %v, want 0, 10", points[0].WeekOverWeekChangePct, points[1].WeekOverWeekChangePct)
+	}
+}
+
+func TestComputeBalanceSeries(t *testing.T) {
+	mails := []MailData{
+		testSale("3", date(2024, time.January, 3), "Armor", "Luke", 300),
+		testSale("1", date(2024, time.January, 1), "Rifle", "Han", 1000),
+		{MailID: "4", Timestamp: date(2024, time.January, 4), Sender: "Han Solo"},
+		testSale("5", date(2024, time.January, 5), "Food", "Chewbacca", 50),
+		testSale("2", date(2024, time.January, 2), "Pistol", "Leia", 500),
+	}
+
+	want := []BalancePoint{
+		{date(2024, time.January, 1), "1", BalanceEventSale, 1000, 11000},
+		{date(2024, time.January, 2), "2", BalanceEventSale, 500, 11500},
+		{date(2024, time.January, 3), "3", BalanceEventSale, 300, 11800},
+		{date(2024, time.January, 4), "4", BalanceEventNonRevenue, 0, 11800},
+		{date(2024, time.January, 5), "5", BalanceEventSale, 50, 11850},
+	}
+	if got := ComputeBalanceSeries(mails, 10000); !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeBalanceSeries() = %+v, want %+v", got, want)
+	}
+	// The input is left in its order
+	if got := mailIDs(mails); !slices.Equal(got, []string{"3", "1", "4", "5", "2"}) {
+		t.Errorf("input reordered to %v", got)
+	}
+}
+
+// senderTreeDepth returns the number of levels of a sender tree and the sum
+// of its leaf counts
+func senderTreeDepth(tree map[string]interface{}) (depth, total int) {
+	for _, node := range tree {
+		switch node := node.(type) {
+		case int:
+			depth = max(depth, 1)
+			total += node
+		case map[string]interface{}:
+			subDepth, subTotal := senderTreeDepth(node)
+			depth = max(depth, subDepth+1)
+			total += subTotal
+		}
+	}
+	return depth, total
+}
+
+func TestBuildSenderTree(t *testing.T) {
+	senders := map[string]int{
+		"SWG.Restoration.auctioner": 3,
+		"SWG.Restoration.system":    2,
+		// A sender that is a prefix of others keeps its count in _count
+		"SWG.Restoration": 1,
+		"Han Solo":        5,
+	}
+	want := map[string]interface{}{
+		"SWG": map[string]interface{}{
+			"Restoration": map[string]interface{}{
+				"auctioner":        3,
+				"system":           2,
+				senderTreeCountKey: 1,
+			},
+		},
+		"Han Solo": 5,
+	}
+
+	tree := buildSenderTree(senders)
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("buildSenderTree() = %v, want %v", tree, want)
+	}
+	if depth, total := senderTreeDepth(tree); depth != 3 || total != 11 {
+		t.Errorf("tree has depth %d and %d mails, want 3 and 11", depth, total)
+	}
+
+	// The prefix sender may come before or after the senders it is a prefix of
+	for _, order := range [][]string{
+		{"SWG.Restoration", "SWG.Restoration.auctioner", "SWG.Restoration.system", "Han Solo"},
+		{"SWG.Restoration.auctioner", "SWG.Restoration.system", "SWG.Restoration", "Han Solo"},
+	} {
+		tree := make(map[string]interface{})
+		for _, sender := range order {
+			insertSenderTree(tree, strings.Split(sender, "."), senders[sender])
+		}
+		if !reflect.DeepEqual(tree, want) {
+			t.Errorf("inserting %v = %v, want %v", order, tree, want)
+		}
+	}
+}
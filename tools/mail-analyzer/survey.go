@@ -0,0 +1,51 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Survey droid reports list the concentration of a resource per planet.
+//
+// Expected format:
+// "Interplanetary Survey: Polysteel Copper" (subject)
+// "Interplanetary survey results for Polysteel Copper:"
+// "Naboo: 45%"
+// "Tatooine - 12.5%"
+var (
+	surveyReportPattern        = regexp.MustCompile(`(?i)interplanetary survey`)
+	surveyResourcePattern      = regexp.MustCompile(`(?im)survey (?:results )?(?:of|for) (?:\[.*?\] )?(.+?)(?:\s+\([^)]*\))?(?:\s+was performed.*)?[:.]?[ \t]*$`)
+	surveySubjectPattern       = regexp.MustCompile(`(?i)survey:\s*(.+)`)
+	surveyConcentrationPattern = regexp.MustCompile(`(?im)^[ \t*•-]*(` + planetNames + `)\b[^%\n]*?(\d+(?:\.\d+)?)[ \t]*%`)
+)
+
+// parseSurveyResults extracts the resource concentrations per planet of a
+// survey report. It returns nil for mails without a resource name or
+// without concentrations.
+func parseSurveyResults(subject, body string) []SurveyResult {
+	var resource string
+	if matches := surveyResourcePattern.FindStringSubmatch(body); matches != nil {
+		resource = strings.TrimSpace(matches[1])
+	} else if matches := surveySubjectPattern.FindStringSubmatch(subject); matches != nil {
+		resource = strings.TrimSpace(matches[1])
+	}
+	if resource == "" {
+		return nil
+	}
+
+	var results []SurveyResult
+	for _, matches := range surveyConcentrationPattern.FindAllStringSubmatch(body, -1) {
+		concentration, err := strconv.ParseFloat(matches[2], 64)
+		if err != nil {
+			continue
+		}
+		_, planet := normalizeLocation("", matches[1])
+		results = append(results, SurveyResult{
+			Resource:      resource,
+			Planet:        planet,
+			Concentration: concentration,
+		})
+	}
+	return results
+}
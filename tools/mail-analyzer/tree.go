@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// renderSenderTree writes a sender tree as an ASCII tree in the style of
+// tree(1), annotating every node with its total mail count
+func renderSenderTree(w io.Writer, tree map[string]interface{}) {
+	renderSenderSubtree(w, tree, "")
+}
+
+func renderSenderSubtree(w io.Writer, tree map[string]interface{}, prefix string) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		if name != senderTreeCountKey {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		fmt.Fprintf(w, "%s%s%s (%d)\n", prefix, branch, name, senderTreeTotal(tree[name]))
+		if subtree, ok := tree[name].(map[string]interface{}); ok {
+			renderSenderSubtree(w, subtree, prefix+indent)
+		}
+	}
+}
+
+// senderTreeTotal returns the total mail count of a sender tree node
+func senderTreeTotal(node interface{}) int {
+	switch n := node.(type) {
+	case int:
+		return n
+	case map[string]interface{}:
+		total := 0
+		for _, child := range n {
+			total += senderTreeTotal(child)
+		}
+		return total
+	default:
+		return 0
+	}
+}
@@ -1,6 +1,12 @@
 package main
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
 
 // MailData represents raw mail data extracted from mail files
 type MailData struct {
@@ -10,12 +16,408 @@ type MailData struct {
 	Timestamp time.Time `json:"timestamp"`
 	Body      string    `json:"body"`
 	Location  string    `json:"location,omitempty"`
+	City      string    `json:"city,omitempty"`
+	Planet    string    `json:"planet,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+
+	// MailIDNormalized is MailID as zero-padded hex, set by --normalize-ids
+	MailIDNormalized string `json:"mail_id_normalized,omitempty"`
+
+	// ContentHash identifies the mail by its content, see contentHash
+	ContentHash string `json:"content_hash,omitempty"`
+
+	// Galaxy is the server the mail was received on, the second segment of
+	// system senders like "SWG.Restoration.auctioner" or set with --galaxy
+	Galaxy string `json:"galaxy,omitempty"`
+
+	// Source is the absolute path of the input directory the mail was parsed from
+	Source string `json:"source,omitempty"`
+
+	// Recovered is set for mails whose malformed header was reconstructed
+	// by --recover-headers, see recoverMailHeader
+	Recovered bool `json:"recovered,omitempty"`
+
+	// Character is the directory the mail file was found in, set by
+	// --infer-character-from-dir
+	Character string `json:"character,omitempty"`
+
+	// MailCategory is one of "sale", "purchase", "auction", "expired", "factory",
+	// "survey", "player" or "unknown"
+	MailCategory string `json:"mail_category"`
+
+	// MailType is what the mail is about, see classifyMailType
+	MailType MailType `json:"mail_type,omitempty"`
+
+	// BroadcastName is the city or guild of city and guild broadcasts,
+	// see parseBroadcastName
+	BroadcastName string `json:"broadcast_name,omitempty"`
+
+	// SaleType is "vendor", "bazaar" or "unknown" for sale mails and empty otherwise
+	SaleType string `json:"sale_type,omitempty"`
+
+	// NormalizedSubject is the subject without server script prefixes such
+	// as "[AUTO]", see normalizeSubject
+	NormalizedSubject string `json:"normalized_subject,omitempty"`
+
+	// Sender parts of dot-separated system senders like "SWG.Restoration.auctioner"
+	SenderDomain    string `json:"sender_domain,omitempty"`
+	SenderSubsystem string `json:"sender_subsystem,omitempty"`
+
+	// SenderLabel is the human-readable name of a known system sender
+	SenderLabel string `json:"sender_label,omitempty"`
+
+	// Sale details extracted from the body; CanonicalItemName is the item
+	// database name for ItemName, if known
+	ItemName          string `json:"item_name,omitempty"`
+	CanonicalItemName string `json:"canonical_item_name,omitempty"`
+	Buyer             string `json:"buyer,omitempty"`
+	Price             int64  `json:"price,omitempty"`
+
+	// ItemKey is ItemName without stack counts, serial numbers and similar
+	// suffixes, see normalizeItemKey
+	ItemKey string `json:"item_key,omitempty"`
+
+	// ItemCategory is the kind of item sold, e.g. "Weapon", see classifyItem
+	ItemCategory string `json:"item_category,omitempty"`
+
+	// SerialNumber is the serial of a crafted item, see parseSerialNumber
+	SerialNumber string `json:"serial_number,omitempty"`
+
+	// Stack size of resource and other stack sales, see parseUnitCount, and
+	// the price divided by it
+	UnitCount    int64   `json:"unit_count,omitempty"`
+	PricePerUnit float64 `json:"price_per_unit,omitempty"`
+
+	// VendorName is the player vendor that made a vendor sale
+	VendorName string `json:"vendor_name,omitempty"`
+
+	// PriceType is "bid", "buy_now" or "unknown" for sale mails and empty otherwise
+	PriceType string `json:"price_type,omitempty"`
+
+	// Sale is set for sale notifications, see isSaleNotification and
+	// serverPatterns
+	Sale *SaleData `json:"sale,omitempty"`
+
+	// Purchase is set for "Auction Item Purchased" notifications, see parsePurchase
+	Purchase *PurchaseData `json:"purchase,omitempty"`
+
+	// Auction is set for auction won and outbid notifications, see parseAuction
+	Auction *AuctionData `json:"auction,omitempty"`
+
+	// Expired is set for notifications about unsold items that expired on
+	// the bazaar, see parseExpiredItem
+	Expired *ExpiredItem `json:"expired,omitempty"`
+
+	// FactoryRun is set for factory run completion notifications, see
+	// parseFactoryRun
+	FactoryRun *FactoryRun `json:"factory_run,omitempty"`
+
+	// Income is set for mission and GCW payouts, see parseIncome
+	Income *IncomeData `json:"income,omitempty"`
+
+	// SurveyResults lists the resource concentrations of survey reports,
+	// see parseSurveyResults
+	SurveyResults []SurveyResult `json:"survey_results,omitempty"`
+
+	// Coordinates embedded in the body, only meaningful when HasCoordinates is set
+	HasCoordinates bool    `json:"has_coordinates,omitempty"`
+	LocationX      float64 `json:"location_x,omitempty"`
+	LocationY      float64 `json:"location_y,omitempty"`
+	LocationZ      float64 `json:"location_z,omitempty"`
+
+	// Waypoints embedded in the body, see parseWaypoints
+	Waypoints []Waypoint `json:"waypoints,omitempty"`
+}
+
+// Waypoint is a waypoint shared in a mail, e.g. "tatooine 3500 -4800 12
+// Krayt Graveyard" with the coordinates in x, z, y order
+type Waypoint struct {
+	// Planet is the lowercase planet name, e.g. "tatooine" or "yavin4"
+	Planet string  `json:"planet"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Z      float64 `json:"z"`
+	Name   string  `json:"name,omitempty"`
+}
+
+// SaleData holds the details of a sale notification
+type SaleData struct {
+	ItemName string `json:"item_name"`
+	Buyer    string `json:"buyer"`
+	Price    int64  `json:"price"`
+
+	// SaleChannel is "vendor", "bazaar" or "unknown", the SaleType of the
+	// mail
+	SaleChannel string `json:"sale_channel"`
+	VendorName  string `json:"vendor_name,omitempty"`
+
+	// Category is the ItemCategory of the mail
+	Category string `json:"category,omitempty"`
+
+	// SerialNumber is set for crafted items, see MailData.SerialNumber
+	SerialNumber string `json:"serial_number,omitempty"`
+
+	// Set for stack sales, see MailData.UnitCount
+	UnitCount    int64   `json:"unit_count,omitempty"`
+	PricePerUnit float64 `json:"price_per_unit,omitempty"`
+}
+
+// PurchaseData holds the details of an item bought on the bazaar or from
+// a vendor
+type PurchaseData struct {
+	ItemName string `json:"item_name"`
+	Seller   string `json:"seller"`
+	Price    int64  `json:"price"`
+	Location string `json:"location,omitempty"`
+}
+
+// AuctionData holds the details of an auction won or outbid notification
+type AuctionData struct {
+	// Event is "won" or "outbid"
+	Event    string `json:"event"`
+	ItemName string `json:"item_name"`
+
+	// Bid is the winning bid for won auctions and the new high bid of the
+	// other bidder for outbid notifications
+	Bid int64 `json:"bid"`
+}
+
+// ExpiredItem holds the details of an item that expired unsold
+type ExpiredItem struct {
+	ItemName string `json:"item_name"`
+	Location string `json:"location,omitempty"`
+}
+
+// IncomeData holds a payout other than a sale
+type IncomeData struct {
+	// Source is "mission" or "gcw"
+	Source  string `json:"source"`
+	Credits int64  `json:"credits"`
+}
+
+// SurveyResult is the concentration of a resource on a planet, in percent
+type SurveyResult struct {
+	Resource      string  `json:"resource"`
+	Planet        string  `json:"planet"`
+	Concentration float64 `json:"concentration"`
+}
+
+// FactoryRun holds the output of a completed factory run. ItemKey groups
+// the item like the item_key of sales.
+type FactoryRun struct {
+	Factory  string `json:"factory"`
+	ItemName string `json:"item_name"`
+	ItemKey  string `json:"item_key"`
+	Quantity int64  `json:"quantity"`
+}
+
+// FilterOpts selects which mails are kept, both at parse time and when
+// filtering an existing batch
+type FilterOpts struct {
+	SenderFilter  string
+	SenderDomain  string
+	SubjectFilter string
+	Category      string
+	MailType      MailType
+	SaleType      string
+	StartDate     time.Time
+	EndDate       time.Time
+	TagFilter     []string
+	TagAny        bool
+	NotTags       []string
+
+	// UseNormalizedSubject matches SubjectFilter against NormalizedSubject
+	UseNormalizedSubject bool
+}
+
+// ParseOptions controls which mail files are parsed and kept
+type ParseOptions struct {
+	FilterOpts
+
+	Verbose   bool
+	StrictIDs bool
+
+	// Strict validates that required fields are present, see validateMail
+	Strict bool
+
+	// FailOnError fails the parse on the first mail file that cannot be
+	// parsed instead of skipping it
+	FailOnError bool
+
+	// NoDedupContent keeps mails whose ContentHash was seen before under
+	// another mail ID, which are dropped by default
+	NoDedupContent bool
+
+	// NormalizeIDs sets MailIDNormalized, see normalizeMailID
+	NormalizeIDs bool
+
+	// InferCharacterFromDir sets Character from the parent directory of
+	// mail files in subdirectories of the input directory
+	InferCharacterFromDir bool
+
+	// ItemDB maps raw item names to canonical item names
+	ItemDB map[string]string
+
+	// ItemKeyRules replace defaultItemKeyRules when set
+	ItemKeyRules []itemKeyRule
+
+	// ItemCategoryRules replace defaultItemCategoryRules when set
+	ItemCategoryRules []itemCategoryRule
+
+	// MailClassifiers replace defaultMailClassifiers when set
+	MailClassifiers []MailClassifier
+
+	// Patterns recognize the sale notifications of other servers, see
+	// --patterns-file
+	Patterns *serverPatterns
+
+	// Galaxy is stamped on every mail instead of detecting it from the sender
+	Galaxy string
+
+	// Tags are stamped on every mail, see --tag
+	Tags []string
+
+	// SystemSenders maps senders to labels, SystemSenders if nil
+	SystemSenders map[string]string
+
+	// TimestampFormat is "unix" or a time.Parse layout for the TIMESTAMP line
+	TimestampFormat string
+
+	// ScannerBufferSize is the maximum line length accepted in mail files
+	ScannerBufferSize int
+
+	// Cache, if set, serves the parse results of unchanged mail file
+	// contents, see --cache-dir
+	Cache *parseCache
+
+	// MaxFileSize is the size in bytes of the largest mail file parsed;
+	// larger files fail to parse. 0 uses defaultMaxFileSize.
+	MaxFileSize int64
+
+	// RecoverHeaders is the number of lines searched for the header of
+	// mails with a malformed header; 0 drops those mails
+	RecoverHeaders int
+
+	// ParseTimeout bounds the time spent on a single mail file; 0 disables it
+	ParseTimeout time.Duration
+
+	// Workers bounds the number of mail files parsed at once; 0 uses one
+	// per CPU
+	Workers int
+
+	// Transient read errors are retried up to MaxRetries times, waiting
+	// RetryBackoff before the first retry and doubling it for each next one
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// OnRetriedRead, if set, is called for files read only after retrying
+	OnRetriedRead func(filename string)
+
+	// ReadFile reads the content of a mail file, readMailFile if nil
+	ReadFile func(filename string, maxSize int64) ([]byte, error)
+
+	// Progress, if set, is updated as mail files are parsed
+	Progress *progressTracker
+
+	// Checkpoint, if set, skips the mail files processed before it was
+	// saved and saves the progress periodically, see --resume
+	Checkpoint *parseCheckpoint
+
+	// OnMail, if set, is called with every mail kept in directory order,
+	// instead of collecting the mails into ParseResult.Mails
+	OnMail func(mail MailData) error
+
+	// State, if set, skips input files and mails processed by earlier runs
+	// and records the ones processed now, see --state-file
+	State *parseState
+}
+
+// ParseError describes why a single mail file could not be parsed
+type ParseError struct {
+	File string
+	Err  error
+	Hint string
+
+	// Field is the MailData field that failed validation, if any
+	Field string
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.File, e.Err)
+	if e.Field != "" {
+		msg = fmt.Sprintf("%s: %s: %v", e.File, e.Field, e.Err)
+	}
+	if e.Hint != "" {
+		msg += fmt.Sprintf(" (%s)", e.Hint)
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseResult represents the outcome of parsing a mail directory
+type ParseResult struct {
+	Mails                 []MailData
+	UnreadableDirectories []string
+	RetriedFiles          int
+	ValidationFailures    []ValidationFailure
+
+	// ParsedFiles lists every mail file that was parsed successfully. It is
+	// left empty for parses with OnMail, which do not collect mails either.
+	ParsedFiles []string
+
+	// DuplicateMails counts mails dropped for an already seen mail ID or
+	// content hash, ContentDuplicates those dropped for their content hash
+	DuplicateMails    int
+	ContentDuplicates int
+
+	// UnchangedFiles and KnownMails count the input files and mails skipped
+	// because an earlier run processed them, see ParseOptions.State
+	UnchangedFiles int
+	KnownMails     int
+
+	// SkippedFiles lists every mail file left out and why
+	SkippedFiles []SkippedFile
+}
+
+// SkippedFile is a mail file or path left out of a parse, listed in the
+// errors report
+type SkippedFile struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+
+	// Duplicate is set for mail files that parsed but were dropped as a
+	// duplicate of an earlier mail
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+// ValidationFailure describes a mail field rejected by --strict
+type ValidationFailure struct {
+	MailID    string `json:"mail_id"`
+	FieldName string `json:"field_name"`
+	Reason    string `json:"reason"`
 }
 
 // MailBatch represents a collection of mail data for batch import
 type MailBatch struct {
+	// SchemaVersion is set to CurrentSchemaVersion when the batch is
+	// written, see MigrateBatch
+	SchemaVersion int `json:"schema_version"`
+
 	Mails []MailData `json:"mails"`
 	Stats MailStats  `json:"stats"`
+
+	// Merge is set on batches produced by the merge command
+	Merge *MergeInfo `json:"merge,omitempty"`
+}
+
+// MergeInfo describes how a merged batch was assembled
+type MergeInfo struct {
+	Inputs                   []string `json:"inputs"`
+	DuplicateIDsRemoved      int      `json:"duplicate_ids_removed"`
+	ContentDuplicatesRemoved int      `json:"content_duplicates_removed"`
 }
 
 // MailStats represents basic statistics about the parsed mail batch
@@ -24,6 +426,126 @@ type MailStats struct {
 	SaleNotifications int            `json:"sale_notifications"`
 	DateRange         DateRange      `json:"date_range"`
 	Senders           map[string]int `json:"senders"`
+	MailsBySubsystem  map[string]int `json:"mails_by_subsystem"`
+	SubjectClusters   map[string]int `json:"subject_clusters"`
+	MailsByCategory   map[string]int `json:"mails_by_category"`
+	MailsByType       map[string]int `json:"mails_by_type"`
+	TotalRevenue      int64          `json:"total_revenue"`
+
+	// Mails from senders with and without a SenderLabel
+	KnownSystemMails   int `json:"known_system_mails"`
+	UnknownSenderMails int `json:"unknown_sender_mails"`
+
+	// Revenue split between vendor and bazaar sales; the ratio is vendor
+	// revenue over bazaar revenue, 0 without bazaar revenue
+	VendorRevenue       int64   `json:"vendor_revenue"`
+	BazaarRevenue       int64   `json:"bazaar_revenue"`
+	VendorSaleCount     int     `json:"vendor_sale_count"`
+	BazaarSaleCount     int     `json:"bazaar_sale_count"`
+	VendorToBazaarRatio float64 `json:"vendor_to_bazaar_ratio"`
+
+	// Sales by price type; the averages are rounded down to whole credits
+	BidSaleCount    int   `json:"bid_sale_count"`
+	BuyNowSaleCount int   `json:"buy_now_sale_count"`
+	AvgBidPrice     int64 `json:"avg_bid_price"`
+	AvgBuyNowPrice  int64 `json:"avg_buy_now_price"`
+
+	// Items bought by the player, counted separately from sales
+	PurchaseCount    int   `json:"purchase_count"`
+	PurchaseSpending int64 `json:"purchase_spending"`
+
+	// Mission and GCW payouts, which do not count as revenue
+	OtherIncome         int64            `json:"other_income"`
+	OtherIncomeBySource map[string]int64 `json:"other_income_by_source,omitempty"`
+
+	// Auctions the player bid on; AuctionWonSpending sums the winning bids
+	AuctionsWon        int   `json:"auctions_won"`
+	AuctionsOutbid     int   `json:"auctions_outbid"`
+	AuctionWonSpending int64 `json:"auction_won_spending"`
+
+	// ExpiredCount counts items that expired unsold
+	ExpiredCount int `json:"expired_count"`
+
+	// Factory runs and the units they produced by item key
+	FactoryRuns         int              `json:"factory_runs"`
+	UnitsProducedByItem map[string]int64 `json:"units_produced_by_item,omitempty"`
+
+	// ResourceConcentrations is the latest surveyed concentration of each
+	// resource by planet
+	ResourceConcentrations map[string]map[string]float64 `json:"resource_concentrations,omitempty"`
+
+	// Vendors aggregates vendor sales by VendorName
+	Vendors map[string]VendorStats `json:"vendors"`
+
+	// Sales by ItemCategory
+	SalesByItemCategory   map[string]int   `json:"sales_by_item_category"`
+	RevenueByItemCategory map[string]int64 `json:"revenue_by_item_category"`
+
+	// GoalProgress is the percentage of the --goal credits earned, if set
+	GoalProgress float64 `json:"goal_progress,omitempty"`
+
+	// SenderTree nests senders by their dot-separated segments with mail
+	// counts as leaves, see buildSenderTree
+	SenderTree map[string]interface{} `json:"sender_tree"`
+
+	AvgInterSaleIntervalHours    float64 `json:"avg_inter_sale_interval_hours"`
+	MedianInterSaleIntervalHours float64 `json:"median_inter_sale_interval_hours"`
+
+	TopItems  []ItemRevenueStat  `json:"top_items"`
+	TopBuyers []BuyerRevenueStat `json:"top_buyers"`
+
+	// ItemDemandIndex is unique buyers per sale of each item, from 0 (one
+	// repeat buyer) to 1 (every sale to a different buyer)
+	ItemDemandIndex map[string]float64 `json:"item_demand_index"`
+	TopDemandItems  []ItemDemandStat   `json:"top_demand_items"`
+
+	MailCountByPlanet map[string]int   `json:"mail_count_by_planet"`
+	RevenueByPlanet   map[string]int64 `json:"revenue_by_planet"`
+	MailCountByCity   map[string]int   `json:"mail_count_by_city"`
+	RevenueByCity     map[string]int64 `json:"revenue_by_city"`
+
+	// Mails and revenue per Galaxy
+	MailsByGalaxy   map[string]int   `json:"mails_by_galaxy,omitempty"`
+	RevenueByGalaxy map[string]int64 `json:"revenue_by_galaxy,omitempty"`
+
+	// MailsBySource counts mails per input directory
+	MailsBySource map[string]int `json:"mails_by_source,omitempty"`
+
+	// Set when mails have a Character, see --infer-character-from-dir
+	MailCountByCharacter map[string]int   `json:"mail_count_by_character,omitempty"`
+	RevenueByCharacter   map[string]int64 `json:"revenue_by_character,omitempty"`
+
+	LocationBoundingBox BoundingBox `json:"location_bounding_box"`
+
+	BodyLengthStats BodyLengthStats `json:"body_length_stats"`
+	ShortBodyMails  []string        `json:"short_body_mails,omitempty"`
+
+	UnrecognizedItems []string `json:"unrecognized_items,omitempty"`
+
+	// AnnouncementsCollapsed counts announcements dropped by --dedup-announcements
+	AnnouncementsCollapsed int `json:"announcements_collapsed,omitempty"`
+
+	UnreadableDirectories []string `json:"unreadable_directories,omitempty"`
+
+	// RetriedFiles counts mail files read only after transient errors
+	RetriedFiles int `json:"retried_files,omitempty"`
+
+	// RecoveredMails counts mails whose header was reconstructed by
+	// --recover-headers
+	RecoveredMails int `json:"recovered_mails,omitempty"`
+
+	// DuplicateMails counts mails dropped by parse because an earlier mail
+	// had the same mail ID or content hash, ContentDuplicateMails those
+	// dropped for their content hash
+	DuplicateMails        int `json:"duplicate_mails,omitempty"`
+	ContentDuplicateMails int `json:"content_duplicate_mails,omitempty"`
+
+	// Gaps in sequential mail IDs, set by --id-format sequential
+	SequentialGapCount int   `json:"sequential_gap_count,omitempty"`
+	MissingIDCount     int64 `json:"missing_id_count,omitempty"`
+
+	// ValidationFailures lists missing fields found by --strict
+	ValidationFailures []ValidationFailure `json:"validation_failures,omitempty"`
 }
 
 // DateRange represents the time span of the data
@@ -31,3 +553,275 @@ type DateRange struct {
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
 }
+
+// ItemRevenueStat represents aggregated sales of a single item
+type ItemRevenueStat struct {
+	ItemName  string `json:"item_name"`
+	SaleCount int    `json:"sale_count"`
+	Revenue   int64  `json:"revenue"`
+
+	// Market lifetime of the item; DaysActive is 0 for a single sale
+	FirstSoldAt time.Time `json:"first_sold_at"`
+	LastSoldAt  time.Time `json:"last_sold_at"`
+	DaysActive  float64   `json:"days_active"`
+}
+
+// ItemDemandStat represents the buyer diversity of a single item
+type ItemDemandStat struct {
+	ItemName     string  `json:"item_name"`
+	SaleCount    int     `json:"sale_count"`
+	UniqueBuyers int     `json:"unique_buyers"`
+	DemandIndex  float64 `json:"demand_index"`
+}
+
+// VendorStats represents aggregated sales of a single player vendor
+type VendorStats struct {
+	MailCount    int   `json:"mail_count"`
+	TotalCredits int64 `json:"total_credits"`
+}
+
+// BuyerRevenueStat represents aggregated purchases of a single buyer
+type BuyerRevenueStat struct {
+	Buyer         string `json:"buyer"`
+	PurchaseCount int    `json:"purchase_count"`
+	Revenue       int64  `json:"revenue"`
+}
+
+// WeeklySummary represents the sales of a single ISO week
+type WeeklySummary struct {
+	Week         string `json:"week"`
+	MailCount    int    `json:"mail_count"`
+	TotalRevenue int64  `json:"total_revenue"`
+	TopItem      string `json:"top_item,omitempty"`
+	TopBuyer     string `json:"top_buyer,omitempty"`
+
+	// YearOverYearGrowth is the revenue growth in percent compared to the
+	// same week of the previous year, if data for that week exists
+	YearOverYearGrowth *float64 `json:"year_over_year_growth,omitempty"`
+}
+
+// PriceBucket represents aggregated sale prices of an item in one time period
+type PriceBucket struct {
+	Period   string `json:"period"`
+	AvgPrice int64  `json:"avg_price"`
+	MinPrice int64  `json:"min_price"`
+	MaxPrice int64  `json:"max_price"`
+	Count    int    `json:"count"`
+}
+
+// PriceTrendPoint represents the average price of an item in one time
+// period and how it changed against the previous period
+type PriceTrendPoint struct {
+	Period   string `json:"period"`
+	AvgPrice int64  `json:"avg_price"`
+	Count    int    `json:"count"`
+
+	// WeekOverWeekChangePct is relative to the previous period of the
+	// chosen bucket, 0 for the first period
+	WeekOverWeekChangePct float64 `json:"week_over_week_change_pct"`
+
+	// Trend is "up", "down" or "flat"
+	Trend string `json:"trend"`
+}
+
+// IDGap represents a range of missing sequential mail IDs, both inclusive
+type IDGap struct {
+	GapStart     int64 `json:"gap_start"`
+	GapEnd       int64 `json:"gap_end"`
+	MissingCount int64 `json:"missing_count"`
+}
+
+// BalancePoint represents the running credit balance after a single mail
+type BalancePoint struct {
+	Timestamp    time.Time `json:"timestamp"`
+	MailID       string    `json:"mail_id"`
+	Event        string    `json:"event"`
+	Amount       int64     `json:"amount"`
+	RunningTotal int64     `json:"running_total"`
+}
+
+// BodyLengthStats represents the distribution of mail body lengths in bytes
+type BodyLengthStats struct {
+	Min    int `json:"min"`
+	Max    int `json:"max"`
+	Mean   int `json:"mean"`
+	Median int `json:"median"`
+	P90    int `json:"p90"`
+}
+
+// ComparisonReport compares the statistics of two date ranges
+type ComparisonReport struct {
+	PeriodA ComparisonPeriod `json:"period_a"`
+	PeriodB ComparisonPeriod `json:"period_b"`
+	Delta   ComparisonDelta  `json:"delta"`
+
+	// OverlapWarning is set when the periods overlap, so the mails in the
+	// overlap are counted in both
+	OverlapWarning   bool `json:"overlap_warning"`
+	OverlapMailCount int  `json:"overlap_mail_count"`
+}
+
+// ComparisonPeriod represents the statistics of one compared date range
+type ComparisonPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Stats MailStats `json:"stats"`
+}
+
+// ComparisonDelta represents the change from period A to period B
+type ComparisonDelta struct {
+	TotalMails        int     `json:"total_mails"`
+	SaleNotifications int     `json:"sale_notifications"`
+	TotalRevenue      int64   `json:"total_revenue"`
+	RevenueChangePct  float64 `json:"revenue_change_pct"`
+}
+
+// ParsedLocation represents a location split into its city and planet
+type ParsedLocation struct {
+	City   string
+	Planet string
+}
+
+// BoundingBox represents the planar (x/z) extent of mail coordinates
+type BoundingBox struct {
+	MinX float64 `json:"min_x"`
+	MaxX float64 `json:"max_x"`
+	MinZ float64 `json:"min_z"`
+	MaxZ float64 `json:"max_z"`
+}
+
+// batchSchema describes the keys of the mails and stats sections of a
+// batch. It is written as "_schema" by parse --self-describing.
+var batchSchema = map[string]string{
+	"mails.mail_id":             "Mail ID from the first line of the mail file",
+	"mails.mail_id_normalized":  "Mail ID as 16-digit lowercase hex, or the original ID if it is not numeric",
+	"mails.galaxy":              "Server the mail was received on, from the sender or --galaxy",
+	"mails.source":              "Absolute path of the input directory the mail was parsed from",
+	"mails.content_hash":        "SHA-256 of the sender, subject, timestamp and body, identical for copies of the same mail",
+	"mails.recovered":           "Set when the malformed header of the mail was reconstructed by --recover-headers",
+	"mails.character":           "Character the mail belongs to, from its directory",
+	"mails.sender":              "Sender, e.g. SWG.Restoration.auctioner for system mails",
+	"mails.subject":             "Mail subject",
+	"mails.timestamp":           "Time the mail was received (RFC 3339)",
+	"mails.body":                "Mail body text",
+	"mails.location":            "\"City, Planet\" of the sale, or the planet and coordinates",
+	"mails.city":                "City of the sale",
+	"mails.planet":              "Planet of the sale",
+	"mails.tags":                "User-defined tags",
+	"mails.mail_type":           "Mail type: sale, purchase, expired, outbid, mission, city, guild, spam or other",
+	"mails.mail_category":       "Mail category: sale, purchase, auction, expired, factory, survey, player or unknown",
+	"mails.sale_type":           "Sale type of sale mails: vendor, bazaar or unknown",
+	"mails.normalized_subject":  "Subject without prefixes such as [AUTO] and trailing punctuation",
+	"mails.sender_domain":       "First dot-separated segment of the sender",
+	"mails.sender_subsystem":    "Segments of the sender after the second dot",
+	"mails.sender_label":        "Human-readable name of a known system sender",
+	"mails.item_name":           "Name of the sold item",
+	"mails.canonical_item_name": "Item database name of the sold item, if known",
+	"mails.item_category":       "Kind of item sold, e.g. Weapon, Armor or Resource",
+	"mails.serial_number":       "Serial number of a crafted item, e.g. xyz123 for \"(serial: xyz123)\"",
+	"mails.unit_count":          "Number of units sold in a stack sale, e.g. 100000 for \"(100000) Polysteel Copper\"",
+	"mails.price_per_unit":      "Sale price divided by the unit count",
+	"mails.item_key":            "Item name without stack counts, serial numbers and similar suffixes, used to group items",
+	"mails.buyer":               "Name of the buyer",
+	"mails.vendor_name":         "Name of the player vendor that made a vendor sale",
+	"mails.price":               "Sale price in credits",
+	"mails.price_type":          "Price type of sale mails: bid, buy_now or unknown",
+	"mails.purchase":            "Item name, seller, price and location of Auction Item Purchased notifications",
+	"mails.auction":             "Event (won or outbid), item name and bid of auction notifications",
+	"mails.expired":             "Item name and location of items that expired unsold",
+	"mails.income":              "Source (mission or gcw) and credits of mission and GCW payouts",
+	"mails.broadcast_name":      "Name of the city or guild that sent a city or guild broadcast",
+	"mails.survey_results":      "Resource, planet and concentration in percent of survey reports",
+	"mails.factory_run":         "Factory, item name, item key and quantity of factory run completion notifications",
+	"mails.sale":                "Item name, buyer, price, sale channel (vendor, bazaar or unknown) and vendor name of auctioneer Sale Complete notifications",
+	"mails.has_coordinates":     "Whether the body contains coordinates",
+	"mails.location_x":          "X coordinate",
+	"mails.location_y":          "Y coordinate",
+	"mails.location_z":          "Z coordinate",
+	"mails.waypoints":           "Waypoints embedded in the body, with planet, x, y, z and name",
+
+	"stats.total_mails":                      "Number of mails in the batch",
+	"stats.sale_notifications":               "Number of auctioneer \"Sale Complete\" mails",
+	"stats.date_range":                       "Timestamps of the first and last mail",
+	"stats.senders":                          "Number of mails per sender",
+	"stats.mails_by_subsystem":               "Number of mails per sender subsystem",
+	"stats.subject_clusters":                 "Number of mails per normalized subject",
+	"stats.mails_by_category":                "Number of mails per mail category",
+	"stats.mails_by_type":                    "Number of mails per mail type",
+	"stats.known_system_mails":               "Number of mails from known system senders",
+	"stats.unknown_sender_mails":             "Number of mails from senders without a label",
+	"stats.total_revenue":                    "Sum of all sale prices in credits",
+	"stats.vendor_revenue":                   "Revenue of vendor sales in credits",
+	"stats.bazaar_revenue":                   "Revenue of bazaar sales in credits",
+	"stats.vendor_sale_count":                "Number of vendor sales",
+	"stats.bazaar_sale_count":                "Number of bazaar sales",
+	"stats.vendor_to_bazaar_ratio":           "Vendor revenue divided by bazaar revenue, 0 without bazaar revenue",
+	"stats.bid_sale_count":                   "Number of sales won by bid",
+	"stats.buy_now_sale_count":               "Number of buy-now sales",
+	"stats.avg_bid_price":                    "Average price of sales won by bid in credits",
+	"stats.avg_buy_now_price":                "Average price of buy-now sales in credits",
+	"stats.purchase_count":                   "Number of items bought",
+	"stats.purchase_spending":                "Total credits spent on purchases",
+	"stats.other_income":                     "Total credits paid out by missions and GCW rewards",
+	"stats.other_income_by_source":           "Other income by source: mission or gcw",
+	"stats.auctions_won":                     "Number of auctions won",
+	"stats.auctions_outbid":                  "Number of outbid notifications",
+	"stats.auction_won_spending":             "Total of the winning bids of won auctions",
+	"stats.expired_count":                    "Number of items that expired unsold",
+	"stats.factory_runs":                     "Number of completed factory runs",
+	"stats.units_produced_by_item":           "Units produced by factory runs per item key",
+	"stats.resource_concentrations":          "Latest surveyed concentration in percent per resource and planet",
+	"stats.goal_progress":                    "Percentage of the --goal credits earned",
+	"stats.sender_tree":                      "Senders nested by their dot-separated segments with mail counts in _count",
+	"stats.avg_inter_sale_interval_hours":    "Average hours between consecutive sales",
+	"stats.median_inter_sale_interval_hours": "Median hours between consecutive sales",
+	"stats.top_items":                        "Items with the highest revenue",
+	"stats.top_buyers":                       "Buyers with the highest revenue",
+	"stats.item_demand_index":                "Unique buyers divided by sales per item, from 0.0 (one repeat buyer) to 1.0 (one sale per buyer)",
+	"stats.top_demand_items":                 "Items with the highest demand index",
+	"stats.sales_by_item_category":           "Number of sales per item category",
+	"stats.revenue_by_item_category":         "Revenue per item category in credits",
+	"stats.vendors":                          "Sale mail count and total credits per vendor",
+	"stats.mail_count_by_planet":             "Number of mails per planet",
+	"stats.revenue_by_planet":                "Revenue per planet in credits",
+	"stats.mail_count_by_city":               "Number of mails per city",
+	"stats.revenue_by_city":                  "Revenue per city in credits",
+	"stats.mails_by_galaxy":                  "Number of mails per galaxy",
+	"stats.revenue_by_galaxy":                "Revenue per galaxy in credits",
+	"stats.mails_by_source":                  "Number of mails per input directory",
+	"stats.mail_count_by_character":          "Number of mails per character",
+	"stats.revenue_by_character":             "Revenue per character in credits",
+	"stats.location_bounding_box":            "Bounding box of all mail coordinates",
+	"stats.body_length_stats":                "Body length distribution in bytes",
+	"stats.short_body_mails":                 "IDs of mails shorter than --flag-short-body",
+	"stats.unrecognized_items":               "Item names missing from the --item-db",
+	"stats.announcements_collapsed":          "Announcements dropped by --dedup-announcements",
+	"stats.unreadable_directories":           "Directories skipped for lack of permissions",
+	"stats.retried_files":                    "Mail files read only after retrying transient read errors",
+	"stats.recovered_mails":                  "Mails whose malformed header was reconstructed by --recover-headers",
+	"stats.duplicate_mails":                  "Mails dropped because an earlier mail had the same mail ID or content hash",
+	"stats.content_duplicate_mails":          "Mails of duplicate_mails dropped for their content hash under a different mail ID",
+	"stats.validation_failures":              "Missing mail fields found by --strict",
+	"stats.sequential_gap_count":             "Number of gaps in sequential mail IDs (--id-format sequential)",
+	"stats.missing_id_count":                 "Number of mail IDs missing from the gaps (--id-format sequential)",
+}
+
+// dedupID returns the ID mails are deduplicated by, the normalized mail ID
+// if set
+func dedupID(m MailData) string {
+	if m.MailIDNormalized != "" {
+		return m.MailIDNormalized
+	}
+	return m.MailID
+}
+
+// contentHash identifies a mail by its content rather than its ID, so the
+// same sale stored under different mail IDs hashes identically
+func contentHash(m MailData) string {
+	h := sha256.New()
+	for _, part := range []string{m.Sender, m.Subject, strconv.FormatInt(m.Timestamp.Unix(), 10), m.Body} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// walkEvent is a mail file or an error found while walking a directory
+type walkEvent struct {
+	path  string
+	entry fs.DirEntry
+	err   error
+}
+
+// walkMailFiles calls fn for every .mail file below root and for every path
+// that could not be read, in lexical order like filepath.WalkDir. Entries
+// are not stat'ed, which is slow on network shares, and directories that
+// cannot hold mails are pruned, see skipMailDir. The top-level
+// subdirectories are traversed with up to workers at a time, while fn is
+// never called concurrently. Walking stops at the first error fn returns.
+func walkMailFiles(root string, workers int, fn func(path string, entry fs.DirEntry, err error) error) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		// A single file, or a directory WalkDir reports the error for
+		return walkMailTree(root, fn)
+	}
+
+	// The events of each top-level subdirectory, replayed in order below
+	subtrees := make([][]walkEvent, len(entries))
+	var group errgroup.Group
+	group.SetLimit(max(workers, 1))
+	for i, entry := range entries {
+		if !entry.IsDir() || skipMailDir(entry.Name()) {
+			continue
+		}
+		group.Go(func() error {
+			return walkMailTree(filepath.Join(root, entry.Name()), func(path string, entry fs.DirEntry, err error) error {
+				subtrees[i] = append(subtrees[i], walkEvent{path: path, entry: entry, err: err})
+				return nil
+			})
+		})
+	}
+	group.Wait()
+
+	for i, entry := range entries {
+		if !entry.IsDir() {
+			if !strings.HasSuffix(entry.Name(), ".mail") {
+				continue
+			}
+			if err := fn(filepath.Join(root, entry.Name()), entry, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, event := range subtrees[i] {
+			if err := fn(event.path, event.entry, event.err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkMailTree calls fn for every .mail file below dir and every path that
+// could not be read, walking sequentially. Unreadable directories are
+// skipped after fn is called for them.
+func walkMailTree(dir string, fn func(path string, entry fs.DirEntry, err error) error) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if err := fn(path, entry, err); err != nil {
+				return err
+			}
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			if path != dir && skipMailDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".mail") {
+			return nil
+		}
+		return fn(path, entry, nil)
+	})
+}
+
+// skipMailDir reports whether a directory cannot hold mails and is not
+// walked: hidden directories such as ".git" or ".snapshot", and the
+// metadata and recycle bin directories NAS systems add to shares
+func skipMailDir(name string) bool {
+	switch name {
+	case "@eaDir", "#recycle", "#snapshot", "$RECYCLE.BIN":
+		return true
+	}
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// mailWatcher watches a mail directory for new and modified .mail files. A
+// file is only reported once its size and modification time are the same
+// in two consecutive polls, so files the client is still writing are not
+// read half-written.
+//
+// The watcher is notified of changed files by the file system, so polls
+// only look at those files. Where notifications are not available, e.g.
+// on some network shares, every poll scans the whole directory instead.
+type mailWatcher struct {
+	dir string
+
+	// observed holds the files of the last poll, reported those already
+	// passed on
+	observed map[string]stateFile
+	reported map[string]stateFile
+
+	// The events of notify add to pending; full makes the next poll scan
+	// the whole directory, e.g. after lost events. notifyErr is set when
+	// notifications could not be set up or a new directory could not be
+	// watched, and every poll scans the whole directory from then on.
+	notify    *fsnotify.Watcher
+	notifyErr error
+	mu        sync.Mutex
+	pending   map[string]bool
+	full      bool
+}
+
+// newMailWatcher returns a watcher for the .mail files below dir. Unless
+// includeExisting is set, the files already present are never reported.
+// The watcher falls back to polling if file system notifications cannot
+// be set up.
+func newMailWatcher(dir string, includeExisting bool) (*mailWatcher, error) {
+	w := &mailWatcher{
+		dir:      dir,
+		observed: make(map[string]stateFile),
+		reported: make(map[string]stateFile),
+		pending:  make(map[string]bool),
+	}
+
+	// Files created while the directories are being added are reported by
+	// the scan below
+	w.notifyErr = w.startNotify()
+
+	files, err := w.scan()
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	if includeExisting {
+		for path := range files {
+			w.pending[path] = true
+		}
+		return w, nil
+	}
+
+	w.observed = files
+	for path, file := range files {
+		w.reported[path] = file
+	}
+	return w, nil
+}
+
+// startNotify watches the directories below w.dir for changes. On failure
+// the watcher keeps polling.
+func (w *mailWatcher) startNotify() error {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w.notify = notify
+	if err := w.addDirs(w.dir); err != nil {
+		notify.Close()
+		w.notify = nil
+		return err
+	}
+
+	go w.handleEvents()
+	return nil
+}
+
+// addDirs watches dir and all directories below it
+func (w *mailWatcher) addDirs(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		return w.notify.Add(path)
+	})
+}
+
+// handleEvents collects the .mail files changed according to the file
+// system notifications until the watcher is closed
+func (w *mailWatcher) handleEvents() {
+	for {
+		select {
+		case event, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				// Files in a new directory may be created before it is
+				// watched, a full scan finds them
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.mu.Lock()
+					if err := w.addDirs(event.Name); err != nil {
+						w.notifyErr = err
+					}
+					w.full = true
+					w.mu.Unlock()
+					continue
+				}
+			}
+			if strings.HasSuffix(event.Name, ".mail") && (event.Has(fsnotify.Create) || event.Has(fsnotify.Write)) {
+				w.mu.Lock()
+				w.pending[event.Name] = true
+				w.mu.Unlock()
+			}
+		case _, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost, e.g. when the queue overflowed
+			w.rescan()
+		}
+	}
+}
+
+// rescan makes the next poll scan the whole directory, for changes that
+// notifications may have missed
+func (w *mailWatcher) rescan() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.full = true
+}
+
+// notifyError returns why the watcher scans the whole directory on every
+// poll, or nil while it is notified of changes
+func (w *mailWatcher) notifyError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.notifyErr
+}
+
+// close stops the file system notifications
+func (w *mailWatcher) close() error {
+	if w.notify == nil {
+		return nil
+	}
+	return w.notify.Close()
+}
+
+// poll returns the files that settled since the last poll, in path order
+func (w *mailWatcher) poll() ([]string, error) {
+	w.mu.Lock()
+	polling := w.notifyErr != nil
+	candidates, full := w.pending, w.full || polling
+	w.pending, w.full = make(map[string]bool), false
+	w.mu.Unlock()
+
+	var files map[string]stateFile
+	if full {
+		var err error
+		if files, err = w.scan(); err != nil {
+			return nil, err
+		}
+		for path := range w.observed {
+			if _, ok := files[path]; !ok {
+				delete(w.observed, path)
+			}
+		}
+		for path := range files {
+			candidates[path] = true
+		}
+	} else {
+		files = make(map[string]stateFile, len(candidates))
+		for path := range candidates {
+			info, err := os.Stat(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				delete(w.observed, path)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			files[path] = stateFile{Size: info.Size(), ModTime: info.ModTime()}
+		}
+	}
+
+	var settled []string
+	for path := range candidates {
+		file, ok := files[path]
+		if !ok {
+			continue
+		}
+		last, ok := w.observed[path]
+		w.observed[path] = file
+		if !ok || !last.equal(file) {
+			// Checked again by the next poll, even without another event
+			if !polling {
+				w.mu.Lock()
+				w.pending[path] = true
+				w.mu.Unlock()
+			}
+			continue
+		}
+		if reported, ok := w.reported[path]; ok && reported.equal(file) {
+			continue
+		}
+		w.reported[path] = file
+		settled = append(settled, path)
+	}
+
+	sort.Strings(settled)
+	return settled, nil
+}
+
+// scan returns the size and modification time of every .mail file below
+// the watched directory. Files vanishing during the scan are ignored.
+func (w *mailWatcher) scan() (map[string]stateFile, error) {
+	files := make(map[string]stateFile)
+	err := filepath.WalkDir(w.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != w.dir {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ".mail") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		files[path] = stateFile{Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// rescanSignals returns no signals, as there is no SIGUSR1 on this platform
+func rescanSignals() []os.Signal {
+	return nil
+}
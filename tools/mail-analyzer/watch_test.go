@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMailWatcherRescan(t *testing.T) {
+	tests := []struct {
+		name   string
+		rescan bool
+		found  bool
+	}{
+		{"missed without rescan", false, false},
+		{"found by rescan", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			watcher, err := newMailWatcher(dir, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := watcher.notifyError(); err != nil {
+				t.Skipf("no file system notifications: %v", err)
+			}
+			// Closing the notifications loses every following event
+			watcher.close()
+
+			path := writeTestMail(t, dir, "1.mail", "1", "Player", "Hello", 1700000000, "Body")
+
+			if tt.rescan {
+				watcher.rescan()
+			}
+			// The first poll observes the file, the second reports it once
+			// it has settled
+			var got []string
+			for range 2 {
+				paths, err := watcher.poll()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, paths...)
+			}
+			var want []string
+			if tt.found {
+				want = []string{path}
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("poll() = %v, want %v", got, want)
+			}
+		})
+	}
+}
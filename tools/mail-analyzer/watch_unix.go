@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// rescanSignals returns the signals that make watch scan the whole mail
+// directory
+func rescanSignals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR1}
+}
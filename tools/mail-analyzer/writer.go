@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteMail serializes mail data back into the .mail file format read by
+// parseMailFile:
+// Line 0: Mail ID
+// Line 1: Sender
+// Line 2: Subject
+// Line 3: TIMESTAMP: <unix timestamp>
+// Line 4+: Body content
+func WriteMail(data *MailData, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, data.MailID)
+	fmt.Fprintln(bw, data.Sender)
+	fmt.Fprintln(bw, data.Subject)
+	fmt.Fprintf(bw, "TIMESTAMP: %d\n", data.Timestamp.Unix())
+	if data.Body != "" {
+		fmt.Fprintln(bw, data.Body)
+	}
+
+	return bw.Flush()
+}
+
+// writeMailFiles writes every mail as <mail id>.mail into dir
+func writeMailFiles(mails []MailData, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for i := range mails {
+		name := strings.NewReplacer("/", "_", "\\", "_").Replace(mails[i].MailID) + ".mail"
+		if err := writeMailFile(&mails[i], filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeMailFile writes a single mail to path
+func writeMailFile(mail *MailData, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create mail file: %w", err)
+	}
+
+	if err := WriteMail(mail, file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write mail file %s: %w", path, err)
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteMailRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		sender  string
+		subject string
+		body    string
+	}{
+		{"sale", "SWG.Restoration.auctioner", "Vendor Sale Complete",
+			"Vendor: Crafter has sold Rifle to Han for 1000 credits.\nThe sale took place at Mos Eisley, on Tatooine."},
+		{"multi-line body", "Han Solo", "Re: Rifle", "Thanks!\n\nMeet me at coordinates -1234.5 7 42 on Tatooine.\n-- Han"},
+		{"single line", "Leia Organa", "Guild meeting", "See you at the hall."},
+		{"empty body", "Leia Organa", "Ping", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			original, err := parseMailFile(writeTestMail(t, dir, "original.mail", "42", tt.sender, tt.subject, 1705312800, tt.body), ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := WriteMail(original, &buf); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "written.mail")
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			reparsed, err := parseMailFile(path, ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(reparsed, original) {
+				t.Errorf("re-parsed mail differs:\ngot  %+v\nwant %+v", reparsed, original)
+			}
+		})
+	}
+}
+
+func TestWriteMailFiles(t *testing.T) {
+	mails := []MailData{
+		{MailID: "1", Sender: "Han Solo", Subject: "Hello", Body: "Hi"},
+		{MailID: "a/b", Sender: "Han Solo", Subject: "Slashes", Body: "Hi"},
+		{MailID: `c\d`, Sender: "Han Solo", Subject: "Backslashes", Body: "Hi"},
+	}
+
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"plain ID", "1.mail"},
+		{"slash in ID", "a_b.mail"},
+		{"backslash in ID", "c_d.mail"},
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := writeMailFiles(mails, dir); err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mail, err := parseMailFile(filepath.Join(dir, tt.file), ParseOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mail.MailID != mails[i].MailID || mail.Subject != mails[i].Subject {
+				t.Errorf("got mail %q %q, want %q %q", mail.MailID, mail.Subject, mails[i].MailID, mails[i].Subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxSheet is a worksheet of a workbook: a header row and the rows below
+// it, produced one at a time so that large batches are not held twice
+type xlsxSheet struct {
+	Name   string
+	Header []string
+	Rows   func(yield func(row []any) error) error
+}
+
+// writeXLSX writes a batch as an Excel workbook with sheets for the mails,
+// the sales, the revenue per item and the totals per vendor. Cells are
+// strings, numbers or dates, see xlsxCell.
+func writeXLSX(w io.Writer, batch MailBatch) error {
+	mails := batch.Mails
+
+	// Computed from the mails, batches read from CSV have no statistics
+	items := make(itemRevenues)
+	addVendor, applyVendors := aggregateVendors()
+	for i := range mails {
+		items.add(&mails[i])
+		addVendor(&mails[i])
+	}
+	var stats MailStats
+	applyVendors(&stats)
+
+	sheets := []xlsxSheet{
+		{
+			Name:   "Mails",
+			Header: columnNames(mailColumns, func(c mailColumn) string { return c.Name }),
+			Rows: func(yield func(row []any) error) error {
+				row := make([]any, len(mailColumns))
+				for i := range mails {
+					for j, column := range mailColumns {
+						row[j] = column.cell(&mails[i])
+					}
+					if err := yield(row); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:   "Sales",
+			Header: columnNames(saleColumns, func(c saleColumn) string { return c.Name }),
+			Rows: func(yield func(row []any) error) error {
+				row := make([]any, len(saleColumns))
+				for i := range mails {
+					if mails[i].Sale == nil {
+						continue
+					}
+					for j, column := range saleColumns {
+						row[j] = column.cell(&mails[i])
+					}
+					if err := yield(row); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:   "Items",
+			Header: []string{"item_name", "sale_count", "revenue", "first_sold_at", "last_sold_at", "days_active"},
+			Rows: func(yield func(row []any) error) error {
+				for _, item := range items.top(0) {
+					row := []any{item.ItemName, item.SaleCount, item.Revenue, item.FirstSoldAt, item.LastSoldAt, item.DaysActive}
+					if err := yield(row); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:   "Vendors",
+			Header: []string{"vendor_name", "mail_count", "total_credits"},
+			Rows: func(yield func(row []any) error) error {
+				names := slices.SortedFunc(maps.Keys(stats.Vendors), func(a, b string) int {
+					return cmp.Or(cmp.Compare(stats.Vendors[b].TotalCredits, stats.Vendors[a].TotalCredits), strings.Compare(a, b))
+				})
+				for _, name := range names {
+					vendor := stats.Vendors[name]
+					if err := yield([]any{name, vendor.MailCount, vendor.TotalCredits}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+
+	return writeWorkbook(w, sheets)
+}
+
+// columnNames returns the names of columns, for a header row
+func columnNames[C any](columns []C, name func(C) string) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = name(column)
+	}
+	return names
+}
+
+// writeWorkbook writes sheets as an Excel workbook. The rows are streamed
+// to the sheets, with a bold, frozen header row.
+func writeWorkbook(w io.Writer, sheets []xlsxSheet) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	headerStyle, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	dateFormat := "yyyy-mm-dd hh:mm:ss"
+	dateStyle, err := file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			err = file.SetSheetName(file.GetSheetName(0), sheet.Name)
+		} else {
+			_, err = file.NewSheet(sheet.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeWorksheet(file, sheet, headerStyle, dateStyle); err != nil {
+			return fmt.Errorf("failed to write sheet %s: %w", sheet.Name, err)
+		}
+	}
+
+	_, err = file.WriteTo(w)
+	return err
+}
+
+// writeWorksheet streams the header and rows of sheet to the sheet of the
+// same name in file
+func writeWorksheet(file *excelize.File, sheet xlsxSheet, headerStyle, dateStyle int) error {
+	stream, err := file.NewStreamWriter(sheet.Name)
+	if err != nil {
+		return err
+	}
+	if err := stream.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+
+	header := make([]any, len(sheet.Header))
+	for i, name := range sheet.Header {
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: name}
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	rowNumber := 1
+	var cells []any
+	err = sheet.Rows(func(row []any) error {
+		rowNumber++
+		cells = cells[:0]
+		for _, value := range row {
+			cells = append(cells, xlsxCell(value, dateStyle))
+		}
+		return stream.SetRow("A"+strconv.Itoa(rowNumber), cells)
+	})
+	if err != nil {
+		return err
+	}
+	return stream.Flush()
+}
+
+// xlsxCell returns the value of a cell for the stream writer. Numbers become
+// number cells, times date cells in their wall clock time and everything
+// else strings; empty strings and zero times leave the cell out. Excel
+// accepts at most 32767 characters in a cell, longer texts, such as long
+// mail bodies, are truncated.
+func xlsxCell(value any, dateStyle int) any {
+	switch v := value.(type) {
+	case int, int64, float64:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return excelize.Cell{StyleID: dateStyle, Value: v}
+	default:
+		text := fmt.Sprint(v)
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	batch := parseTestBatch(t)
+	var buf bytes.Buffer
+	if err := writeXLSX(&buf, batch); err != nil {
+		t.Fatal(err)
+	}
+
+	workbook, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer workbook.Close()
+
+	sheets := []struct {
+		name   string
+		header []string
+		rows   int
+	}{
+		{"Mails", columnNames(mailColumns, func(c mailColumn) string { return c.Name }), 4},
+		{"Sales", columnNames(saleColumns, func(c saleColumn) string { return c.Name }), 2},
+		{"Items", []string{"item_name", "sale_count", "revenue", "first_sold_at", "last_sold_at", "days_active"}, 2},
+		{"Vendors", []string{"vendor_name", "mail_count", "total_credits"}, 1},
+	}
+	if got, want := workbook.GetSheetList(), []string{"Mails", "Sales", "Items", "Vendors"}; !slices.Equal(got, want) {
+		t.Errorf("sheets = %v, want %v", got, want)
+	}
+
+	for _, tt := range sheets {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := workbook.GetRows(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) == 0 {
+				t.Fatal("sheet is empty")
+			}
+			if !slices.Equal(rows[0], tt.header) {
+				t.Errorf("header = %v, want %v", rows[0], tt.header)
+			}
+			if got := len(rows) - 1; got != tt.rows {
+				t.Errorf("sheet has %d rows below the header, want %d", got, tt.rows)
+			}
+		})
+	}
+}